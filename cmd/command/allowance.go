@@ -0,0 +1,238 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/batch"
+	"github.com/maxipaz/wallet/internal/budget"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"github.com/spf13/cobra"
+	"io"
+)
+
+// changeSymbols symbol printed before each kind of change
+var changeSymbols = map[string]string{
+	budget.CreateChange:   "+",
+	budget.IncreaseChange: "↑",
+	budget.ReduceChange:   "↓",
+	budget.ZeroChange:     "-",
+}
+
+// NewAllowanceCommand creates the allowance command
+func NewAllowanceCommand(ctx context.Context) *cobra.Command {
+	allowanceCommand := &cobra.Command{
+		Use:   "allowance",
+		Short: "Plan and apply the allowances declared in a budgets file, or change them in bulk",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [plan, apply, batch]", errs.ErrInvalidUsage)
+		},
+	}
+
+	allowanceCommand.AddCommand(newAllowancePlanCommand(ctx))
+	allowanceCommand.AddCommand(newAllowanceApplyCommand(ctx))
+	allowanceCommand.AddCommand(newBatchCommand(ctx, batch.AllowanceBatch))
+	return allowanceCommand
+}
+
+func newAllowancePlanCommand(ctx context.Context) *cobra.Command {
+	var (
+		file string
+		out  string
+	)
+
+	planCommand := &cobra.Command{
+		Use:   "plan",
+		Short: "Compare the budgets with the on-chain allowances and print the changes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			budgets, err := budget.Load(file, contacts.Default())
+			if err != nil {
+				return err
+			}
+
+			return withPlanner(ctx, false, func(planner *budget.Planner, client *ethclient.Client) error {
+				plan, err := planner.Plan(ctx, client, file, budgets)
+				if err != nil {
+					return err
+				}
+
+				if out != "" {
+					if err := budget.Save(plan, out); err != nil {
+						return err
+					}
+				}
+				return printPlan(plan, out)
+			})
+		},
+	}
+
+	planCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	planCommand.Flags().StringVarP(&file, "file", "f", "", "Budgets file listing the desired allowances")
+	planCommand.Flags().StringVarP(&out, "out", "o", "", "File to save the plan to, for apply")
+	_ = planCommand.MarkFlagRequired("file")
+
+	return planCommand
+}
+
+func newAllowanceApplyCommand(ctx context.Context) *cobra.Command {
+	var planFile string
+
+	applyCommand := &cobra.Command{
+		Use:   "apply",
+		Short: "Apply the changes of a saved plan, resuming it when it was interrupted, and check the final allowances",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := budget.Open(planFile)
+			if err != nil {
+				return err
+			}
+
+			return withPlanner(ctx, true, func(planner *budget.Planner, client *ethclient.Client) error {
+				return applyPlan(ctx, planner, client, plan)
+			})
+		},
+	}
+
+	applyCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	applyCommand.Flags().StringVarP(&planFile, "plan", "p", "", "Plan file saved by allowance plan")
+	_ = applyCommand.MarkFlagRequired("plan")
+
+	return applyCommand
+}
+
+// appliedChange change applied by a plan, the transaction is empty when it was already applied
+type appliedChange struct {
+	budget.Change
+	TxHash      string `json:"tx_hash,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+}
+
+func applyPlan(ctx context.Context, planner *budget.Planner, client *ethclient.Client, plan *budget.Plan) error {
+	total := len(plan.Changes) - plan.Count()[budget.UnchangedChange]
+	output.Progressf("Applying plan %s: %d changes\n", plan.ID, total)
+
+	result := struct {
+		Plan       string          `json:"plan"`
+		Applied    []appliedChange `json:"applied"`
+		Mismatches []budget.Change `json:"mismatches"`
+	}{Plan: plan.ID, Applied: make([]appliedChange, 0, total), Mismatches: []budget.Change{}}
+
+	err := planner.Apply(ctx, client, plan, func(_ int, change budget.Change, tx *wallet.TransactionResult) {
+		applied := appliedChange{Change: change}
+		status := "already applied"
+		if tx != nil {
+			applied.TxHash, applied.BlockNumber = tx.TxHash, tx.BlockNumber
+			status = fmt.Sprintf("transaction %s mined in block %d", tx.TxHash, tx.BlockNumber)
+		}
+		result.Applied = append(result.Applied, applied)
+		output.Progressf("[%d/%d] %s %s %d -> %d: %s\n", len(result.Applied), total, change.Kind, contacts.Display(change.Address), change.Current, change.Desired, status)
+	})
+	if err != nil {
+		return err
+	}
+
+	mismatches, err := planner.Verify(ctx, client, plan)
+	if err != nil {
+		return err
+	}
+	result.Mismatches = append(result.Mismatches, mismatches...)
+
+	if err := output.Print(result, func(w io.Writer) {
+		for _, change := range result.Mismatches {
+			fmt.Fprintf(w, "allowance of %s is %d instead of %d\n", contacts.Display(change.Address), change.Current, change.Desired)
+		}
+		if len(result.Mismatches) == 0 {
+			fmt.Fprintf(w, "Plan %s applied, the %d allowances match the plan\n", plan.ID, len(plan.Changes))
+		}
+	}); err != nil {
+		return err
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%w: %d allowances differ", errs.ErrPlanNotApplied, len(mismatches))
+	}
+	return nil
+}
+
+// withPlanner dials the node and calls the given function with the planner. The store is opened when configured, or
+// required to apply a plan
+func withPlanner(ctx context.Context, apply bool, fn func(planner *budget.Planner, client *ethclient.Client) error) error {
+	pol, err := policy.New(config.App.Policy)
+	if err != nil {
+		return err
+	}
+
+	var st *store.Store
+	if config.App.Store.Path != "" {
+		if st, err = store.Open(ctx, config.App.Store.Path); err != nil {
+			return err
+		}
+		defer st.Close()
+	} else if apply {
+		return errs.ErrPlanStoreRequired
+	}
+
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx = wallet.WithRequester(ctx, "cli:"+common.CurrentUser())
+	return fn(budget.NewPlanner(st, config.App.Blockchain.PrivateKey, config.App.Contract.Address, pol), client)
+}
+
+// printPlan prints the changes of the plan and their total against the contract balance, with the file it was saved to
+func printPlan(plan *budget.Plan, file string) error {
+	result := struct {
+		*budget.Plan
+		File string `json:"file,omitempty"`
+	}{plan, file}
+
+	return output.Print(result, func(w io.Writer) {
+		count := plan.Count()
+		fmt.Fprintf(w, "Plan %s for contract %s: %d to create, %d to increase, %d to reduce, %d to zero, %d unchanged\n\n",
+			plan.ID,
+			plan.Contract,
+			count[budget.CreateChange],
+			count[budget.IncreaseChange],
+			count[budget.ReduceChange],
+			count[budget.ZeroChange],
+			count[budget.UnchangedChange],
+		)
+
+		fmt.Fprintln(w, "\tCHANGE\tADDRESS\tNAME\tCURRENT (ETH)\tDESIRED (ETH)\tDELTA (ETH)")
+		for _, change := range plan.Changes {
+			if change.Kind == budget.UnchangedChange {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%+d\n",
+				changeSymbols[change.Kind],
+				change.Kind,
+				change.Address,
+				change.Name,
+				change.Current,
+				change.Desired,
+				change.Delta(),
+			)
+		}
+
+		fmt.Fprintf(w, "\nTotal delta: %+d ether\nPlanned allowances: %d ether\nContract balance: %d ether\n",
+			plan.TotalDelta(), plan.TotalAllowance(), plan.ContractBalance)
+		if excess := plan.TotalAllowance() - plan.ContractBalance; excess > 0 {
+			fmt.Fprintf(w, "Warning: the planned allowances exceed the contract balance by %d ether\n", excess)
+		}
+		if file != "" {
+			fmt.Fprintf(w, "\nPlan saved to %s, apply it with: wallet allowance apply --plan %s\n", file, file)
+		}
+	})
+}
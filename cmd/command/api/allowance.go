@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/contacts"
 	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
 	"github.com/maxipaz/wallet/internal/wallet"
 	"github.com/spf13/cobra"
-	"log/slog"
+	"io"
 )
 
 var allowanceActions = map[string]struct{}{
@@ -18,37 +20,47 @@ var allowanceActions = map[string]struct{}{
 	"reduce":   {},
 }
 
+// allowanceResult allowance of an address, expressed in Ether
+type allowanceResult struct {
+	Address   string `json:"address"`
+	Name      string `json:"name,omitempty"`
+	Allowance int64  `json:"allowance"`
+}
+
 // NewAllowanceCommand creates the allowance command
 func NewAllowanceCommand(ctx context.Context) *cobra.Command {
 	var (
-		action        string
-		targetAddress string
-		amount        int64
+		action          string
+		targetAddresses []string
+		amount          int64
+		idempotencyKey  string
 	)
 	allowanceCommand := &cobra.Command{
 		Use:   "allowance",
 		Short: "Change the allowance for a beneficiary",
-		Run: func(cmd *cobra.Command, args []string) {
-
-			if err := runAllowance(ctx, action, targetAddress, amount); err != nil {
-				slog.ErrorContext(ctx, "failed to run allowance", slog.String("error", err.Error()))
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAllowance(ctx, action, targetAddresses, amount, idempotencyKey)
 		},
 	}
 
 	allowanceCommand.Flags().StringVar(&action, "action", "", "Action to perform: set, get, increase or reduce")
 	allowanceCommand.Flags().Int64Var(&amount, "amount", 0, "Amount")
-	allowanceCommand.Flags().StringVarP(&targetAddress, "target.address", "t", "", "Target address")
+	allowanceCommand.Flags().StringSliceVarP(&targetAddresses, "target.address", "t", nil, "Target address, get accepts several addresses")
+	contacts.MarkAddressFlag(allowanceCommand.Flags(), "target.address")
+	allowanceCommand.Flags().StringVar(&idempotencyKey, "idempotency-key", "", idempotencyKeyUsage)
 	_ = allowanceCommand.MarkFlagRequired("action")
 	_ = allowanceCommand.MarkFlagRequired("target.address")
 
 	return allowanceCommand
 }
 
-func runAllowance(ctx context.Context, action string, targetAddress string, amount int64) error {
+func runAllowance(ctx context.Context, action string, targetAddresses []string, amount int64, idempotencyKey string) error {
 	if _, ok := allowanceActions[action]; !ok {
 		return errs.ErrInvalidAllowanceAction
 	}
+	if action != wallet.GetAction && len(targetAddresses) != 1 {
+		return errs.ErrSingleTargetAddress
+	}
 
 	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
 	defer cancel()
@@ -64,21 +76,44 @@ func runAllowance(ctx context.Context, action string, targetAddress string, amou
 
 	switch action {
 	case wallet.GetAction:
-		allowance, err := runner.GetAllowance(ctx, client, targetAddress)
-		if err != nil {
-			return fmt.Errorf("failed to get allowance: %w", err)
+		allowances := make(map[string]int64, 1)
+		if len(targetAddresses) == 1 {
+			allowance, err := runner.GetAllowance(ctx, client, targetAddresses[0])
+			if err != nil {
+				return fmt.Errorf("failed to get allowance: %w", err)
+			}
+			allowances[targetAddresses[0]] = allowance
+		} else if allowances, err = runner.GetAllowances(ctx, client, targetAddresses); err != nil {
+			return fmt.Errorf("failed to get allowances: %w", err)
 		}
 
-		fmt.Printf("Current allowance for address %s is %d\n", targetAddress, allowance)
+		result := struct {
+			Allowances []allowanceResult `json:"allowances"`
+		}{Allowances: make([]allowanceResult, 0, len(targetAddresses))}
+		for _, address := range targetAddresses {
+			result.Allowances = append(result.Allowances, allowanceResult{Address: address, Name: contacts.Label(address), Allowance: allowances[address]})
+		}
+		return output.Print(result, func(w io.Writer) {
+			for _, allowance := range result.Allowances {
+				fmt.Fprintf(w, "Current allowance for address %s is %d\n", contacts.Display(allowance.Address), allowance.Allowance)
+			}
+		})
 	default:
 		if amount <= 0 {
 			return errs.ErrInvalidAmountAction
 		}
 
-		if err := runner.ChangeAllowance(ctx, client, action, targetAddress, amount); err != nil {
+		ctx, closeStore, err := setupRunner(ctx, idempotencyKey, runner)
+		if err != nil {
+			return err
+		}
+		defer closeStore()
+
+		result, err := runner.ChangeAllowance(ctx, client, action, targetAddresses[0], amount)
+		if err != nil {
 			return fmt.Errorf("failed to change allowance: %w", err)
 		}
-	}
 
-	return nil
+		return printTransaction(result)
+	}
 }
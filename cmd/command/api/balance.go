@@ -2,17 +2,15 @@ package api
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/maxipaz/wallet/config"
-	"github.com/maxipaz/wallet/pkg/blockchain"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/wallet"
 	"github.com/spf13/cobra"
-)
-
-var (
-	ErrInvalidBalanceAction  = errors.New("invalid balance action")
-	ErrInvalidBalanceAddress = errors.New("invalid balance address")
+	"io"
 )
 
 var balanceOfList = map[string]struct{}{
@@ -21,32 +19,37 @@ var balanceOfList = map[string]struct{}{
 	"contract": {},
 }
 
+// balanceResult balance of an address, expressed in Ether
+type balanceResult struct {
+	Address string `json:"address"`
+	Name    string `json:"name,omitempty"`
+	Balance int64  `json:"balance"`
+}
+
 // NewBalanceCommand creates the balance command
 func NewBalanceCommand(ctx context.Context) *cobra.Command {
 	var (
-		of            string
-		targetAddress string
+		of              string
+		targetAddresses []string
 	)
 	balanceCommand := &cobra.Command{
 		Use:   "balance",
 		Short: "Get the balance of an address or contract",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := runBalance(ctx, of, targetAddress)
-			if err != nil {
-				fmt.Println("ERROR: " + err.Error())
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBalance(ctx, of, targetAddresses)
 		},
 	}
 
 	balanceCommand.Flags().StringVar(&of, "of", "", "Balance of: address, contract")
-	balanceCommand.Flags().StringVarP(&targetAddress, "target.address", "t", "", "Target address")
+	balanceCommand.Flags().StringSliceVarP(&targetAddresses, "target.address", "t", nil, "Target address, several addresses are read in batches")
+	contacts.MarkAddressFlag(balanceCommand.Flags(), "target.address")
 	_ = balanceCommand.MarkFlagRequired("of")
 	return balanceCommand
 }
 
-func runBalance(ctx context.Context, of string, targetAddress string) error {
+func runBalance(ctx context.Context, of string, targetAddresses []string) error {
 	if _, ok := balanceOfList[of]; !ok {
-		return ErrInvalidBalanceAction
+		return errs.ErrInvalidBalanceAction
 	}
 
 	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
@@ -55,24 +58,46 @@ func runBalance(ctx context.Context, of string, targetAddress string) error {
 	if err != nil {
 		return err
 	}
-	runner := blockchain.NewBalanceRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
+	runner := wallet.NewBalanceRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
 
+	var result struct {
+		Balances []balanceResult `json:"balances"`
+	}
 	switch of {
-	case blockchain.ContractBalance:
+	case wallet.ContractBalance:
 		balance, err := runner.GetContractBalance(ctx, client)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("The contract balance is %d\n", balance)
-	case blockchain.AddressBalance:
-		if targetAddress == "" {
-			return ErrInvalidBalanceAddress
+		result.Balances = []balanceResult{{Address: config.App.Contract.Address, Balance: balance}}
+		return output.Print(result, func(w io.Writer) {
+			fmt.Fprintf(w, "The contract balance is %d\n", balance)
+		})
+	case wallet.AddressBalance:
+		balances := make(map[string]int64, 1)
+		switch len(targetAddresses) {
+		case 0:
+			return errs.ErrMissingTargetAddress
+		case 1:
+			balance, err := runner.GetAddressBalance(ctx, client, targetAddresses[0])
+			if err != nil {
+				return err
+			}
+			balances[targetAddresses[0]] = balance
+		default:
+			if balances, err = runner.GetAddressBalances(ctx, client, targetAddresses); err != nil {
+				return err
+			}
 		}
-		balance, err := runner.GetAddressBalance(ctx, client, targetAddress)
-		if err != nil {
-			return err
+
+		for _, address := range targetAddresses {
+			result.Balances = append(result.Balances, balanceResult{Address: address, Name: contacts.Label(address), Balance: balances[address]})
 		}
-		fmt.Printf("The contract balance is %d\n", balance)
+		return output.Print(result, func(w io.Writer) {
+			for _, balance := range result.Balances {
+				fmt.Fprintf(w, "The balance of %s is %d\n", contacts.Display(balance.Address), balance.Balance)
+			}
+		})
 	}
 
 	return nil
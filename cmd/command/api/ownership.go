@@ -2,17 +2,15 @@ package api
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/maxipaz/wallet/config"
-	"github.com/maxipaz/wallet/pkg/blockchain"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/wallet"
 	"github.com/spf13/cobra"
-)
-
-var (
-	ErrInvalidOwnershipAction  = errors.New("invalid ownership action")
-	ErrInvalidOwnershipAddress = errors.New("invalid ownership address")
+	"io"
 )
 
 var ownershipActions = map[string]struct{}{
@@ -29,23 +27,21 @@ func NewOwnershipCommand(ctx context.Context) *cobra.Command {
 	ownershipCommand := &cobra.Command{
 		Use:   "ownership",
 		Short: "Get or transfer contract ownership",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := runOwnership(ctx, action, targetAddress)
-			if err != nil {
-				fmt.Println("ERROR: " + err.Error())
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOwnership(ctx, action, targetAddress)
 		},
 	}
 
 	ownershipCommand.Flags().StringVar(&action, "action", "", "Ownership action: get, transfer")
 	ownershipCommand.Flags().StringVarP(&targetAddress, "target.address", "t", "", "Target address")
+	contacts.MarkAddressFlag(ownershipCommand.Flags(), "target.address")
 	_ = ownershipCommand.MarkFlagRequired("action")
 	return ownershipCommand
 }
 
 func runOwnership(ctx context.Context, action string, targetAddress string) error {
 	if _, ok := ownershipActions[action]; !ok {
-		return ErrInvalidOwnershipAction
+		return errs.ErrInvalidOwnershipAction
 	}
 
 	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
@@ -54,24 +50,35 @@ func runOwnership(ctx context.Context, action string, targetAddress string) erro
 	if err != nil {
 		return err
 	}
-	runner := blockchain.NewOwnerRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
+	runner := wallet.NewOwnerRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
 
 	switch action {
-	case blockchain.GetAction:
+	case wallet.GetAction:
 		owner, err := runner.GetOwner(ctx, client)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Current owner address is %s\n", owner)
+		result := struct {
+			Owner string `json:"owner"`
+		}{Owner: owner}
+		return output.Print(result, func(w io.Writer) {
+			fmt.Fprintf(w, "Current owner address is %s\n", result.Owner)
+		})
 	default:
 		if targetAddress == "" {
-			return ErrInvalidOwnershipAddress
+			return errs.ErrMissingTargetAddress
 		}
-		err = runner.TransferOwner(ctx, client, targetAddress)
+
+		ctx, closeStore, err := setupRunner(ctx, "", runner)
 		if err != nil {
 			return err
 		}
-	}
+		defer closeStore()
 
-	return nil
+		result, err := runner.TransferOwner(ctx, client, targetAddress)
+		if err != nil {
+			return err
+		}
+		return printTransaction(result)
+	}
 }
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"io"
+)
+
+// idempotencyKeyUsage usage of the idempotency key flag
+const idempotencyKeyUsage = "Key identifying the request, retrying with the same key returns the original transaction instead of sending a new one"
+
+// transactionRunner runner signing transactions
+type transactionRunner interface {
+	SetStore(st *store.Store)
+	SetPolicy(p *policy.Policy)
+}
+
+// setupRunner sets the spending policy of the runner and opens the store, when configured, recording its
+// transactions in the outbox. The idempotency key and the requester are set in the context and the returned function
+// closes the store
+func setupRunner(ctx context.Context, key string, runner transactionRunner) (context.Context, func(), error) {
+	p, err := policy.New(config.App.Policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	runner.SetPolicy(p)
+
+	if config.App.Store.Path == "" {
+		if key != "" {
+			return nil, nil, errs.ErrIdempotencyStoreRequired
+		}
+		return ctx, func() {}, nil
+	}
+
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	runner.SetStore(st)
+
+	ctx = wallet.WithRequester(ctx, "cli:"+common.CurrentUser())
+	return wallet.WithIdempotencyKey(ctx, key), func() { _ = st.Close() }, nil
+}
+
+// printTransaction prints the mined transaction of an operation
+func printTransaction(result *wallet.TransactionResult) error {
+	return output.Print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "Transaction %s mined in block %d\n", result.TxHash, result.BlockNumber)
+	})
+}
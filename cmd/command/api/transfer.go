@@ -2,10 +2,11 @@ package api
 
 import (
 	"context"
-	"fmt"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/maxipaz/wallet/config"
-	"github.com/maxipaz/wallet/pkg/blockchain"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/wallet"
 	"github.com/spf13/cobra"
 )
 
@@ -17,34 +18,34 @@ var transferActions = map[string]struct{}{
 // NewTransferCommand creates the transfers command
 func NewTransferCommand(ctx context.Context) *cobra.Command {
 	var (
-		action        string
-		targetAddress string
-		amount        int64
+		action         string
+		targetAddress  string
+		amount         int64
+		idempotencyKey string
 	)
 
 	transfersCommand := &cobra.Command{
 		Use:   "transfer",
 		Short: "Perform transfer operations",
-		Run: func(cmd *cobra.Command, args []string) {
-
-			if err := runTransfers(ctx, action, targetAddress, amount); err != nil {
-				fmt.Println("ERROR: " + err.Error())
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTransfers(ctx, action, targetAddress, amount, idempotencyKey)
 		},
 	}
 
 	transfersCommand.Flags().StringVar(&action, "action", "", "Action to perform: send, receive")
 	transfersCommand.Flags().Int64Var(&amount, "amount", 0, "Amount")
 	transfersCommand.Flags().StringVarP(&targetAddress, "target.address", "t", "", "Target address")
+	contacts.MarkAddressFlag(transfersCommand.Flags(), "target.address")
+	transfersCommand.Flags().StringVar(&idempotencyKey, "idempotency-key", "", idempotencyKeyUsage)
 	_ = transfersCommand.MarkFlagRequired("action")
 	_ = transfersCommand.MarkFlagRequired("amount")
 
 	return transfersCommand
 }
 
-func runTransfers(ctx context.Context, action string, targetAddress string, amount int64) error {
+func runTransfers(ctx context.Context, action string, targetAddress string, amount int64, idempotencyKey string) error {
 	if _, ok := transferActions[action]; !ok {
-		return ErrInvalidTransferAction
+		return errs.ErrInvalidTransferAction
 	}
 	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
 	defer cancel()
@@ -52,24 +53,31 @@ func runTransfers(ctx context.Context, action string, targetAddress string, amou
 	if err != nil {
 		return err
 	}
-	runner := blockchain.NewTransfersRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
+	runner := wallet.NewTransfersRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
 
 	if amount <= 0 {
-		return ErrInvalidAmountAction
+		return errs.ErrInvalidAmountAction
+	}
+
+	ctx, closeStore, err := setupRunner(ctx, idempotencyKey, runner)
+	if err != nil {
+		return err
 	}
+	defer closeStore()
 
+	var result *wallet.TransactionResult
 	switch action {
-	case blockchain.SendAction:
+	case wallet.SendAction:
 		if targetAddress == "" {
-			return ErrInvalidOwnershipAddress
+			return errs.ErrMissingTargetAddress
 		}
-		err = runner.Send(ctx, client, targetAddress, amount)
-	case blockchain.ReceiveAction:
-		err = runner.Receive(ctx, client, amount)
+		result, err = runner.Send(ctx, client, targetAddress, amount)
+	case wallet.ReceiveAction:
+		result, err = runner.Receive(ctx, client, amount)
 	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return printTransaction(result)
 }
@@ -3,19 +3,43 @@ package command
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	"github.com/maxipaz/wallet/internal/ens"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/store"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"strconv"
+	"strings"
 )
 
+const (
+	// standaloneAnnotation marks the commands running without the configuration
+	standaloneAnnotation = "wallet_standalone"
+	// contractAnnotation marks the commands using the contract, besides those with the contract address flag
+	contractAnnotation = "wallet_contract"
+)
+
+// cleanups release the resources opened by the setup, once the command is done
+var cleanups []func()
+
 // NewRootCommand creates the root command
 func NewRootCommand(ctx context.Context) *cobra.Command {
 	rootCommand := &cobra.Command{
 		Use:   "wallet",
 		Short: "run the wallet service",
 
-		PersistentPreRunE: config.Setup,
+		// the errors are written by Execute in the selected output format
+		SilenceErrors:     true,
+		SilenceUsage:      true,
+		PersistentPreRunE: setup,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("command was not provided, please specify a command: deploy, monitor or run")
+			return fmt.Errorf("%w: command was not provided, please specify a command: allowance, config, contacts, deploy, deployments, events, monitor, outbox, proposals, reconcile, report, run, schedule, secrets, serve, spend-requests or transfer", errs.ErrInvalidUsage)
 		},
 	}
 
@@ -28,9 +52,170 @@ func NewRootCommand(ctx context.Context) *cobra.Command {
 	)
 
 	rootCommand.PersistentFlags().StringP("blockchain.pk", "k", "", "Account private key")
+	rootCommand.PersistentFlags().String("network", "", "Network profile, overriding the blockchain and contract settings")
+	rootCommand.PersistentFlags().StringVar(&output.Format, "output", output.TableFormat, "Output format of the results and errors: table, json or yaml")
+	rootCommand.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidUsage, err)
+	})
+
+	rootCommand.AddCommand(NewAllowanceCommand(ctx))
+	rootCommand.AddCommand(NewConfigCommand(ctx))
+	rootCommand.AddCommand(NewContactsCommand(ctx))
 	rootCommand.AddCommand(NewDeployCommand(ctx))
+	rootCommand.AddCommand(NewDeploymentsCommand(ctx))
+	rootCommand.AddCommand(NewEventsCommand(ctx))
 	rootCommand.AddCommand(NewMonitorCommand(ctx))
+	rootCommand.AddCommand(NewOutboxCommand(ctx))
+	rootCommand.AddCommand(NewProposalsCommand(ctx))
+	rootCommand.AddCommand(NewReconcileCommand(ctx))
+	rootCommand.AddCommand(NewReportCommand(ctx))
 	rootCommand.AddCommand(NewRunnerCommand(ctx))
+	rootCommand.AddCommand(NewScheduleCommand(ctx))
+	rootCommand.AddCommand(NewSecretsCommand(ctx))
+	rootCommand.AddCommand(NewServeCommand(ctx))
+	rootCommand.AddCommand(NewSpendRequestsCommand(ctx))
+	rootCommand.AddCommand(NewTransferCommand(ctx))
+	registerAddressCompletions(rootCommand)
 
 	return rootCommand
 }
+
+// Execute runs the command and writes its error, with its stable code, in the selected output format. It returns the
+// exit code of the kind of error, 0 on success
+func Execute(ctx context.Context) int {
+	rootCommand := NewRootCommand(ctx)
+
+	// the unknown commands and the invalid arguments are reported before the setup runs
+	var ready bool
+	rootCommand.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		ready = true
+		return setup(cmd, args)
+	}
+
+	cmd, err := rootCommand.ExecuteC()
+	for _, cleanup := range cleanups {
+		cleanup()
+	}
+	if err == nil {
+		return 0
+	}
+	if !ready && !errors.Is(err, errs.ErrInvalidUsage) {
+		err = fmt.Errorf("%w: %w", errs.ErrInvalidUsage, err)
+		_ = output.Validate()
+	}
+
+	code := output.Error(err)
+	if errors.Is(err, errs.ErrInvalidUsage) && !output.Structured() {
+		fmt.Fprint(os.Stderr, "\n"+cmd.UsageString())
+	}
+	return code
+}
+
+// setup checks the output format and the required flags, then loads the configuration unless the command is standalone
+func setup(cmd *cobra.Command, args []string) error {
+	if err := output.Validate(); err != nil {
+		return err
+	}
+	if missing := missingFlags(cmd); len(missing) > 0 {
+		return fmt.Errorf("%w: required flag(s) %s not set", errs.ErrInvalidUsage, strings.Join(missing, ", "))
+	}
+	if cmd.Annotations[standaloneAnnotation] == "true" {
+		return nil
+	}
+	if err := config.Setup(cmd, args); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidConfig, err)
+	}
+	if usesContract(cmd) {
+		if err := config.RequireContract(); err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrInvalidConfig, err)
+		}
+	}
+
+	book, err := contacts.Load(cmd.Context(), config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	if config.App.ENS.Registry != "" {
+		resolver, err := newENSResolver(cmd.Context())
+		if err != nil {
+			return err
+		}
+		book.SetLookup(resolver)
+	}
+	contacts.SetDefault(book)
+	return book.ResolveFlags(cmd.Flags())
+}
+
+// newENSResolver returns the ENS resolver of the configured registry, caching the lookups in the store when
+// configured. The resolved names are shown before the commands sign anything
+func newENSResolver(ctx context.Context) (*ens.Resolver, error) {
+	if err := common.ValidateAddress(config.App.ENS.Registry); err != nil {
+		return nil, fmt.Errorf("%w: ens registry: %w", errs.ErrInvalidConfig, err)
+	}
+
+	var st *store.Store
+	if config.App.Store.Path != "" {
+		var err error
+		if st, err = store.Open(ctx, config.App.Store.Path); err != nil {
+			return nil, err
+		}
+		cleanups = append(cleanups, func() { _ = st.Close() })
+	}
+
+	resolver := ens.New(ctx, config.App.ENS.Registry, config.App.Blockchain.WS, config.App.Blockchain.TimeoutIn, st, config.App.ENS.CacheTTLIn)
+	resolver.OnResolve = func(name string, address string) {
+		output.Progressf("Resolved %s to %s\n", name, address)
+	}
+	cleanups = append(cleanups, resolver.Close)
+	return resolver, nil
+}
+
+// registerAddressCompletions completes the address flags of the commands with the contact names and tags
+func registerAddressCompletions(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if _, ok := flag.Annotations[contacts.AddressAnnotation]; ok {
+			_ = cmd.RegisterFlagCompletionFunc(flag.Name, completeAddress)
+		}
+	})
+	for _, child := range cmd.Commands() {
+		registerAddressCompletions(child)
+	}
+}
+
+// completeAddress returns the contact names and tags of the address book, it completes nothing when the configuration
+// or the store cannot be read. The setup resolves no secret reference, no command or endpoint is run on completion
+func completeAddress(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if err := config.Setup(cmd, args); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	book, err := contacts.Load(cmd.Context(), config.App.Store.Path)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return book.Completions(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// usesContract reports whether the command uses the contract, requiring its address
+func usesContract(cmd *cobra.Command) bool {
+	if cmd.Flags().Lookup("contract.address") != nil {
+		return true
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[contractAnnotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// missingFlags returns the required flags of the command which were not set. Cobra checks them after the setup, they
+// are checked first to report them as usage errors
+func missingFlags(cmd *cobra.Command) []string {
+	var missing []string
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if required, ok := flag.Annotations[cobra.BashCompOneRequiredFlag]; ok && required[0] == "true" && !flag.Changed {
+			missing = append(missing, strconv.Quote(flag.Name))
+		}
+	})
+	return missing
+}
@@ -0,0 +1,166 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/batch"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// NewTransferCommand creates the transfer command
+func NewTransferCommand(ctx context.Context) *cobra.Command {
+	transferCommand := &cobra.Command{
+		Use:   "transfer",
+		Short: "Send payouts in bulk",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [batch]", errs.ErrInvalidUsage)
+		},
+	}
+
+	transferCommand.AddCommand(newBatchCommand(ctx, batch.TransferBatch))
+	return transferCommand
+}
+
+func newBatchCommand(ctx context.Context, kind string) *cobra.Command {
+	var (
+		file string
+		out  string
+	)
+
+	columns := "address, amount, action (send) and memo"
+	if kind == batch.AllowanceBatch {
+		columns = "address, amount, action (set, increase or reduce, set by default) and memo"
+	}
+
+	batchCommand := &cobra.Command{
+		Use:   "batch",
+		Short: "Run the " + kind + " operations of a CSV file, resuming it from its results file",
+		Long: "Run the " + kind + " operations of a CSV file with the " + columns + " columns. Every row is validated " +
+			"before the first one is submitted, the transactions are submitted with consecutive nonces and the result " +
+			"of each row is written to the results file. Running the batch again with the same results file resumes it.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if out == "" {
+				out = strings.TrimSuffix(file, filepath.Ext(file)) + ".results.csv"
+			}
+			return runBatch(ctx, kind, file, out)
+		},
+	}
+
+	batchCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	batchCommand.Flags().StringVarP(&file, "file", "f", "", "CSV file with the "+columns+" columns")
+	batchCommand.Flags().StringVarP(&out, "out", "o", "", "Results file, defaults to the file name ending with .results.csv")
+	_ = batchCommand.MarkFlagRequired("file")
+
+	return batchCommand
+}
+
+func runBatch(ctx context.Context, kind string, file string, out string) error {
+	b, err := batch.Read(file, kind, contacts.Default())
+	if err != nil {
+		return err
+	}
+
+	previous, id, err := batch.ReadResults(out)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		b.ID = id
+	}
+
+	if config.App.Store.Path == "" {
+		return errs.ErrBatchStoreRequired
+	}
+
+	pol, err := policy.New(config.App.Policy)
+	if err != nil {
+		return err
+	}
+
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	runner, err := batch.New(st, config.App.Blockchain.PrivateKey, config.App.Contract.Address, pol)
+	if err != nil {
+		return err
+	}
+
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := runner.Validate(ctx, client, b, previous); err != nil {
+		return err
+	}
+
+	if previous != nil {
+		output.Progressf("Resuming batch %s from %s\n", b.ID, out)
+	} else {
+		output.Progressf("Running batch %s: %d rows\n", b.ID, len(b.Rows))
+	}
+
+	ctx = wallet.WithRequester(ctx, "cli:"+common.CurrentUser())
+	results, runErr := runner.Run(ctx, client, b, previous, func(result batch.Result, results []batch.Result) {
+		printBatchResult(result)
+		if err := batch.WriteResults(out, b, results); err != nil {
+			slog.ErrorContext(ctx, "failed to write batch results", slog.String("error", err.Error()))
+		}
+	})
+	if err := batch.WriteResults(out, b, results); err != nil {
+		return err
+	}
+
+	result := struct {
+		Batch   string         `json:"batch"`
+		File    string         `json:"results_file"`
+		Count   map[string]int `json:"count"`
+		Results []batch.Result `json:"results"`
+	}{Batch: b.ID, File: out, Count: make(map[string]int), Results: results}
+	for _, row := range results {
+		result.Count[row.Status]++
+	}
+
+	if err := output.Print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "Batch %s: %d mined, %d failed, %d pending, %d not submitted, results written to %s\n",
+			b.ID, result.Count[batch.RowMined], result.Count[batch.RowFailed], result.Count[batch.RowPending], result.Count[batch.RowNotSubmitted], out)
+	}); err != nil {
+		return err
+	}
+	return runErr
+}
+
+// printBatchResult prints the progress of a row
+func printBatchResult(result batch.Result) {
+	line := fmt.Sprintf("line %d: %s %d ether %s: %s", result.Line, result.Action, result.Amount, contacts.Display(result.Address), result.Status)
+	if result.TxHash != "" {
+		line += " " + result.TxHash
+	}
+	if result.BlockNumber > 0 {
+		line += fmt.Sprintf(" in block %d", result.BlockNumber)
+	}
+	if result.Error != "" {
+		line += " (" + result.Error + ")"
+	}
+	output.Progressf("%s\n", line)
+}
@@ -0,0 +1,217 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/maxipaz/wallet/config"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/secrets"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// starterConfig configuration written by the config init command
+type starterConfig struct {
+	Blockchain struct {
+		Address string `yaml:"address"`
+		WS      string `yaml:"ws"`
+		PK      string `yaml:"pk"`
+		Timeout string `yaml:"timeout"`
+		ChainID uint64 `yaml:"chain_id,omitempty"`
+	} `yaml:"blockchain"`
+	Contract struct {
+		Address string `yaml:"address"`
+	} `yaml:"contract"`
+	Store struct {
+		Path string `yaml:"path"`
+	} `yaml:"store"`
+	Server struct {
+		Address string `yaml:"address"`
+	} `yaml:"server"`
+}
+
+// NewConfigCommand creates the config command
+func NewConfigCommand(ctx context.Context) *cobra.Command {
+	configCommand := &cobra.Command{
+		Use:   "config",
+		Short: "Show the effective configuration or create a starter one",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [init, show]", errs.ErrInvalidUsage)
+		},
+	}
+
+	configCommand.AddCommand(newConfigInitCommand(ctx))
+	configCommand.AddCommand(newConfigShowCommand(ctx))
+	return configCommand
+}
+
+func newConfigShowCommand(_ context.Context) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show the effective configuration with the source of each setting, the secrets redacted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			list := config.Settings()
+			result := struct {
+				File     string           `json:"file"`
+				Network  string           `json:"network,omitempty"`
+				Settings []config.Setting `json:"settings"`
+			}{File: config.Filename, Network: config.App.Network, Settings: output.List(list)}
+
+			return output.Print(result, func(w io.Writer) {
+				fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+				for _, setting := range list {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Key, formatSetting(setting.Value), setting.Source)
+				}
+			})
+		},
+	}
+}
+
+func newConfigInitCommand(_ context.Context) *cobra.Command {
+	var (
+		path  string
+		force bool
+	)
+
+	initCommand := &cobra.Command{
+		Use:   "init",
+		Short: "Create a starter configuration, asking for the main settings",
+		// there is no configuration to load yet
+		Annotations: map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := os.Stat(path); err == nil && !force {
+				return fmt.Errorf("%w: %s exists, please use --force to overwrite it", errs.ErrInvalidUsage, path)
+			}
+
+			starter, err := askStarter(bufio.NewReader(cmd.InOrStdin()), cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			var data bytes.Buffer
+			encoder := yaml.NewEncoder(&data)
+			encoder.SetIndent(2)
+			if err := encoder.Encode(starter); err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			// the file may hold the private key
+			if err := os.WriteFile(path, data.Bytes(), 0o600); err != nil {
+				return err
+			}
+
+			result := struct {
+				File string `json:"file"`
+			}{File: path}
+			return output.Print(result, func(w io.Writer) {
+				fmt.Fprintf(w, "Configuration written to %s\n", path)
+			})
+		},
+	}
+
+	initCommand.Flags().StringVar(&path, "file", "config/config.yaml", "Path of the configuration file to create")
+	initCommand.Flags().BoolVar(&force, "force", false, "Overwrite the file when it exists")
+	return initCommand
+}
+
+// askStarter asks for the settings of the starter configuration, the empty answers take the defaults
+func askStarter(in *bufio.Reader, w io.Writer) (*starterConfig, error) {
+	starter := &starterConfig{}
+	var err error
+
+	if starter.Blockchain.Address, err = ask(in, w, "blockchain.address", "Node URL", "http://127.0.0.1:8545"); err != nil {
+		return nil, err
+	}
+	wsDefault := strings.NewReplacer("https://", "wss://", "http://", "ws://").Replace(starter.Blockchain.Address)
+	if !strings.HasPrefix(wsDefault, "ws") {
+		wsDefault = ""
+	}
+	if starter.Blockchain.WS, err = ask(in, w, "blockchain.ws", "Node websocket URL, used by the monitor", wsDefault); err != nil {
+		return nil, err
+	}
+
+	chainID, err := ask(in, w, "blockchain.chain_id", "Expected chain ID, nothing is signed on another chain (empty to skip the check)", "")
+	if err != nil {
+		return nil, err
+	}
+	if chainID != "" {
+		starter.Blockchain.ChainID, _ = strconv.ParseUint(chainID, 10, 64)
+	}
+
+	if starter.Blockchain.PK, err = ask(in, w, "blockchain.pk", "Private key, preferably a reference: file:<path>, env:<name>, exec:<command>", "env:WALLET_PK"); err != nil {
+		return nil, err
+	}
+	if secrets.Scheme(starter.Blockchain.PK) == "" {
+		fmt.Fprintln(w, "The private key is written in the file, keep it out of version control")
+	}
+
+	if starter.Contract.Address, err = ask(in, w, "contract.address", "Contract address (empty when deploying it later)", ""); err != nil {
+		return nil, err
+	}
+	if starter.Store.Path, err = ask(in, w, "store.path", "Store path, keeping the outbox, the contacts and the history", "wallet.db"); err != nil {
+		return nil, err
+	}
+	if starter.Server.Address, err = ask(in, w, "server.address", "HTTP API address (empty to disable it)", ""); err != nil {
+		return nil, err
+	}
+
+	starter.Blockchain.Timeout = "5s"
+	return starter, nil
+}
+
+// ask asks for a setting until the answer is valid, the end of the input takes the default
+func ask(in *bufio.Reader, w io.Writer, path string, question string, fallback string) (string, error) {
+	for {
+		if fallback != "" {
+			fmt.Fprintf(w, "%s [%s]: ", question, fallback)
+		} else {
+			fmt.Fprintf(w, "%s: ", question)
+		}
+
+		line, err := in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = fallback
+		}
+
+		validationErr := config.ValidateField(path, answer)
+		if validationErr == nil {
+			return answer, nil
+		}
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(w)
+			return "", fmt.Errorf("%w: %w", errs.ErrInvalidConfig, validationErr)
+		}
+		fmt.Fprintln(w, validationErr)
+	}
+}
+
+// formatSetting formats the value of a setting in a table cell
+func formatSetting(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case []any, []map[string]any, map[string]any:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
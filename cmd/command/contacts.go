@@ -0,0 +1,166 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/spf13/cobra"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// NewContactsCommand creates the contacts command
+func NewContactsCommand(ctx context.Context) *cobra.Command {
+	contactsCommand := &cobra.Command{
+		Use:   "contacts",
+		Short: "Manage the address book, its names and tags are accepted anywhere an address is",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [add, list, remove]", errs.ErrInvalidUsage)
+		},
+	}
+
+	contactsCommand.AddCommand(newContactsAddCommand(ctx))
+	contactsCommand.AddCommand(newContactsListCommand(ctx))
+	contactsCommand.AddCommand(newContactsRemoveCommand(ctx))
+	return contactsCommand
+}
+
+func newContactsAddCommand(ctx context.Context) *cobra.Command {
+	var tags []string
+
+	addCommand := &cobra.Command{
+		Use:   "add NAME ADDRESS",
+		Short: "Add a named address to the address book",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contact := store.Contact{Name: args[0], Address: args[1], Tags: []string{}}
+			if err := contacts.ValidateName(contact.Name); err != nil {
+				return err
+			}
+			if err := common.ValidateAddress(contact.Address); err != nil {
+				return fmt.Errorf("%w: %s", err, contact.Address)
+			}
+			contact.Address = ethcommon.HexToAddress(contact.Address).Hex()
+
+			for _, tag := range tags {
+				if err := contacts.ValidateName(tag); err != nil {
+					return err
+				}
+				if !slices.ContainsFunc(contact.Tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+					contact.Tags = append(contact.Tags, tag)
+				}
+			}
+
+			return withContactsStore(ctx, func(st *store.Store) error {
+				added, err := st.AddContact(ctx, contact)
+				if err != nil {
+					return err
+				}
+				if !added {
+					return fmt.Errorf("%w: %s", errs.ErrDuplicateContact, contact.Name)
+				}
+
+				contact.CreatedAt = time.Now().Truncate(time.Second)
+				return output.Print(contact, func(w io.Writer) {
+					fmt.Fprintf(w, "Contact %s added for address %s\n", contact.Name, contact.Address)
+				})
+			})
+		},
+	}
+
+	addCommand.Flags().StringSliceVar(&tags, "tag", nil, "Tags of the contact, a tag naming several contacts expands to all of them in the lists of addresses")
+	return addCommand
+}
+
+func newContactsListCommand(ctx context.Context) *cobra.Command {
+	var tag string
+
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "List the contacts ordered by name",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withContactsStore(ctx, func(st *store.Store) error {
+				all, err := st.ListContacts(ctx)
+				if err != nil {
+					return err
+				}
+
+				var list []store.Contact
+				for _, contact := range all {
+					if tag == "" || slices.ContainsFunc(contact.Tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+						list = append(list, contact)
+					}
+				}
+
+				result := struct {
+					Contacts []store.Contact `json:"contacts"`
+				}{Contacts: output.List(list)}
+				return output.Print(result, func(w io.Writer) {
+					fmt.Fprintln(w, "NAME\tADDRESS\tTAGS\tCREATED")
+					for _, contact := range list {
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+							contact.Name,
+							contact.Address,
+							strings.Join(contact.Tags, ","),
+							contact.CreatedAt.Format(time.RFC3339),
+						)
+					}
+				})
+			})
+		},
+	}
+
+	listCommand.Flags().StringVar(&tag, "tag", "", "Only the contacts with the tag")
+	return listCommand
+}
+
+func newContactsRemoveCommand(ctx context.Context) *cobra.Command {
+	removeCommand := &cobra.Command{
+		Use:   "remove NAME",
+		Short: "Remove a contact from the address book",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withContactsStore(ctx, func(st *store.Store) error {
+				removed, err := st.RemoveContact(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				if !removed {
+					return fmt.Errorf("%w: %s", errs.ErrUnknownContact, args[0])
+				}
+
+				result := struct {
+					Removed string `json:"removed"`
+				}{Removed: args[0]}
+				return output.Print(result, func(w io.Writer) {
+					fmt.Fprintf(w, "Contact %s removed\n", args[0])
+				})
+			})
+		},
+	}
+
+	return removeCommand
+}
+
+// withContactsStore opens the store of the address book and calls the given function with it
+func withContactsStore(ctx context.Context, fn func(st *store.Store) error) error {
+	if config.App.Store.Path == "" {
+		return errs.ErrContactsStoreRequired
+	}
+
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	return fn(st)
+}
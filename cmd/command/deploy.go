@@ -2,43 +2,100 @@ package command
 
 import (
 	"context"
+	"fmt"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/maxipaz/wallet/config"
 	deploy2 "github.com/maxipaz/wallet/internal/deploy"
+	"github.com/maxipaz/wallet/internal/deployments"
+	"github.com/maxipaz/wallet/internal/output"
 	"github.com/spf13/cobra"
+	"io"
 	"log/slog"
+	"time"
 )
 
+// defaultDeployWaitTimeout maximum time to wait for the deployment to be mined, a block can take longer than the
+// blockchain timeout
+const defaultDeployWaitTimeout = 5 * time.Minute
+
 // NewDeployCommand creates the deploy command
 func NewDeployCommand(ctx context.Context) *cobra.Command {
+	var (
+		updateConfig bool
+		waitTimeout  time.Duration
+	)
+
 	deployCommand := &cobra.Command{
 		Use:   "deploy",
-		Short: "Deploy contract to blockchain",
+		Short: "Deploy contract to blockchain, recording the deployment in the deployments file",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return deploy(ctx)
+			return deploy(ctx, updateConfig, waitTimeout)
 		},
 	}
 
+	deployCommand.Flags().BoolVar(&updateConfig, "update-config", false, "Set the contract address of the selected network profile, or of the contract settings, in the configuration file")
+	deployCommand.Flags().DurationVar(&waitTimeout, "wait-timeout", defaultDeployWaitTimeout, "Maximum time to wait for the deployment to be mined")
 	return deployCommand
 }
 
-func deploy(ctx context.Context) error {
+func deploy(ctx context.Context, updateConfig bool, waitTimeout time.Duration) error {
 	slog.DebugContext(ctx, "deploying contract")
 
-	ctx, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
 	defer cancel()
 
-	client, err := ethclient.DialContext(ctx, config.App.Blockchain.Address)
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.Address)
 	if err != nil {
 		return err
 	}
 
 	deployer := deploy2.NewDeployer()
 
-	if err := deployer.Deploy(ctx, client); err != nil {
+	if err := deployer.Deploy(ctxCall, client); err != nil {
 		return err
 	}
 
-	slog.DebugContext(ctx, "contract deployed at address %s", deployer.ContractAddress())
-	return nil
+	ctxWait, cancelWait := context.WithTimeout(ctx, waitTimeout)
+	defer cancelWait()
+	if err := deployer.Wait(ctxWait, client); err != nil {
+		return fmt.Errorf("contract deployment at %s by transaction %s is not confirmed, check the transaction before deploying again: %w",
+			deployer.ContractAddress(), deployer.TxHash(), err)
+	}
+
+	slog.DebugContext(ctx, "contract deployed", slog.String("address", deployer.ContractAddress()))
+
+	deployment := deployments.Deployment{
+		Network:      config.App.Network,
+		ChainID:      deployer.ChainID(),
+		Address:      deployer.ContractAddress(),
+		TxHash:       deployer.TxHash(),
+		BlockNumber:  deployer.BlockNumber(),
+		Deployer:     deployer.From(),
+		BytecodeHash: deploy2.BytecodeHash(),
+		DeployedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	if err := deployments.Append(config.App.Deployments.Path, deployment); err != nil {
+		return fmt.Errorf("contract deployed at %s by transaction %s but the deployment was not recorded in %s: %w",
+			deployment.Address, deployment.TxHash, config.App.Deployments.Path, err)
+	}
+
+	result := struct {
+		ContractAddress string                 `json:"contract_address"`
+		Deployment      deployments.Deployment `json:"deployment"`
+		UpdatedSetting  string                 `json:"updated_setting,omitempty"`
+	}{ContractAddress: deployment.Address, Deployment: deployment}
+	if updateConfig {
+		result.UpdatedSetting, err = config.SaveContractAddress(deployment.Address)
+		if err != nil {
+			return fmt.Errorf("contract deployed at %s but the configuration was not updated: %w", deployment.Address, err)
+		}
+	}
+
+	return output.Print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "Contract deployed at %s in block %d, transaction %s\n", deployment.Address, deployment.BlockNumber, deployment.TxHash)
+		fmt.Fprintf(w, "Deployment recorded in %s\n", config.App.Deployments.Path)
+		if result.UpdatedSetting != "" {
+			fmt.Fprintf(w, "%s set in %s\n", result.UpdatedSetting, config.Filename)
+		}
+	})
 }
@@ -0,0 +1,104 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/deployments"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/spf13/cobra"
+	"io"
+	"strings"
+	"time"
+)
+
+// NewDeploymentsCommand creates the deployments command
+func NewDeploymentsCommand(ctx context.Context) *cobra.Command {
+	deploymentsCommand := &cobra.Command{
+		Use:   "deployments",
+		Short: "Browse the deployments of the contract recorded by the deploy command",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [list, show]", errs.ErrInvalidUsage)
+		},
+	}
+
+	deploymentsCommand.AddCommand(newDeploymentsListCommand(ctx))
+	deploymentsCommand.AddCommand(newDeploymentsShowCommand(ctx))
+	return deploymentsCommand
+}
+
+func newDeploymentsListCommand(_ context.Context) *cobra.Command {
+	var network string
+
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "List the deployments, the latest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, err := deployments.Load(config.App.Deployments.Path)
+			if err != nil {
+				return err
+			}
+
+			var list []deployments.Deployment
+			for i := len(all) - 1; i >= 0; i-- {
+				if network == "" || strings.EqualFold(all[i].Network, network) {
+					list = append(list, all[i])
+				}
+			}
+
+			result := struct {
+				Deployments []deployments.Deployment `json:"deployments"`
+			}{Deployments: output.List(list)}
+			return output.Print(result, func(w io.Writer) {
+				fmt.Fprintln(w, "DEPLOYED\tNETWORK\tCHAIN ID\tADDRESS\tBLOCK\tDEPLOYER")
+				for _, deployment := range list {
+					fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%s\n",
+						deployment.DeployedAt.Format(time.RFC3339),
+						valueOrDash(deployment.Network),
+						deployment.ChainID,
+						deployment.Address,
+						deployment.BlockNumber,
+						deployment.Deployer,
+					)
+				}
+			})
+		},
+	}
+
+	// not named network, the global flag selects the network profile
+	listCommand.Flags().StringVar(&network, "profile", "", "Only the deployments made with the network profile")
+	return listCommand
+}
+
+func newDeploymentsShowCommand(_ context.Context) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show ADDRESS|TX_HASH",
+		Short: "Show the latest deployment of a contract address or of a transaction",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, err := deployments.Load(config.App.Deployments.Path)
+			if err != nil {
+				return err
+			}
+			deployment, err := deployments.Find(all, args[0])
+			if err != nil {
+				return err
+			}
+
+			return output.Print(deployment, func(w io.Writer) {
+				fmt.Fprintf(w, "Contract %s deployed at %s\n", deployment.Address, deployment.DeployedAt.Format(time.RFC3339))
+				fmt.Fprintf(w, "  network: %s\n  chain id: %d\n  transaction: %s\n  block: %d\n  deployer: %s\n  bytecode hash: %s\n",
+					valueOrDash(deployment.Network), deployment.ChainID, deployment.TxHash, deployment.BlockNumber, deployment.Deployer, deployment.BytecodeHash)
+			})
+		},
+	}
+}
+
+// valueOrDash returns the value, or a dash when empty
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
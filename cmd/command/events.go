@@ -0,0 +1,163 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/spf13/cobra"
+	"io"
+	"time"
+)
+
+// dateLayout layout accepted for dates besides RFC3339
+const dateLayout = "2006-01-02"
+
+// NewEventsCommand creates the events command
+func NewEventsCommand(ctx context.Context) *cobra.Command {
+	eventsCommand := &cobra.Command{
+		Use:   "events",
+		Short: "Query the contract events recorded by the monitor",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [query]", errs.ErrInvalidUsage)
+		},
+	}
+
+	eventsCommand.AddCommand(newEventsQueryCommand(ctx))
+	return eventsCommand
+}
+
+func newEventsQueryCommand(ctx context.Context) *cobra.Command {
+	var (
+		filter    store.EventFilter
+		address   string
+		since     string
+		until     string
+		aggregate bool
+	)
+
+	queryCommand := &cobra.Command{
+		Use:   "query",
+		Short: "Query the recorded events by type, address, block or time range",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if address != "" {
+				if err := common.ValidateAddress(address); err != nil {
+					return err
+				}
+				filter.Address = ethcommon.HexToAddress(address).Hex()
+			}
+			if filter.Since, err = parseTime(since); err != nil {
+				return fmt.Errorf("%w: invalid since: %w", errs.ErrInvalidUsage, err)
+			}
+			if filter.Until, err = parseTime(until); err != nil {
+				return fmt.Errorf("%w: invalid until: %w", errs.ErrInvalidUsage, err)
+			}
+			filter.Contract = ethcommon.HexToAddress(config.App.Contract.Address).Hex()
+
+			return queryEvents(ctx, filter, aggregate)
+		},
+	}
+
+	queryCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	queryCommand.Flags().StringSliceVar(&filter.Types, "type", nil, "Event types: AllowanceChanged, MoneySent, MoneyReceived, OwnershipTransferred")
+	queryCommand.Flags().StringVarP(&address, "target.address", "t", "", "Beneficiary, sender or owner address")
+	contacts.MarkAddressFlag(queryCommand.Flags(), "target.address")
+	queryCommand.Flags().Uint64Var(&filter.FromBlock, "from-block", 0, "First block, included")
+	queryCommand.Flags().Uint64Var(&filter.ToBlock, "to-block", 0, "Last block, included")
+	queryCommand.Flags().StringVar(&since, "since", "", "Start time, included (RFC3339 or YYYY-MM-DD)")
+	queryCommand.Flags().StringVar(&until, "until", "", "End time, excluded (RFC3339 or YYYY-MM-DD)")
+	queryCommand.Flags().IntVar(&filter.Limit, "limit", 0, "Maximum number of events")
+	queryCommand.Flags().BoolVar(&aggregate, "by-beneficiary", false, "Aggregate payouts and allowances by beneficiary")
+
+	return queryCommand
+}
+
+// labelledEvent event with the contact names of its addresses
+type labelledEvent struct {
+	store.Event
+	AddressName      string `json:"address_name,omitempty"`
+	CounterpartyName string `json:"counterparty_name,omitempty"`
+}
+
+// labelledTotal beneficiary totals with the contact name of the beneficiary
+type labelledTotal struct {
+	store.BeneficiaryTotal
+	BeneficiaryName string `json:"beneficiary_name,omitempty"`
+}
+
+func queryEvents(ctx context.Context, filter store.EventFilter, aggregate bool) error {
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	if aggregate {
+		totals, err := st.AggregateByBeneficiary(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		result := struct {
+			Beneficiaries []labelledTotal `json:"beneficiaries"`
+		}{Beneficiaries: make([]labelledTotal, 0, len(totals))}
+		for _, total := range totals {
+			result.Beneficiaries = append(result.Beneficiaries, labelledTotal{BeneficiaryTotal: total, BeneficiaryName: contacts.Label(total.Beneficiary)})
+		}
+		return output.Print(result, func(w io.Writer) {
+			fmt.Fprintln(w, "BENEFICIARY\tPAYOUTS\tSENT (ETH)\tALLOWANCE (ETH)")
+			for _, total := range result.Beneficiaries {
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\n",
+					contacts.Labelled(total.Beneficiary, total.BeneficiaryName), total.Payouts, common.FormatEther(total.Sent), common.FormatEther(total.Allowance),
+				)
+			}
+		})
+	}
+
+	events, err := st.QueryEvents(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Events []labelledEvent `json:"events"`
+	}{Events: make([]labelledEvent, 0, len(events))}
+	for _, event := range events {
+		result.Events = append(result.Events, labelledEvent{
+			Event:            event,
+			AddressName:      contacts.Label(event.Address),
+			CounterpartyName: contacts.Label(event.Counterparty),
+		})
+	}
+	return output.Print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "BLOCK\tTIME\tEVENT\tADDRESS\tCOUNTERPARTY\tAMOUNT (ETH)\tTX")
+		for _, event := range result.Events {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				event.BlockNumber,
+				event.Timestamp.Format(time.RFC3339),
+				event.Type,
+				contacts.Labelled(event.Address, event.AddressName),
+				contacts.Labelled(event.Counterparty, event.CounterpartyName),
+				common.FormatEther(event.Amount),
+				event.TxHash,
+			)
+		}
+	})
+}
+
+// parseTime parse a RFC3339 time or a date, an empty value returns the zero time
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(dateLayout, value)
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/alerts"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"github.com/maxipaz/wallet/internal/store"
 	"github.com/maxipaz/wallet/internal/wallet"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
 )
 
 // NewMonitorCommand creates the monitor command
@@ -19,6 +24,7 @@ func NewMonitorCommand(ctx context.Context) *cobra.Command {
 	}
 
 	monitorCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	monitorCommand.Flags().String("metrics.address", "", "Address to expose the /metrics endpoint, i.e.: :9100")
 	return monitorCommand
 }
 
@@ -31,5 +37,64 @@ func monitoring(ctx context.Context) error {
 		return err
 	}
 
-	return wallet.NewMonitor(config.App.Contract.Address).Start(ctx, client)
+	monitor := wallet.NewMonitor(config.App.Contract.Address)
+
+	var engine *alerts.Engine
+	if len(config.App.Alerts.Rules) > 0 {
+		engine, err = alerts.NewEngine(client, config.App.Contract.Address, config.App.Alerts)
+		if err != nil {
+			return err
+		}
+
+		if err := engine.Load(ctx); err != nil {
+			return err
+		}
+		monitor.AddHandler(engine)
+	}
+
+	var recorder *wallet.Recorder
+	if config.App.Store.Path != "" {
+		st, err := store.Open(ctx, config.App.Store.Path)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		chainID, err := client.ChainID(ctx)
+		if err != nil {
+			return err
+		}
+
+		recorder = wallet.NewRecorder(st, client, chainID.Uint64(), config.App.Contract.Address)
+		monitor.AddHandler(recorder)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	if config.App.Metrics.Address != "" {
+		eg.Go(func() error {
+			return metrics.Serve(ctx, config.App.Metrics.Address)
+		})
+	}
+
+	if engine != nil {
+		eg.Go(func() error {
+			return engine.Start(ctx)
+		})
+	}
+
+	if recorder != nil {
+		// a failed backfill leaves a gap in the history, the live events are still recorded
+		eg.Go(func() error {
+			if err := recorder.Backfill(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "failed to backfill events", slog.String("error", err.Error()))
+			}
+			return nil
+		})
+	}
+
+	eg.Go(func() error {
+		return monitor.Start(ctx, client)
+	})
+
+	return eg.Wait()
 }
@@ -0,0 +1,162 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// NewOutboxCommand creates the outbox command
+func NewOutboxCommand(ctx context.Context) *cobra.Command {
+	outboxCommand := &cobra.Command{
+		Use:   "outbox",
+		Short: "Inspect and resume the signed transactions recorded before broadcasting",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [list, resume]", errs.ErrInvalidUsage)
+		},
+	}
+
+	outboxCommand.AddCommand(newOutboxListCommand(ctx))
+	outboxCommand.AddCommand(newOutboxResumeCommand(ctx))
+	return outboxCommand
+}
+
+func newOutboxListCommand(ctx context.Context) *cobra.Command {
+	var (
+		filter store.TransactionFilter
+		since  string
+	)
+
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "List the recorded transactions by status, operation or time",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if filter.Since, err = parseTime(since); err != nil {
+				return fmt.Errorf("%w: invalid since: %w", errs.ErrInvalidUsage, err)
+			}
+			return listTransactions(ctx, filter)
+		},
+	}
+
+	listCommand.Flags().StringSliceVar(&filter.Statuses, "status", nil, "Statuses: signed, pending, mined, failed, replaced, dropped")
+	listCommand.Flags().StringSliceVar(&filter.Operations, "operation", nil, "Operations, i.e.: send_money, set_allowance")
+	listCommand.Flags().StringVar(&since, "since", "", "Start time, included (RFC3339 or YYYY-MM-DD)")
+	listCommand.Flags().IntVar(&filter.Limit, "limit", 0, "Maximum number of transactions")
+
+	return listCommand
+}
+
+// labelledTransaction outbox transaction with the contact name of its target
+type labelledTransaction struct {
+	store.Transaction
+	TargetName string `json:"target_name,omitempty"`
+}
+
+func listTransactions(ctx context.Context, filter store.TransactionFilter) error {
+	if config.App.Store.Path == "" {
+		return errs.ErrOutboxStoreRequired
+	}
+
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	transactions, err := st.ListTransactions(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Transactions []labelledTransaction `json:"transactions"`
+	}{Transactions: make([]labelledTransaction, 0, len(transactions))}
+	for _, tx := range transactions {
+		result.Transactions = append(result.Transactions, labelledTransaction{Transaction: tx, TargetName: contacts.Label(tx.Target)})
+	}
+	return output.Print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "CREATED\tSTATUS\tOPERATION\tTARGET\tAMOUNT (ETH)\tNONCE\tATTEMPTS\tREQUESTER\tTX")
+		for _, tx := range result.Transactions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+				tx.CreatedAt.Format(time.RFC3339),
+				tx.Status,
+				tx.Operation,
+				contacts.Labelled(tx.Target, tx.TargetName),
+				common.FormatEther(tx.Amount),
+				tx.Nonce,
+				tx.Attempts,
+				tx.Requester,
+				tx.Hash,
+			)
+		}
+	})
+}
+
+func newOutboxResumeCommand(ctx context.Context) *cobra.Command {
+	resumeCommand := &cobra.Command{
+		Use:   "resume",
+		Short: "Track the pending transactions until they are final, broadcasting again the dropped ones",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return resumeOutbox(ctx)
+		},
+	}
+
+	resumeCommand.Flags().String("store.outbox_interval", "", "Time between two checks of the pending transactions")
+	return resumeCommand
+}
+
+func resumeOutbox(ctx context.Context) error {
+	if config.App.Store.Path == "" {
+		return errs.ErrOutboxStoreRequired
+	}
+
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.Address)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	outbox := wallet.NewOutbox(st)
+	for {
+		pending, err := outbox.Resume(ctx, client)
+		if err != nil {
+			return err
+		}
+		if pending == 0 {
+			result := struct {
+				Pending int `json:"pending"`
+			}{Pending: pending}
+			return output.Print(result, func(w io.Writer) {
+				fmt.Fprintln(w, "No pending transactions left")
+			})
+		}
+		slog.InfoContext(ctx, "waiting for pending transactions", slog.Int("pending", pending))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(config.App.Store.OutboxIntervalIn):
+		}
+	}
+}
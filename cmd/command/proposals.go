@@ -0,0 +1,288 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/approval"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/spf13/cobra"
+	"io"
+	"strings"
+	"time"
+)
+
+// NewProposalsCommand creates the proposals command
+func NewProposalsCommand(ctx context.Context) *cobra.Command {
+	proposalsCommand := &cobra.Command{
+		Use:   "proposals",
+		Short: "Propose, approve and execute the operations requiring multiple approvals",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [create, approve, reject, execute, list, show]", errs.ErrInvalidUsage)
+		},
+	}
+
+	proposalsCommand.AddCommand(newProposalsCreateCommand(ctx))
+	proposalsCommand.AddCommand(newProposalsDecisionCommand(ctx, store.Approve))
+	proposalsCommand.AddCommand(newProposalsDecisionCommand(ctx, store.Reject))
+	proposalsCommand.AddCommand(newProposalsExecuteCommand(ctx))
+	proposalsCommand.AddCommand(newProposalsListCommand(ctx))
+	proposalsCommand.AddCommand(newProposalsShowCommand(ctx))
+	return proposalsCommand
+}
+
+func newProposalsCreateCommand(ctx context.Context) *cobra.Command {
+	var (
+		operation     string
+		targetAddress string
+		amount        int64
+	)
+
+	createCommand := &cobra.Command{
+		Use:   "create",
+		Short: "Create a proposal waiting for the approvers decisions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withWorkflow(ctx, func(w *approval.Workflow) error {
+				proposal, err := w.Create(ctx, operation, targetAddress, amount, "cli:"+common.CurrentUser())
+				if err != nil {
+					return err
+				}
+				return printProposal(w, proposal)
+			})
+		},
+	}
+
+	createCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	createCommand.Flags().StringVar(&operation, "operation", "", "Operation: send_money, set_allowance, increase_allowance or transfer_ownership")
+	createCommand.Flags().StringVarP(&targetAddress, "target.address", "t", "", "Beneficiary or new owner address")
+	contacts.MarkAddressFlag(createCommand.Flags(), "target.address")
+	createCommand.Flags().Int64Var(&amount, "amount", 0, "Amount in ether")
+	_ = createCommand.MarkFlagRequired("operation")
+	_ = createCommand.MarkFlagRequired("target.address")
+
+	return createCommand
+}
+
+func newProposalsDecisionCommand(ctx context.Context, decision string) *cobra.Command {
+	var (
+		approver  string
+		signature string
+		key       string
+	)
+
+	decisionCommand := &cobra.Command{
+		Use:   decision + " ID",
+		Short: "Record the signed " + decision + " decision of an approver",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (signature == "") == (key == "") {
+				return fmt.Errorf("%w: please specify either the signature or the approver key", errs.ErrInvalidUsage)
+			}
+
+			return withWorkflow(ctx, func(w *approval.Workflow) error {
+				if key != "" {
+					proposal, err := w.Get(ctx, args[0])
+					if err != nil {
+						return err
+					}
+
+					privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+					if err != nil {
+						return fmt.Errorf("%w: approver key: %w", errs.ErrInvalidKey, err)
+					}
+					if signature, err = w.Sign(proposal, decision, privateKey); err != nil {
+						return err
+					}
+				}
+
+				proposal, err := w.Decide(ctx, args[0], approver, decision, signature)
+				if err != nil {
+					return err
+				}
+				return printProposal(w, proposal)
+			})
+		},
+	}
+
+	decisionCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	decisionCommand.Flags().StringVar(&approver, "approver", "", "Approver name")
+	decisionCommand.Flags().StringVar(&signature, "signature", "", "Approver personal_sign signature of the decision message shown by proposals show")
+	decisionCommand.Flags().StringVar(&key, "key", "", "Approver private key, signs the decision message instead of passing the signature")
+	_ = decisionCommand.MarkFlagRequired("approver")
+
+	return decisionCommand
+}
+
+func newProposalsExecuteCommand(ctx context.Context) *cobra.Command {
+	executeCommand := &cobra.Command{
+		Use:   "execute ID",
+		Short: "Sign and send the operation of an approved proposal",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return executeProposal(ctx, args[0])
+		},
+	}
+
+	executeCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	return executeCommand
+}
+
+func executeProposal(ctx context.Context, id string) error {
+	pol, err := policy.New(config.App.Policy)
+	if err != nil {
+		return err
+	}
+
+	return withWorkflow(ctx, func(w *approval.Workflow) error {
+		ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+		defer cancel()
+
+		client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		proposal, err := w.Execute(ctx, client, config.App.Blockchain.PrivateKey, pol, id)
+		if err != nil {
+			return err
+		}
+
+		return output.Print(proposal, func(w io.Writer) {
+			fmt.Fprintf(w, "Proposal %s executed in transaction %s\n", proposal.ID, proposal.TxHash)
+		})
+	})
+}
+
+func newProposalsListCommand(ctx context.Context) *cobra.Command {
+	var statuses []string
+
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "List the proposals, the latest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listProposals(ctx, statuses)
+		},
+	}
+
+	listCommand.Flags().StringSliceVar(&statuses, "status", nil, "Statuses: pending, approved, rejected, executing, executed, expired")
+	return listCommand
+}
+
+// labelledProposal proposal with the contact name of its target
+type labelledProposal struct {
+	store.Proposal
+	TargetName string `json:"target_name,omitempty"`
+}
+
+func listProposals(ctx context.Context, statuses []string) error {
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	proposals, err := st.ListProposals(ctx, statuses)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Proposals []labelledProposal `json:"proposals"`
+	}{Proposals: make([]labelledProposal, 0, len(proposals))}
+	for _, proposal := range proposals {
+		result.Proposals = append(result.Proposals, labelledProposal{Proposal: proposal, TargetName: contacts.Label(proposal.Target)})
+	}
+	return output.Print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tCREATED\tSTATUS\tOPERATION\tTARGET\tAMOUNT (ETH)\tPROPOSER\tEXPIRES")
+		for _, proposal := range result.Proposals {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				proposal.ID,
+				proposal.CreatedAt.Format(time.RFC3339),
+				proposal.Status,
+				proposal.Operation,
+				contacts.Labelled(proposal.Target, proposal.TargetName),
+				proposal.Amount,
+				proposal.Proposer,
+				proposal.ExpiresAt.Format(time.RFC3339),
+			)
+		}
+	})
+}
+
+func newProposalsShowCommand(ctx context.Context) *cobra.Command {
+	showCommand := &cobra.Command{
+		Use:   "show ID",
+		Short: "Show a proposal, its decisions and the messages the approvers sign",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withWorkflow(ctx, func(w *approval.Workflow) error {
+				proposal, err := w.Get(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return printProposal(w, proposal)
+			})
+		},
+	}
+
+	showCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	return showCommand
+}
+
+// withWorkflow opens the store recording the proposals and calls the given function with the approval workflow
+func withWorkflow(ctx context.Context, fn func(w *approval.Workflow) error) error {
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	w, err := approval.New(st, config.App.Contract.Address, config.App.Policy.Approvals)
+	if err != nil {
+		return err
+	}
+	return fn(w)
+}
+
+// printProposal prints the proposal details, its decisions and, while it is pending, the messages to sign
+func printProposal(w *approval.Workflow, proposal *store.Proposal) error {
+	result := struct {
+		*store.Proposal
+		Messages map[string]string `json:"messages,omitempty"`
+	}{Proposal: proposal}
+	if proposal.Status == store.ProposalPending {
+		result.Messages = map[string]string{
+			store.Approve: w.Message(proposal, store.Approve),
+			store.Reject:  w.Message(proposal, store.Reject),
+		}
+	}
+
+	return output.Print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "Proposal %s is %s\n", proposal.ID, proposal.Status)
+		fmt.Fprintf(w, "  operation: %s\n  target: %s\n  amount: %d ether\n  proposer: %s\n  expires: %s\n",
+			proposal.Operation, contacts.Display(proposal.Target), proposal.Amount, proposal.Proposer, proposal.ExpiresAt.Format(time.RFC3339))
+		if proposal.TxHash != "" {
+			fmt.Fprintf(w, "  transaction: %s\n", proposal.TxHash)
+		}
+		if proposal.Error != "" {
+			fmt.Fprintf(w, "  last error: %s\n", proposal.Error)
+		}
+		for _, decision := range proposal.Decisions {
+			fmt.Fprintf(w, "  %s: %s (%s)\n", decision.Approver, decision.Decision, decision.CreatedAt.Format(time.RFC3339))
+		}
+
+		for _, decision := range []string{store.Approve, store.Reject} {
+			if message, ok := result.Messages[decision]; ok {
+				fmt.Fprintf(w, "\nMessage to sign to %s:\n%s\n", decision, message)
+			}
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"github.com/spf13/cobra"
+	"io"
+)
+
+// NewReconcileCommand creates the reconcile command
+func NewReconcileCommand(ctx context.Context) *cobra.Command {
+	var block uint64
+
+	reconcileCommand := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compare the on-chain allowances and balance with the replayed events history",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reconcile(ctx, block)
+		},
+	}
+
+	reconcileCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	reconcileCommand.Flags().Uint64Var(&block, "block", 0, "Block to reconcile at, the latest one when not provided")
+
+	return reconcileCommand
+}
+
+func reconcile(ctx context.Context, block uint64) error {
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var at *uint64
+	if block != 0 {
+		at = &block
+	}
+
+	result, err := wallet.NewReconcileRunner(config.App.Contract.Address).Reconcile(ctx, client, at)
+	if err != nil {
+		return err
+	}
+
+	report := struct {
+		*wallet.Reconciliation
+		Matches bool `json:"matches"`
+	}{result, result.Matches()}
+	report.Allowances = output.List(report.Allowances)
+	report.Issues = output.List(report.Issues)
+
+	if err := output.Print(report, func(w io.Writer) {
+		fmt.Fprintf(w, "Reconciliation at block %d\n\n", result.Block)
+		fmt.Fprintln(w, "ITEM\tEXPECTED (ETH)\tON-CHAIN (ETH)\tSTATUS\tFIRST BLOCK")
+		fmt.Fprintf(w, "contract balance\t%s\t%s\t%s\t%s\n",
+			common.FormatEther(result.ExpectedBalance),
+			common.FormatEther(result.ActualBalance),
+			status(result.BalanceMatches()),
+			firstBlock(result.BalanceMatches(), result.BalanceFirstBlock),
+		)
+		for _, allowance := range result.Allowances {
+			fmt.Fprintf(w, "allowance %s\t%s\t%s\t%s\t%s\n",
+				contacts.Display(allowance.Beneficiary),
+				common.FormatEther(allowance.Expected),
+				common.FormatEther(allowance.Actual),
+				status(allowance.Matches()),
+				firstBlock(allowance.Matches(), allowance.FirstBlock),
+			)
+		}
+
+		if len(result.Issues) > 0 {
+			fmt.Fprintln(w, "\nHISTORY ISSUES\t\t\t\t")
+			for _, issue := range result.Issues {
+				fmt.Fprintf(w, "block %d\t%s\t%s\t\t\n", issue.Block, issue.TxHash, issue.Description)
+			}
+		}
+	}); err != nil {
+		return err
+	}
+
+	if !result.Matches() {
+		return errs.ErrReconciliationMismatch
+	}
+	return nil
+}
+
+func status(matches bool) string {
+	if matches {
+		return "ok"
+	}
+	return "DRIFT"
+}
+
+func firstBlock(matches bool, block *uint64) string {
+	switch {
+	case matches:
+		return "-"
+	case block == nil:
+		return "unknown"
+	}
+	return fmt.Sprintf("%d", *block)
+}
@@ -0,0 +1,131 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/maxipaz/wallet/config"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/report"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"time"
+)
+
+// monthLayout layout of the report month flag
+const monthLayout = "2006-01"
+
+// NewReportCommand creates the report command
+func NewReportCommand(ctx context.Context) *cobra.Command {
+	reportCommand := &cobra.Command{
+		Use:   "report",
+		Short: "Build reports from the recorded contract events",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [spend]", errs.ErrInvalidUsage)
+		},
+	}
+
+	reportCommand.AddCommand(newSpendReportCommand(ctx))
+	return reportCommand
+}
+
+func newSpendReportCommand(ctx context.Context) *cobra.Command {
+	var (
+		month  string
+		since  string
+		until  string
+		format string
+		file   string
+	)
+
+	spendCommand := &cobra.Command{
+		Use:   "spend",
+		Short: "Per beneficiary allowance and payouts for a period",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := report.Formats[format]; !ok && format != "" {
+				return fmt.Errorf("%w: %s", errs.ErrInvalidReportFormat, format)
+			}
+
+			from, to, err := reportPeriod(month, since, until)
+			if err != nil {
+				return err
+			}
+
+			return spendReport(ctx, from, to, format, file)
+		},
+	}
+
+	spendCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	spendCommand.Flags().StringVar(&month, "month", "", "Report month (YYYY-MM), alternative to since and until")
+	spendCommand.Flags().StringVar(&since, "since", "", "Period start, included (RFC3339 or YYYY-MM-DD)")
+	spendCommand.Flags().StringVar(&until, "until", "", "Period end, excluded (RFC3339 or YYYY-MM-DD)")
+	spendCommand.Flags().StringVar(&format, "format", "", "Export format: csv, json or markdown, by default the json or yaml output format, csv otherwise")
+	spendCommand.Flags().StringVar(&file, "file", "", "Write the report to a file instead of the standard output")
+
+	return spendCommand
+}
+
+func spendReport(ctx context.Context, since time.Time, until time.Time, format string, file string) error {
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	contract := ethcommon.HexToAddress(config.App.Contract.Address).Hex()
+	spend, err := report.Spend(ctx, st, contract, since, until)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// the report follows the global output format unless an export format is given
+	switch {
+	case format == "" && output.Structured():
+		return output.Encode(w, spend)
+	case format == "":
+		format = report.CSVFormat
+	}
+	return spend.Write(w, format)
+}
+
+// reportPeriod returns the period boundaries from either a month or a since/until pair
+func reportPeriod(month string, since string, until string) (time.Time, time.Time, error) {
+	if month != "" {
+		if since != "" || until != "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: month cannot be combined with since or until", errs.ErrInvalidReportPeriod)
+		}
+
+		from, err := time.Parse(monthLayout, month)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: %w", errs.ErrInvalidReportPeriod, err)
+		}
+		return from, from.AddDate(0, 1, 0), nil
+	}
+
+	from, err := parseTime(since)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %w", errs.ErrInvalidReportPeriod, err)
+	}
+	to, err := parseTime(until)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %w", errs.ErrInvalidReportPeriod, err)
+	}
+	if !to.IsZero() && !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: until must be after since", errs.ErrInvalidReportPeriod)
+	}
+
+	return from, to, nil
+}
@@ -2,8 +2,9 @@ package command
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"github.com/maxipaz/wallet/cmd/command/api"
+	errs "github.com/maxipaz/wallet/internal/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -12,8 +13,10 @@ func NewRunnerCommand(ctx context.Context) *cobra.Command {
 	runCommand := &cobra.Command{
 		Use:   "run",
 		Short: "Run contract methods in the blockchain",
+		// every subcommand calls the contract
+		Annotations: map[string]string{contractAnnotation: "true"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("please specify a subcommand: [allowance, balance, ownership or transfer]")
+			return fmt.Errorf("%w: please specify a subcommand: [allowance, balance, ownership or transfer]", errs.ErrInvalidUsage)
 		},
 	}
 
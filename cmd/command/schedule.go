@@ -0,0 +1,246 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/scheduler"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/spf13/cobra"
+	"io"
+	"strings"
+	"time"
+)
+
+// scheduleResult configured schedule with its last and next runs, the amount is expressed in Ether
+type scheduleResult struct {
+	Name          string             `json:"name"`
+	Cron          string             `json:"cron"`
+	Action        string             `json:"action"`
+	Amount        int64              `json:"amount"`
+	Beneficiaries []string           `json:"beneficiaries"`
+	LastRun       *store.ScheduleRun `json:"last_run,omitempty"`
+	NextRun       time.Time          `json:"next_run"`
+}
+
+// NewScheduleCommand creates the schedule command
+func NewScheduleCommand(ctx context.Context) *cobra.Command {
+	scheduleCommand := &cobra.Command{
+		Use:   "schedule",
+		Short: "Apply recurring allowance changes configured as cron schedules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [list, run-now, history, start]", errs.ErrInvalidUsage)
+		},
+	}
+
+	scheduleCommand.AddCommand(newScheduleListCommand(ctx))
+	scheduleCommand.AddCommand(newScheduleRunNowCommand(ctx))
+	scheduleCommand.AddCommand(newScheduleHistoryCommand(ctx))
+	scheduleCommand.AddCommand(newScheduleStartCommand(ctx))
+	return scheduleCommand
+}
+
+func newScheduleListCommand(ctx context.Context) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the configured schedules with their last and next runs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listSchedules(ctx)
+		},
+	}
+}
+
+func listSchedules(ctx context.Context) error {
+	schedules, err := scheduler.Parse(config.App.Schedules)
+	if err != nil {
+		return err
+	}
+
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	now := time.Now()
+	result := struct {
+		Schedules []scheduleResult `json:"schedules"`
+	}{Schedules: make([]scheduleResult, 0, len(schedules))}
+	for _, schedule := range schedules {
+		runs, err := st.ListScheduleRuns(ctx, store.ScheduleRunFilter{Schedule: schedule.Name, Limit: 1})
+		if err != nil {
+			return err
+		}
+
+		item := scheduleResult{
+			Name:          schedule.Name,
+			Cron:          schedule.Cron,
+			Action:        schedule.Action,
+			Amount:        schedule.Amount,
+			Beneficiaries: schedule.Beneficiaries,
+			NextRun:       schedule.Next(now),
+		}
+		if len(runs) > 0 {
+			item.LastRun = &runs[0]
+		}
+		result.Schedules = append(result.Schedules, item)
+	}
+
+	return output.Print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tCRON\tACTION\tAMOUNT (ETH)\tBENEFICIARIES\tLAST RUN\tNEXT RUN")
+		for _, schedule := range result.Schedules {
+			lastRun := "-"
+			if schedule.LastRun != nil {
+				lastRun = fmt.Sprintf("%s (%s)", schedule.LastRun.ScheduledAt.Format(time.RFC3339), schedule.LastRun.Status)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+				schedule.Name,
+				schedule.Cron,
+				schedule.Action,
+				schedule.Amount,
+				len(schedule.Beneficiaries),
+				lastRun,
+				schedule.NextRun.Format(time.RFC3339),
+			)
+		}
+	})
+}
+
+func newScheduleRunNowCommand(ctx context.Context) *cobra.Command {
+	runNowCommand := &cobra.Command{
+		Use:   "run-now NAME",
+		Short: "Run a schedule immediately, without changing its scheduled runs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runScheduleNow(ctx, args[0])
+		},
+	}
+
+	runNowCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	return runNowCommand
+}
+
+func runScheduleNow(ctx context.Context, name string) error {
+	return withScheduler(ctx, func(s *scheduler.Scheduler) error {
+		// a failed run is printed, its error keeps the classification of the beneficiary errors
+		run, err := s.RunNow(ctx, name)
+		if run == nil {
+			return err
+		}
+
+		if printErr := output.Print(run, func(w io.Writer) {
+			fmt.Fprintf(w, "Schedule %s %s: %d applied, %d failed\n", run.Schedule, run.Status, run.Applied, run.Failed)
+		}); printErr != nil {
+			return printErr
+		}
+		if err == nil && run.Error != "" {
+			// the run was already recorded in the same second, only its message is known
+			return errors.New(run.Error)
+		}
+		return err
+	})
+}
+
+func newScheduleHistoryCommand(ctx context.Context) *cobra.Command {
+	var filter store.ScheduleRunFilter
+
+	historyCommand := &cobra.Command{
+		Use:   "history",
+		Short: "List the recorded schedule runs, the latest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return scheduleHistory(ctx, filter)
+		},
+	}
+
+	historyCommand.Flags().StringVar(&filter.Schedule, "schedule", "", "Schedule name")
+	historyCommand.Flags().StringSliceVar(&filter.Statuses, "status", nil, "Statuses: running, succeeded, failed")
+	historyCommand.Flags().IntVar(&filter.Limit, "limit", 20, "Maximum number of runs")
+
+	return historyCommand
+}
+
+func scheduleHistory(ctx context.Context, filter store.ScheduleRunFilter) error {
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	runs, err := st.ListScheduleRuns(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Runs []store.ScheduleRun `json:"runs"`
+	}{Runs: output.List(runs)}
+	return output.Print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "SCHEDULED\tSCHEDULE\tTRIGGER\tMISSED\tSTATUS\tAPPLIED\tFAILED\tERROR")
+		for _, run := range runs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%d\t%s\n",
+				run.ScheduledAt.Format(time.RFC3339),
+				run.Schedule,
+				run.Trigger,
+				run.Missed,
+				run.Status,
+				run.Applied,
+				run.Failed,
+				strings.ReplaceAll(run.Error, "\n", " "),
+			)
+		}
+	})
+}
+
+func newScheduleStartCommand(ctx context.Context) *cobra.Command {
+	startCommand := &cobra.Command{
+		Use:   "start",
+		Short: "Run the schedules at their scheduled times, catching up the runs missed while stopped",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withScheduler(ctx, func(s *scheduler.Scheduler) error {
+				return s.Start(ctx)
+			})
+		},
+	}
+
+	startCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	return startCommand
+}
+
+// withScheduler opens the store and the blockchain connection used by the scheduler and calls the given function
+func withScheduler(ctx context.Context, fn func(s *scheduler.Scheduler) error) error {
+	if config.App.Store.Path == "" {
+		return errs.ErrScheduleStoreRequired
+	}
+
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	p, err := policy.New(config.App.Policy)
+	if err != nil {
+		return err
+	}
+
+	s, err := scheduler.NewScheduler(client, config.App.Blockchain.PrivateKey, config.App.Contract.Address, st, p, config.App.Schedules)
+	if err != nil {
+		return err
+	}
+	return fn(s)
+}
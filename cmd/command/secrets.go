@@ -0,0 +1,83 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/secrets"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// NewSecretsCommand creates the secrets command
+func NewSecretsCommand(ctx context.Context) *cobra.Command {
+	secretsCommand := &cobra.Command{
+		Use:   "secrets",
+		Short: "Tools for the secret references of the configuration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [serve]", errs.ErrInvalidUsage)
+		},
+	}
+
+	secretsCommand.AddCommand(newSecretsServeCommand(ctx))
+	return secretsCommand
+}
+
+func newSecretsServeCommand(ctx context.Context) *cobra.Command {
+	var (
+		file    string
+		address string
+		token   string
+	)
+
+	serveCommand := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve secrets from a YAML file as a local stand-in for a secrets endpoint, for development and tests",
+		// the configuration is not loaded, it may reference this endpoint
+		Annotations: map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			var values map[string]string
+			if err := yaml.Unmarshal(data, &values); err != nil {
+				return fmt.Errorf("%w: secrets file %s: %w", errs.ErrInvalidUsage, file, err)
+			}
+
+			if token != "" {
+				token, err = secrets.Resolve(ctx, token, secrets.Options{Timeout: 10 * time.Second})
+				if err != nil {
+					return err
+				}
+			}
+
+			server := &http.Server{
+				Addr:              address,
+				Handler:           secrets.StandIn(values, token),
+				ReadHeaderTimeout: 5 * time.Second,
+			}
+			go func() {
+				<-ctx.Done()
+				_ = server.Close()
+			}()
+
+			slog.InfoContext(ctx, "serving secrets", slog.String("address", address), slog.Int("secrets", len(values)))
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	serveCommand.Flags().StringVar(&file, "file", "", "YAML file mapping the secret names to their values")
+	serveCommand.Flags().StringVar(&address, "address", "127.0.0.1:8200", "Address to serve the secrets")
+	serveCommand.Flags().StringVar(&token, "token", "", "Bearer token required by the endpoint, it can be a file, env or exec reference")
+	_ = serveCommand.MarkFlagRequired("file")
+	return serveCommand
+}
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/auth"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/rpc"
+	"github.com/maxipaz/wallet/internal/scheduler"
+	"github.com/maxipaz/wallet/internal/server"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// NewServeCommand creates the serve command
+func NewServeCommand(ctx context.Context) *cobra.Command {
+	serveCommand := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the wallet operations over a JSON HTTP API and gRPC",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(ctx)
+		},
+	}
+
+	serveCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	serveCommand.Flags().String("server.address", "", "Address to serve the HTTP API, i.e.: :8080")
+	serveCommand.Flags().String("grpc.address", "", "Address to serve the gRPC service, i.e.: :9090")
+	serveCommand.Flags().String("metrics.address", "", "Address to serve the Prometheus metrics apart from the HTTP API, i.e.: :9100")
+	return serveCommand
+}
+
+func serve(ctx context.Context) error {
+	if config.App.Server.Address == "" && config.App.GRPC.Address == "" {
+		return fmt.Errorf("%w: please specify the server address, the grpc address or both", errs.ErrInvalidUsage)
+	}
+
+	// the signer key is resolved at start rather than on the first transaction
+	if config.App.Blockchain.PrivateKey != "" {
+		if _, err := config.PrivateKey(ctx); err != nil {
+			return err
+		}
+	}
+
+	authConfig, err := config.Auth(ctx)
+	if err != nil {
+		return err
+	}
+	authenticator, err := auth.New(authConfig)
+	if err != nil {
+		return err
+	}
+
+	pol, err := policy.New(config.App.Policy)
+	if err != nil {
+		return err
+	}
+
+	var st *store.Store
+	if config.App.Store.Path != "" {
+		st, err = store.Open(ctx, config.App.Store.Path)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+	}
+
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+
+	// the websocket connection is shared with the monitor feeding the events streamed by WatchEvents, like the other
+	// commands the runners use it
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var (
+		service *rpc.Server
+		monitor *wallet.Monitor
+	)
+	if config.App.GRPC.Address != "" {
+		service = rpc.New(client, config.App.Blockchain.PrivateKey, config.App.Contract.Address, authenticator, st, pol)
+		monitor = wallet.NewMonitor(config.App.Contract.Address)
+		monitor.AddHandler(service)
+	}
+
+	var schedules *scheduler.Scheduler
+	if len(config.App.Schedules) > 0 {
+		schedules, err = scheduler.NewScheduler(client, config.App.Blockchain.PrivateKey, config.App.Contract.Address, st, pol, config.App.Schedules)
+		if err != nil {
+			return err
+		}
+	}
+
+	var api *server.Server
+	if config.App.Server.Address != "" {
+		api, err = server.New(client, config.App.Blockchain.PrivateKey, config.App.Contract.Address, authenticator, st, pol)
+		if err != nil {
+			return err
+		}
+		// the metrics are only served by their own listener when it is configured
+		api.SetMetrics(config.App.Metrics.Address == "")
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	if config.App.Metrics.Address != "" {
+		eg.Go(func() error {
+			return metrics.Serve(ctx, config.App.Metrics.Address)
+		})
+	}
+
+	if api != nil {
+		eg.Go(func() error {
+			return api.Serve(ctx, config.App.Server.Address)
+		})
+	}
+
+	if schedules != nil {
+		eg.Go(func() error {
+			return schedules.Start(ctx)
+		})
+	}
+
+	if st != nil {
+		// the transactions left pending by a previous run are tracked along the new ones
+		eg.Go(func() error {
+			return wallet.NewOutbox(st).Start(ctx, client, config.App.Store.OutboxIntervalIn)
+		})
+	}
+
+	if service != nil {
+		eg.Go(func() error {
+			return service.Serve(ctx, config.App.GRPC.Address)
+		})
+		eg.Go(func() error {
+			return monitor.Start(ctx, client)
+		})
+	}
+
+	return eg.Wait()
+}
@@ -0,0 +1,232 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/output"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/spend"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/spf13/cobra"
+	"io"
+	"strings"
+	"time"
+)
+
+// NewSpendRequestsCommand creates the spend-requests command
+func NewSpendRequestsCommand(ctx context.Context) *cobra.Command {
+	spendRequestsCommand := &cobra.Command{
+		Use:   "spend-requests",
+		Short: "Submit, review and decide on the payouts requested by the beneficiaries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: please specify a subcommand: [submit, list, approve, reject]", errs.ErrInvalidUsage)
+		},
+	}
+
+	spendRequestsCommand.AddCommand(newSpendRequestsSubmitCommand(ctx))
+	spendRequestsCommand.AddCommand(newSpendRequestsListCommand(ctx))
+	spendRequestsCommand.AddCommand(newSpendRequestsApproveCommand(ctx))
+	spendRequestsCommand.AddCommand(newSpendRequestsRejectCommand(ctx))
+	return spendRequestsCommand
+}
+
+func newSpendRequestsSubmitCommand(ctx context.Context) *cobra.Command {
+	var (
+		beneficiary string
+		amount      int64
+		reason      string
+		reference   string
+		signature   string
+		key         string
+	)
+
+	submitCommand := &cobra.Command{
+		Use:   "submit",
+		Short: "Submit a payout request signed by the beneficiary",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (signature == "") == (key == "") {
+				return fmt.Errorf("%w: please specify either the signature or the beneficiary key", errs.ErrInvalidUsage)
+			}
+
+			return withSpendManager(ctx, func(m *spend.Manager) error {
+				if key != "" {
+					privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+					if err != nil {
+						return fmt.Errorf("%w: beneficiary key: %w", errs.ErrInvalidKey, err)
+					}
+					beneficiary = crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+					if signature, err = m.Sign(amount, reason, reference, privateKey); err != nil {
+						return err
+					}
+				} else if beneficiary == "" {
+					return fmt.Errorf("%w: please specify the beneficiary address signing the request", errs.ErrInvalidUsage)
+				}
+
+				request, err := m.Submit(ctx, beneficiary, amount, reason, reference, signature)
+				if err != nil {
+					return err
+				}
+				return printSpendRequest(request)
+			})
+		},
+	}
+
+	submitCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	submitCommand.Flags().StringVar(&beneficiary, "beneficiary", "", "Beneficiary address, derived from the key when it signs the request")
+	contacts.MarkAddressFlag(submitCommand.Flags(), "beneficiary")
+	submitCommand.Flags().Int64Var(&amount, "amount", 0, "Amount in ether")
+	submitCommand.Flags().StringVar(&reason, "reason", "", "Reason of the request")
+	submitCommand.Flags().StringVar(&reference, "reference", "", "Reference of the request, unique per beneficiary, i.e.: an invoice number")
+	submitCommand.Flags().StringVar(&signature, "signature", "", "Beneficiary personal_sign signature of the request message")
+	submitCommand.Flags().StringVar(&key, "key", "", "Beneficiary private key, signs the request message instead of passing the signature")
+	_ = submitCommand.MarkFlagRequired("amount")
+	_ = submitCommand.MarkFlagRequired("reference")
+
+	return submitCommand
+}
+
+func newSpendRequestsListCommand(ctx context.Context) *cobra.Command {
+	statuses := []string{store.SpendRequestPending}
+
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "List the requests with the beneficiaries current allowance and the contract balance, the oldest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withSpendManager(ctx, func(m *spend.Manager) error {
+				return withSpendClient(ctx, func(client *ethclient.Client) error {
+					review, err := m.Review(ctx, client, statuses)
+					if err != nil {
+						return err
+					}
+					return printSpendReview(review)
+				})
+			})
+		},
+	}
+
+	listCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	listCommand.Flags().StringSliceVar(&statuses, "status", statuses, "Statuses: pending, paid, rejected")
+	return listCommand
+}
+
+func newSpendRequestsApproveCommand(ctx context.Context) *cobra.Command {
+	approveCommand := &cobra.Command{
+		Use:   "approve ID",
+		Short: "Pay a pending request and link its transaction to it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withSpendManager(ctx, func(m *spend.Manager) error {
+				return withSpendClient(ctx, func(client *ethclient.Client) error {
+					request, _, err := m.Approve(ctx, client, args[0], "cli:"+common.CurrentUser())
+					if err != nil {
+						return err
+					}
+					return printSpendRequest(request)
+				})
+			})
+		},
+	}
+
+	approveCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	return approveCommand
+}
+
+func newSpendRequestsRejectCommand(ctx context.Context) *cobra.Command {
+	rejectCommand := &cobra.Command{
+		Use:   "reject ID",
+		Short: "Close a pending request without paying it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withSpendManager(ctx, func(m *spend.Manager) error {
+				request, err := m.Reject(ctx, args[0], "cli:"+common.CurrentUser())
+				if err != nil {
+					return err
+				}
+				return printSpendRequest(request)
+			})
+		},
+	}
+
+	rejectCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	return rejectCommand
+}
+
+// withSpendManager opens the store recording the spend requests and calls the given function with the manager
+func withSpendManager(ctx context.Context, fn func(m *spend.Manager) error) error {
+	pol, err := policy.New(config.App.Policy)
+	if err != nil {
+		return err
+	}
+
+	st, err := store.Open(ctx, config.App.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	m, err := spend.New(st, config.App.Blockchain.PrivateKey, config.App.Contract.Address, pol)
+	if err != nil {
+		return err
+	}
+	return fn(m)
+}
+
+// withSpendClient dials the node and calls the given function with the client
+func withSpendClient(ctx context.Context, fn func(client *ethclient.Client) error) error {
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return fn(client)
+}
+
+// printSpendRequest prints the request details
+func printSpendRequest(request *store.SpendRequest) error {
+	return output.Print(request, func(w io.Writer) {
+		fmt.Fprintf(w, "Spend request %s is %s\n", request.ID, request.Status)
+		fmt.Fprintf(w, "  beneficiary: %s\n  amount: %d ether\n  reason: %s\n  reference: %s\n  created: %s\n",
+			contacts.Display(request.Beneficiary), request.Amount, request.Reason, request.Reference, request.CreatedAt.Format(time.RFC3339))
+		if request.DecidedBy != "" {
+			fmt.Fprintf(w, "  decided by: %s\n", request.DecidedBy)
+		}
+		if request.TxHash != "" {
+			fmt.Fprintf(w, "  transaction: %s\n", request.TxHash)
+		}
+		if request.Error != "" {
+			fmt.Fprintf(w, "  last error: %s\n", request.Error)
+		}
+	})
+}
+
+// printSpendReview prints the contract balance and the requests with the allowance of their beneficiaries
+func printSpendReview(review *spend.Review) error {
+	return output.Print(review, func(w io.Writer) {
+		fmt.Fprintf(w, "Contract balance: %d ether\n\n", review.ContractBalance)
+
+		fmt.Fprintln(w, "ID\tCREATED\tSTATUS\tBENEFICIARY\tAMOUNT (ETH)\tALLOWANCE (ETH)\tREFERENCE\tREASON\tTRANSACTION")
+		for _, request := range review.Requests {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n",
+				request.ID,
+				request.CreatedAt.Format(time.RFC3339),
+				request.Status,
+				contacts.Display(request.Beneficiary),
+				request.Amount,
+				request.Allowance,
+				request.Reference,
+				request.Reason,
+				request.TxHash,
+			)
+		}
+	})
+}
@@ -15,7 +15,8 @@ const shutdownTimeout = 5
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	// the standard output is left to the commands results
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
 		AddSource: true,
 		Level:     slog.LevelDebug,
 	})))
@@ -36,7 +37,7 @@ func main() {
 		os.Exit(1)
 	}()
 
-	if err := command.NewRootCommand(ctx).Execute(); err != nil {
-		os.Exit(1)
+	if code := command.Execute(ctx); code != 0 {
+		os.Exit(code)
 	}
 }
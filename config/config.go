@@ -1,6 +1,9 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"github.com/maxipaz/wallet/internal/secrets"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -10,8 +13,22 @@ import (
 
 // AppConfig struct
 type AppConfig struct {
-	Blockchain BlockchainConfig
-	Contract   ContractConfig
+	// Network name of the selected network profile, the profile overrides the blockchain and contract settings
+	Network     string                   `mapstructure:"network"`
+	Networks    map[string]NetworkConfig `mapstructure:"networks"`
+	Blockchain  BlockchainConfig
+	Contract    ContractConfig
+	Metrics     MetricsConfig
+	Alerts      AlertsConfig
+	Store       StoreConfig
+	Server      ServerConfig
+	GRPC        GRPCConfig
+	Auth        AuthConfig
+	Schedules   []ScheduleConfig
+	Policy      PolicyConfig
+	ENS         ENSConfig
+	Secrets     SecretsConfig
+	Deployments DeploymentsConfig
 }
 
 // BlockchainConfig struct
@@ -21,6 +38,26 @@ type BlockchainConfig struct {
 	PrivateKey string `mapstructure:"pk"`
 	Timeout    string `mapstructure:"timeout"`
 	TimeoutIn  time.Duration
+	// ChainID expected chain ID of the node, nothing is signed when the node reports another one. Not checked when zero
+	ChainID uint64     `mapstructure:"chain_id"`
+	Fees    FeesConfig `mapstructure:"fees"`
+}
+
+// FeesConfig struct, the fees are expressed in Gwei and the zero values use the node suggestions. The EIP-1559 fees
+// take precedence over the legacy gas price
+type FeesConfig struct {
+	MaxFeePerGas         float64 `mapstructure:"max_fee_per_gas"`
+	MaxPriorityFeePerGas float64 `mapstructure:"max_priority_fee_per_gas"`
+	GasPrice             float64 `mapstructure:"gas_price"`
+}
+
+// NetworkConfig struct, a named network profile selected with the network flag
+type NetworkConfig struct {
+	Address         string     `mapstructure:"address"`
+	WS              string     `mapstructure:"ws"`
+	ChainID         uint64     `mapstructure:"chain_id"`
+	ContractAddress string     `mapstructure:"contract_address"`
+	Fees            FeesConfig `mapstructure:"fees"`
 }
 
 // ContractConfig struct
@@ -29,6 +66,152 @@ type ContractConfig struct {
 	DefaultWeiFounds int64  `mapstructure:"default_wei_founds"`
 }
 
+// MetricsConfig struct
+type MetricsConfig struct {
+	Address string `mapstructure:"address"`
+}
+
+// AlertsConfig struct
+type AlertsConfig struct {
+	Interval   string `mapstructure:"interval"`
+	IntervalIn time.Duration
+	Cooldown   string `mapstructure:"cooldown"`
+	CooldownIn time.Duration
+	Rules      []AlertRule `mapstructure:"rules"`
+}
+
+// AlertRule struct
+type AlertRule struct {
+	Name       string  `mapstructure:"name"`
+	Condition  string  `mapstructure:"condition"`
+	Threshold  float64 `mapstructure:"threshold"`
+	Cooldown   string  `mapstructure:"cooldown"`
+	CooldownIn time.Duration
+}
+
+// ServerConfig struct
+type ServerConfig struct {
+	Address string `mapstructure:"address"`
+}
+
+// GRPCConfig struct
+type GRPCConfig struct {
+	Address string `mapstructure:"address"`
+}
+
+// AuthConfig struct
+type AuthConfig struct {
+	// Disabled serves the API without authentication, only intended for local development
+	Disabled bool     `mapstructure:"disabled"`
+	APIKeys  []APIKey `mapstructure:"api_keys"`
+	JWT      JWTConfig
+}
+
+// APIKey struct
+type APIKey struct {
+	Name  string   `mapstructure:"name"`
+	Key   string   `mapstructure:"key"`
+	Roles []string `mapstructure:"roles"`
+}
+
+// JWTConfig struct
+type JWTConfig struct {
+	// Secret HMAC secret of the HS256, HS384 and HS512 tokens
+	Secret string `mapstructure:"secret"`
+	// PublicKey path to the PEM RSA public key of the RS256, RS384 and RS512 tokens
+	PublicKey  string `mapstructure:"public_key"`
+	Issuer     string `mapstructure:"issuer"`
+	Audience   string `mapstructure:"audience"`
+	RolesClaim string `mapstructure:"roles_claim"`
+}
+
+// ScheduleConfig struct
+type ScheduleConfig struct {
+	Name string `mapstructure:"name"`
+	// Cron standard cron expression, a CRON_TZ=<zone> prefix sets its time zone
+	Cron          string   `mapstructure:"cron"`
+	Action        string   `mapstructure:"action"`
+	Amount        int64    `mapstructure:"amount"`
+	Beneficiaries []string `mapstructure:"beneficiaries"`
+}
+
+// PolicyConfig struct, the amounts are expressed in Ether and the zero values are not enforced
+type PolicyConfig struct {
+	// Timezone location of the business hours and of the daily and monthly periods, local time when empty
+	Timezone     string `mapstructure:"timezone"`
+	MaxPayout    int64  `mapstructure:"max_payout"`
+	MaxAllowance int64  `mapstructure:"max_allowance"`
+	DailyCap     int64  `mapstructure:"daily_cap"`
+	MonthlyCap   int64  `mapstructure:"monthly_cap"`
+	// BeneficiaryDailyCap and BeneficiaryMonthlyCap limit the payouts to each beneficiary
+	BeneficiaryDailyCap   int64               `mapstructure:"beneficiary_daily_cap"`
+	BeneficiaryMonthlyCap int64               `mapstructure:"beneficiary_monthly_cap"`
+	AllowedRecipients     []string            `mapstructure:"allowed_recipients"`
+	BusinessHours         BusinessHoursConfig `mapstructure:"business_hours"`
+	Approvals             ApprovalsConfig     `mapstructure:"approvals"`
+}
+
+// BusinessHoursConfig struct
+type BusinessHoursConfig struct {
+	// Days abbreviated week days, i.e.: mon, tue
+	Days  []string `mapstructure:"days"`
+	Start string   `mapstructure:"start"`
+	End   string   `mapstructure:"end"`
+}
+
+// ApprovalsConfig struct, the operations above the thresholds are executed as proposals approved by Required of the
+// approvers. The thresholds are expressed in Ether and the zero values are not enforced
+type ApprovalsConfig struct {
+	Required           int        `mapstructure:"required"`
+	Approvers          []Approver `mapstructure:"approvers"`
+	PayoutThreshold    int64      `mapstructure:"payout_threshold"`
+	AllowanceThreshold int64      `mapstructure:"allowance_threshold"`
+	OwnershipTransfer  bool       `mapstructure:"ownership_transfer"`
+	Expiry             string     `mapstructure:"expiry"`
+	ExpiryIn           time.Duration
+}
+
+// Approver struct
+type Approver struct {
+	Name    string `mapstructure:"name"`
+	Address string `mapstructure:"address"`
+}
+
+// StoreConfig struct
+type StoreConfig struct {
+	Path string `mapstructure:"path"`
+	// OutboxInterval time between two checks of the pending transactions recorded in the outbox
+	OutboxInterval   string `mapstructure:"outbox_interval"`
+	OutboxIntervalIn time.Duration
+}
+
+// ENSConfig struct, the ENS names are resolved when the registry is set
+type ENSConfig struct {
+	Registry string `mapstructure:"registry"`
+	// CacheTTL time the primary names of the addresses are cached in the store
+	CacheTTL   string `mapstructure:"cache_ttl"`
+	CacheTTLIn time.Duration
+}
+
+// DeploymentsConfig struct
+type DeploymentsConfig struct {
+	// Path file recording the deployments of the contract
+	Path string `mapstructure:"path"`
+}
+
+const (
+	// defaultAlertsInterval time between two evaluations of the alert rules
+	defaultAlertsInterval = time.Minute
+	// defaultAlertsCooldown minimum time between two deliveries of the same alert
+	defaultAlertsCooldown = time.Hour
+	// defaultOutboxInterval time between two checks of the pending transactions
+	defaultOutboxInterval = 15 * time.Second
+	// defaultProposalExpiry time after which a proposal not executed expires
+	defaultProposalExpiry = 72 * time.Hour
+	// defaultENSCacheTTL time the ENS reverse lookups are cached
+	defaultENSCacheTTL = 24 * time.Hour
+)
+
 // environmentPrefix prefix used to avoid environment variable names collisions
 const environmentPrefix = "SW"
 
@@ -38,6 +221,24 @@ var (
 	// App configuration struct
 	App AppConfig
 
+	// settings merged settings of the last setup and flags its command flags, to report the sources of the settings
+	settings *viper.Viper
+	flags    *pflag.FlagSet
+	// networkKeys settings overridden by the selected network profile
+	networkKeys map[string]struct{}
+
+	// defaults default values of the settings, the durations are parsed with the settings
+	defaults = map[string]any{
+		"alerts.interval":         "1m",
+		"alerts.cooldown":         "1h",
+		"store.outbox_interval":   "15s",
+		"policy.approvals.expiry": "72h",
+		"ens.cache_ttl":           "24h",
+		"secrets.timeout":         "10s",
+		"deployments.path":        "deployments.json",
+		"auth.jwt.roles_claim":    "roles",
+	}
+
 	// environmentVarList list of environment variables read by the app. The name should match with a struct field.
 	// The dots will be replaced by underscores, it will be capitalized and the environmentPrefix will be added
 	// 		i.e.: blockchain.pk => SW_BLOCKCHAIN_PK
@@ -46,7 +247,8 @@ var (
 	}
 )
 
-// Setup bind command flags and environment variables
+// Setup bind command flags and environment variables and validates the settings, the secret references are resolved
+// when used, see PrivateKey and Auth
 // The precedence to override a configuration is: flag -> environment variable -> configuration field
 func Setup(cmd *cobra.Command, _ []string) error {
 	v := viper.New()
@@ -58,6 +260,10 @@ func Setup(cmd *cobra.Command, _ []string) error {
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
 	if err := v.ReadInConfig(); err != nil {
 		return err
 	}
@@ -69,15 +275,94 @@ func Setup(cmd *cobra.Command, _ []string) error {
 		_ = v.BindPFlag(env, cmd.Flags().Lookup(env))
 	}
 
+	if err := applyNetwork(v); err != nil {
+		return err
+	}
+
 	if err := v.Unmarshal(&App); err != nil {
 		return err
 	}
+	settings, flags = v, cmd.Flags()
 
-	var err error
-	App.Blockchain.TimeoutIn, err = time.ParseDuration(App.Blockchain.Timeout)
-	if err != nil {
+	if err := parseDurations(&App); err != nil {
 		return err
 	}
 
-	return nil
+	// the secret references are resolved on first use
+	secretsMu.Lock()
+	resolved = make(map[string]string)
+	secretsMu.Unlock()
+	if secrets.Scheme(App.Blockchain.PrivateKey) == "" {
+		App.Blockchain.PrivateKey = strings.TrimPrefix(App.Blockchain.PrivateKey, "0x")
+	}
+
+	return validate(&App)
+}
+
+// applyNetwork merges the selected network profile over the blockchain and contract settings of the file, the flags and
+// the environment variables still take precedence over the profile
+func applyNetwork(v *viper.Viper) error {
+	networkKeys = nil
+	name := v.GetString("network")
+	if name == "" {
+		return nil
+	}
+
+	var networks map[string]NetworkConfig
+	if err := v.UnmarshalKey("networks", &networks); err != nil {
+		return err
+	}
+	// viper lower cases the keys
+	network, ok := networks[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown network %s, please add it under networks", name)
+	}
+
+	blockchain := map[string]any{}
+	for key, value := range map[string]string{"address": network.Address, "ws": network.WS} {
+		if value != "" {
+			blockchain[key] = value
+		}
+	}
+	if network.ChainID != 0 {
+		blockchain["chain_id"] = network.ChainID
+	}
+	if network.Fees != (FeesConfig{}) {
+		blockchain["fees"] = map[string]any{
+			"max_fee_per_gas":          network.Fees.MaxFeePerGas,
+			"max_priority_fee_per_gas": network.Fees.MaxPriorityFeePerGas,
+			"gas_price":                network.Fees.GasPrice,
+		}
+	}
+
+	// the contract address of the file is never inherited, it belongs to another network
+	profile := map[string]any{
+		"blockchain": blockchain,
+		"contract":   map[string]any{"address": network.ContractAddress},
+	}
+	networkKeys = flatten("", profile)
+	return v.MergeConfigMap(profile)
+}
+
+// parseDurations parses the durations of the settings, applying the defaults for the missing ones. Every invalid
+// duration is reported, the timeouts and intervals must be positive
+func parseDurations(app *AppConfig) error {
+	v := &validator{}
+
+	app.Blockchain.TimeoutIn = v.duration("blockchain.timeout", app.Blockchain.Timeout, 0, "5s", true)
+	app.Alerts.IntervalIn = v.duration("alerts.interval", app.Alerts.Interval, defaultAlertsInterval, "1m", true)
+	app.Alerts.CooldownIn = v.duration("alerts.cooldown", app.Alerts.Cooldown, defaultAlertsCooldown, "1h", false)
+	for i := range app.Alerts.Rules {
+		rule := &app.Alerts.Rules[i]
+		rule.CooldownIn = app.Alerts.CooldownIn
+		if rule.Cooldown != "" {
+			rule.CooldownIn = v.duration(fmt.Sprintf("alerts.rules[%d].cooldown", i), rule.Cooldown, 0, "1h", false)
+		}
+	}
+	app.Store.OutboxIntervalIn = v.duration("store.outbox_interval", app.Store.OutboxInterval, defaultOutboxInterval, "15s", true)
+	app.Policy.Approvals.ExpiryIn = v.duration("policy.approvals.expiry", app.Policy.Approvals.Expiry, defaultProposalExpiry, "72h", true)
+	app.ENS.CacheTTLIn = v.duration("ens.cache_ttl", app.ENS.CacheTTL, defaultENSCacheTTL, "24h", true)
+	app.Secrets.TimeoutIn = v.duration("secrets.timeout", app.Secrets.Timeout, defaultSecretsTimeout, "10s", true)
+
+	return errors.Join(v.errs...)
 }
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"strings"
+	"testing"
+)
+
+func TestApplyNetworkContractAddress(t *testing.T) {
+	content := `contract:
+  address: "0xaD86Df8c289739A6fCb95005A3F5df0ea56F88c6"
+networks:
+  ganache:
+    chain_id: 1337
+    contract_address: "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a"
+  sepolia:
+    chain_id: 11155111
+`
+	tests := []struct {
+		network string
+		want    string
+	}{
+		{"", "0xaD86Df8c289739A6fCb95005A3F5df0ea56F88c6"},
+		{"ganache", "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a"},
+		{"sepolia", ""},
+	}
+	for _, tt := range tests {
+		t.Run("network "+tt.network, func(t *testing.T) {
+			v := viper.New()
+			v.SetConfigType("yaml")
+			if err := v.ReadConfig(strings.NewReader(content)); err != nil {
+				t.Fatal(err)
+			}
+			v.Set("network", tt.network)
+			if err := applyNetwork(v); err != nil {
+				t.Fatal(err)
+			}
+			if got := v.GetString("contract.address"); got != tt.want {
+				t.Fatalf("contract address is %q, expected %q", got, tt.want)
+			}
+		})
+	}
+
+	app := AppConfig{Network: "sepolia"}
+	app.Blockchain.Address = "https://sepolia.example.org"
+	withApp(t, app)
+	err := RequireContract()
+	if err == nil || !strings.Contains(err.Error(), "networks.sepolia.contract_address") {
+		t.Fatalf("got %v, expected the contract address of the profile to be required", err)
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SaveContractAddress sets the contract address of the selected network profile, or of the contract settings without
+// profile, in the configuration file. The comments and the order of the other settings are kept. It returns the
+// path of the updated setting
+func SaveContractAddress(address string) (string, error) {
+	keys := []string{"contract", "address"}
+	if App.Network != "" {
+		keys = []string{"networks", App.Network, "contract_address"}
+	}
+
+	info, err := os.Stat(Filename)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(Filename)
+	if err != nil {
+		return "", err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return "", err
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return "", fmt.Errorf("%s is not a YAML mapping", Filename)
+	}
+	setValue(document.Content[0], keys, address)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return "", err
+	}
+	if err := writeFile(Filename, out.Bytes(), info.Mode().Perm()); err != nil {
+		return "", err
+	}
+
+	App.Contract.Address = address
+	return strings.Join(keys, "."), nil
+}
+
+// writeFile replaces a file with a temporary file renamed over it, an interrupted write never leaves it truncated.
+// The temporary file gets the mode of the file it replaces
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// setValue sets a nested value of a mapping, adding the missing keys. The keys are matched ignoring the case, as
+// the settings are read
+func setValue(mapping *yaml.Node, keys []string, value string) {
+	var node *yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, keys[0]) {
+			node = mapping.Content[i+1]
+			break
+		}
+	}
+	if node == nil {
+		node = &yaml.Node{}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keys[0]}, node)
+	}
+
+	if len(keys) == 1 {
+		// quoted, some YAML parsers would read the address as a hexadecimal number. The comments are kept
+		node.Kind, node.Tag, node.Value, node.Style, node.Content = yaml.ScalarNode, "!!str", value, yaml.DoubleQuotedStyle, nil
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	// the empty mappings are written in flow style, i.e.: networks: {}
+	node.Style = 0
+	setValue(node, keys[1:], value)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveContractAddress(t *testing.T) {
+	previous := Filename
+	Filename = filepath.Join(t.TempDir(), "config.yaml")
+	t.Cleanup(func() { Filename = previous })
+
+	content := "# wallet settings\nnetwork: sepolia\nnetworks:\n  sepolia:\n    chain_id: 11155111 # pinned\n"
+	if err := os.WriteFile(Filename, []byte(content), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	app := AppConfig{Network: "sepolia"}
+	withApp(t, app)
+
+	address := "0xaD86Df8c289739A6fCb95005A3F5df0ea56F88c6"
+	setting, err := SaveContractAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setting != "networks.sepolia.contract_address" {
+		t.Fatalf("updated setting is %s", setting)
+	}
+
+	data, err := os.ReadFile(Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# wallet settings", "# pinned", `contract_address: "` + address + `"`} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("the file does not hold %q:\n%s", want, data)
+		}
+	}
+
+	info, err := os.Stat(Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Fatalf("the file mode is %o, expected 640", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(filepath.Dir(Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("the directory holds %d files, expected the temporary file to be renamed", len(entries))
+	}
+}
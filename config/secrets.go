@@ -0,0 +1,126 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/secrets"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSecretsTimeout maximum time of a secret command or of a request to the secrets endpoint
+const defaultSecretsTimeout = 10 * time.Second
+
+// redacted replaces the secrets in the configuration dumps
+const redacted = "[redacted]"
+
+// SecretsConfig struct, settings of the secret references: file:<path>, env:<name>, exec:<command> or an http(s) URL
+type SecretsConfig struct {
+	// HTTPToken bearer token of the secrets endpoint, it can be a file, env or exec reference
+	HTTPToken string `mapstructure:"http_token"`
+	Timeout   string `mapstructure:"timeout"`
+	TimeoutIn time.Duration
+}
+
+var (
+	secretsMu sync.Mutex
+	// resolved secrets of the references, by field path
+	resolved = make(map[string]string)
+)
+
+// PrivateKey returns the private key of blockchain.pk, resolving its reference on first use. The references are
+// only resolved by the commands using them, so a missing secret does not break the other commands
+func PrivateKey(ctx context.Context) (string, error) {
+	key, err := secret(ctx, "blockchain.pk", App.Blockchain.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	key = strings.TrimPrefix(key, "0x")
+	if !privateKeyRegex.MatchString(key) {
+		return "", fmt.Errorf("%w: %w", errs.ErrInvalidConfig, &FieldError{Path: "blockchain.pk", Expected: "64 hexadecimal characters"})
+	}
+	return key, nil
+}
+
+// Auth returns the authentication settings with the JWT secret and the API keys resolved
+func Auth(ctx context.Context) (AuthConfig, error) {
+	auth := App.Auth
+	var err error
+	if auth.JWT.Secret, err = secret(ctx, "auth.jwt.secret", auth.JWT.Secret); err != nil {
+		return AuthConfig{}, err
+	}
+
+	auth.APIKeys = make([]APIKey, len(App.Auth.APIKeys))
+	for i, key := range App.Auth.APIKeys {
+		if key.Key, err = secret(ctx, fmt.Sprintf("auth.api_keys[%d].key", i), key.Key); err != nil {
+			return AuthConfig{}, err
+		}
+		auth.APIKeys[i] = key
+	}
+	return auth, nil
+}
+
+// secret returns the secret of a field, resolving its reference once
+func secret(ctx context.Context, field string, value string) (string, error) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	return resolveSecret(ctx, field, value)
+}
+
+// resolveSecret returns the secret of a field, the value itself when it is not a reference. The http references are
+// resolved with the bearer token of secrets.http_token. The caller holds secretsMu
+func resolveSecret(ctx context.Context, field string, value string) (string, error) {
+	scheme := secrets.Scheme(value)
+	if scheme == "" {
+		return value, nil
+	}
+	if secret, ok := resolved[field]; ok {
+		return secret, nil
+	}
+
+	opts := secrets.Options{Timeout: App.Secrets.TimeoutIn}
+	if scheme == secrets.HTTPScheme || scheme == secrets.HTTPSScheme {
+		token, err := resolveSecret(ctx, "secrets.http_token", App.Secrets.HTTPToken)
+		if err != nil {
+			return "", err
+		}
+		opts.HTTPToken = token
+	}
+
+	secret, err := secrets.Resolve(ctx, value, opts)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", field, err)
+	}
+	resolved[field] = secret
+	return secret, nil
+}
+
+// Redacted returns a copy of the configuration without its secrets, to be dumped. The secrets are replaced by
+// [redacted], followed by the scheme of their reference when given as a reference
+func Redacted() AppConfig {
+	app := App
+	app.Blockchain.PrivateKey = redact(app.Blockchain.PrivateKey)
+	app.Auth.JWT.Secret = redact(app.Auth.JWT.Secret)
+	app.Secrets.HTTPToken = redact(app.Secrets.HTTPToken)
+
+	app.Auth.APIKeys = make([]APIKey, len(App.Auth.APIKeys))
+	for i, key := range App.Auth.APIKeys {
+		key.Key = redact(key.Key)
+		app.Auth.APIKeys[i] = key
+	}
+	return app
+}
+
+// redact returns the replacement of a secret, empty when the secret is not set
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	if scheme := secrets.Scheme(value); scheme != "" {
+		return fmt.Sprintf("[redacted: %s]", scheme)
+	}
+	return redacted
+}
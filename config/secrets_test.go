@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"errors"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"strings"
+	"testing"
+)
+
+const testKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+// withApp replaces the configuration and the resolved secrets for the test
+func withApp(t *testing.T, app AppConfig) {
+	previous := App
+	App = app
+	resolved = make(map[string]string)
+	t.Cleanup(func() {
+		App = previous
+		resolved = make(map[string]string)
+	})
+}
+
+func TestPrivateKeyResolvedOnUse(t *testing.T) {
+	app := AppConfig{}
+	app.Blockchain.PrivateKey = "env:WALLET_TEST_PK"
+	app.Secrets.TimeoutIn = defaultSecretsTimeout
+	withApp(t, app)
+
+	// the reference is only resolved when the key is used
+	if _, err := PrivateKey(context.Background()); !errors.Is(err, errs.ErrSecretUnavailable) || !strings.Contains(err.Error(), "blockchain.pk") {
+		t.Fatalf("got %v, expected a secret unavailable error for blockchain.pk", err)
+	}
+
+	t.Setenv("WALLET_TEST_PK", "0x"+testKey)
+	key, err := PrivateKey(context.Background())
+	if err != nil || key != testKey {
+		t.Fatalf("got %q, %v, expected the key without prefix", key, err)
+	}
+
+	// the secret is resolved once
+	t.Setenv("WALLET_TEST_PK", "changed")
+	if key, err := PrivateKey(context.Background()); err != nil || key != testKey {
+		t.Fatalf("got %q, %v, expected the first resolved key", key, err)
+	}
+
+	if got := Redacted().Blockchain.PrivateKey; got != "[redacted: env]" {
+		t.Fatalf("redacted key is %q", got)
+	}
+}
+
+func TestPrivateKeyInvalid(t *testing.T) {
+	app := AppConfig{}
+	app.Blockchain.PrivateKey = "env:WALLET_TEST_PK"
+	app.Secrets.TimeoutIn = defaultSecretsTimeout
+	withApp(t, app)
+	t.Setenv("WALLET_TEST_PK", "not-a-key")
+
+	_, err := PrivateKey(context.Background())
+	var fieldErr *FieldError
+	if !errors.Is(err, errs.ErrInvalidConfig) || !errors.As(err, &fieldErr) || fieldErr.Path != "blockchain.pk" {
+		t.Fatalf("got %v, expected an invalid blockchain.pk field", err)
+	}
+	if strings.Contains(err.Error(), "not-a-key") {
+		t.Fatal("the error holds the secret")
+	}
+}
+
+func TestAuthResolvesKeys(t *testing.T) {
+	app := AppConfig{}
+	app.Auth.APIKeys = []APIKey{{Name: "literal", Key: "plain"}, {Name: "reference", Key: "env:WALLET_TEST_API_KEY"}}
+	app.Secrets.TimeoutIn = defaultSecretsTimeout
+	withApp(t, app)
+	t.Setenv("WALLET_TEST_API_KEY", "resolved")
+
+	auth, err := Auth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.APIKeys[0].Key != "plain" || auth.APIKeys[1].Key != "resolved" {
+		t.Fatalf("got keys %q and %q", auth.APIKeys[0].Key, auth.APIKeys[1].Key)
+	}
+	// the configuration keeps the reference
+	if App.Auth.APIKeys[1].Key != "env:WALLET_TEST_API_KEY" {
+		t.Fatalf("the configuration key is %q", App.Auth.APIKeys[1].Key)
+	}
+}
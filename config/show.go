@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Sources of the settings, in order of precedence
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceNetwork = "network"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// Setting effective value of a setting and its source
+type Setting struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+// Settings returns the effective settings of the last setup, merged from the flags, the environment, the network
+// profile, the file and the defaults, ordered by key. The secrets are redacted
+func Settings() []Setting {
+	if settings == nil {
+		return nil
+	}
+
+	app, sections := Redacted(), sectionKeys()
+	var result []Setting
+	for _, key := range settings.AllKeys() {
+		section, _, _ := strings.Cut(key, ".")
+		if _, ok := sections[section]; !ok {
+			continue
+		}
+		result = append(result, Setting{Key: key, Value: value(key, app), Source: source(key)})
+	}
+
+	slices.SortFunc(result, func(a, b Setting) int { return strings.Compare(a.Key, b.Key) })
+	return result
+}
+
+// source returns where the effective value of a setting comes from
+func source(key string) string {
+	if flag := flags.Lookup(key); flag != nil && flag.Changed {
+		return SourceFlag
+	}
+	if _, ok := os.LookupEnv(environmentPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))); ok {
+		return SourceEnv
+	}
+	if _, ok := networkKeys[key]; ok {
+		return SourceNetwork
+	}
+	if settings.InConfig(key) {
+		return SourceFile
+	}
+	return SourceDefault
+}
+
+// value returns the value of a setting, the secrets taken from the redacted configuration
+func value(key string, redactedApp AppConfig) any {
+	switch key {
+	case "blockchain.pk":
+		return redactedApp.Blockchain.PrivateKey
+	case "auth.jwt.secret":
+		return redactedApp.Auth.JWT.Secret
+	case "secrets.http_token":
+		return redactedApp.Secrets.HTTPToken
+	case "auth.api_keys":
+		keys := make([]map[string]any, 0, len(redactedApp.Auth.APIKeys))
+		for _, key := range redactedApp.Auth.APIKeys {
+			keys = append(keys, map[string]any{"name": key.Name, "key": key.Key, "roles": key.Roles})
+		}
+		return keys
+	}
+	return settings.Get(key)
+}
+
+// sectionKeys returns the top level keys of the configuration
+func sectionKeys() map[string]struct{} {
+	keys := make(map[string]struct{})
+	t := reflect.TypeOf(AppConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		if key == "" {
+			key = strings.ToLower(t.Field(i).Name)
+		}
+		keys[key] = struct{}{}
+	}
+	return keys
+}
+
+// flatten returns the dotted keys of the leaves of nested settings
+func flatten(prefix string, values map[string]any) map[string]struct{} {
+	keys := make(map[string]struct{})
+	for key, value := range values {
+		if nested, ok := value.(map[string]any); ok {
+			for nestedKey := range flatten(prefix+key+".", nested) {
+				keys[nestedKey] = struct{}{}
+			}
+			continue
+		}
+		keys[prefix+key] = struct{}{}
+	}
+	return keys
+}
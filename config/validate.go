@@ -0,0 +1,255 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/maxipaz/wallet/internal/secrets"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// privateKeyRegex hexadecimal secp256k1 private key
+var privateKeyRegex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// addressFormat expected format of the addresses
+const addressFormat = "a 0x prefixed address of 40 hexadecimal characters"
+
+// weekDays abbreviated week days accepted by the business hours
+var weekDays = map[string]struct{}{"mon": {}, "tue": {}, "wed": {}, "thu": {}, "fri": {}, "sat": {}, "sun": {}}
+
+// FieldError invalid setting of the configuration, with the path of the field and the expected format
+type FieldError struct {
+	Path     string
+	Expected string
+	// Value invalid value, empty for the secrets and the missing values
+	Value string
+}
+
+// Error implements the error interface
+func (e *FieldError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: expected %s", e.Path, e.Expected)
+	}
+	return fmt.Sprintf("%s: expected %s, got %q", e.Path, e.Expected, e.Value)
+}
+
+// validator collects the invalid fields of the configuration
+type validator struct {
+	errs []error
+}
+
+// check records an invalid field when the condition does not hold
+func (v *validator) check(ok bool, path string, expected string, value string) {
+	if !ok {
+		v.errs = append(v.errs, &FieldError{Path: path, Expected: expected, Value: value})
+	}
+}
+
+// validate checks the format of the settings, every invalid field is reported. The settings required by some
+// commands only, like the contract address, are checked by the commands
+func validate(app *AppConfig) error {
+	v := &validator{}
+
+	v.node("blockchain.address", app.Blockchain.Address, "http", "https", "ws", "wss")
+	v.node("blockchain.ws", app.Blockchain.WS, "ws", "wss")
+	// the references are checked once resolved
+	v.check(app.Blockchain.PrivateKey == "" || secrets.Scheme(app.Blockchain.PrivateKey) != "" || privateKeyRegex.MatchString(app.Blockchain.PrivateKey),
+		"blockchain.pk", "64 hexadecimal characters or a secret reference", "")
+	v.fees("blockchain.fees", app.Blockchain.Fees)
+	v.address("contract.address", app.Contract.Address)
+	v.check(app.Contract.DefaultWeiFounds >= 0, "contract.default_wei_founds", "a positive amount or 0", fmt.Sprint(app.Contract.DefaultWeiFounds))
+
+	names := make([]string, 0, len(app.Networks))
+	for name := range app.Networks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		network, path := app.Networks[name], "networks."+name
+		v.node(path+".address", network.Address, "http", "https", "ws", "wss")
+		v.node(path+".ws", network.WS, "ws", "wss")
+		// a profile always pins its chain, its contract address is only valid there
+		v.check(network.ChainID != 0, path+".chain_id", "the chain ID of the network, i.e.: 11155111", fmt.Sprint(network.ChainID))
+		v.address(path+".contract_address", network.ContractAddress)
+		v.fees(path+".fees", network.Fees)
+	}
+
+	v.listen("metrics.address", app.Metrics.Address)
+	v.listen("server.address", app.Server.Address)
+	v.listen("grpc.address", app.GRPC.Address)
+
+	for i, rule := range app.Alerts.Rules {
+		path := fmt.Sprintf("alerts.rules[%d]", i)
+		v.check(rule.Name != "", path+".name", "a name", "")
+		v.check(rule.Condition != "", path+".condition", "a condition", "")
+	}
+
+	for i, key := range app.Auth.APIKeys {
+		path := fmt.Sprintf("auth.api_keys[%d]", i)
+		v.check(key.Name != "", path+".name", "a name", "")
+		v.check(key.Key != "", path+".key", "a key or a secret reference", "")
+		v.check(len(key.Roles) > 0, path+".roles", "at least one role", "")
+	}
+
+	for i, schedule := range app.Schedules {
+		path := fmt.Sprintf("schedules[%d]", i)
+		v.check(schedule.Name != "", path+".name", "a name", "")
+		v.check(schedule.Cron != "", path+".cron", "a cron expression, i.e.: 0 9 * * mon", "")
+		v.check(schedule.Action == "set" || schedule.Action == "increase", path+".action", "set or increase", schedule.Action)
+		v.check(schedule.Amount > 0, path+".amount", "a positive amount", fmt.Sprint(schedule.Amount))
+	}
+
+	// the token cannot come from the endpoint it authenticates to
+	scheme := secrets.Scheme(app.Secrets.HTTPToken)
+	v.check(scheme != secrets.HTTPScheme && scheme != secrets.HTTPSScheme, "secrets.http_token", "a token or a file, env or exec reference", "")
+
+	v.policy(app.Policy)
+	v.address("ens.registry", app.ENS.Registry)
+
+	return errors.Join(v.errs...)
+}
+
+// node checks the URL of a node, the IPC paths are accepted when http is
+func (v *validator) node(path string, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+
+	article := "a "
+	if schemes[0] == "http" {
+		article = "an "
+	}
+	expected := article + strings.Join(schemes[:len(schemes)-1], ", ") + " or " + schemes[len(schemes)-1] + " URL"
+	if !strings.Contains(value, "://") {
+		v.check(schemes[0] == "http", path, expected+", or an IPC path", value)
+		return
+	}
+	u, err := url.Parse(value)
+	v.check(err == nil && u.Host != "" && slices.Contains(schemes, u.Scheme), path, expected, value)
+}
+
+// duration parses a duration setting, the empty value takes the fallback. The timeouts and intervals must be
+// positive, the cooldowns may be zero
+func (v *validator) duration(path string, value string, fallback time.Duration, example string, positive bool) time.Duration {
+	parsed, err := fallback, error(nil)
+	if value != "" {
+		parsed, err = time.ParseDuration(value)
+	}
+
+	if positive {
+		v.check(err == nil && parsed > 0, path, "a positive duration, i.e.: "+example, value)
+	} else {
+		v.check(err == nil && parsed >= 0, path, "a duration, i.e.: "+example, value)
+	}
+	return parsed
+}
+
+// address checks an optional hexadecimal address
+func (v *validator) address(path string, value string) {
+	v.check(value == "" || isAddress(value), path, addressFormat, value)
+}
+
+// listen checks an optional listen address
+func (v *validator) listen(path string, value string) {
+	if value == "" {
+		return
+	}
+	_, _, err := net.SplitHostPort(value)
+	v.check(err == nil, path, "a host:port address, i.e.: :8080", value)
+}
+
+// fees checks the fees of a network
+func (v *validator) fees(path string, fees FeesConfig) {
+	v.check(fees.MaxFeePerGas >= 0, path+".max_fee_per_gas", "a positive amount of Gwei or 0", fmt.Sprint(fees.MaxFeePerGas))
+	v.check(fees.MaxPriorityFeePerGas >= 0, path+".max_priority_fee_per_gas", "a positive amount of Gwei or 0", fmt.Sprint(fees.MaxPriorityFeePerGas))
+	v.check(fees.GasPrice >= 0, path+".gas_price", "a positive amount of Gwei or 0", fmt.Sprint(fees.GasPrice))
+	v.check(fees.MaxFeePerGas == 0 || fees.MaxPriorityFeePerGas <= fees.MaxFeePerGas,
+		path+".max_priority_fee_per_gas", "at most max_fee_per_gas", fmt.Sprint(fees.MaxPriorityFeePerGas))
+}
+
+// policy checks the spending policy
+func (v *validator) policy(policy PolicyConfig) {
+	if policy.Timezone != "" {
+		_, err := time.LoadLocation(policy.Timezone)
+		v.check(err == nil, "policy.timezone", "an IANA time zone, i.e.: Europe/Madrid", policy.Timezone)
+	}
+
+	amounts := []struct {
+		name   string
+		amount int64
+	}{
+		{"max_payout", policy.MaxPayout},
+		{"max_allowance", policy.MaxAllowance},
+		{"daily_cap", policy.DailyCap},
+		{"monthly_cap", policy.MonthlyCap},
+		{"beneficiary_daily_cap", policy.BeneficiaryDailyCap},
+		{"beneficiary_monthly_cap", policy.BeneficiaryMonthlyCap},
+	}
+	for _, a := range amounts {
+		v.check(a.amount >= 0, "policy."+a.name, "a positive amount of Ether or 0", fmt.Sprint(a.amount))
+	}
+
+	for i, day := range policy.BusinessHours.Days {
+		_, ok := weekDays[strings.ToLower(day)]
+		v.check(ok, fmt.Sprintf("policy.business_hours.days[%d]", i), "mon, tue, wed, thu, fri, sat or sun", day)
+	}
+	// the hours default to the whole day when both are empty
+	if hours := policy.BusinessHours; hours.Start != "" || hours.End != "" {
+		_, err := time.Parse("15:04", hours.Start)
+		v.check(err == nil, "policy.business_hours.start", "a time of the day, i.e.: 09:00", hours.Start)
+		_, err = time.Parse("15:04", hours.End)
+		v.check(err == nil, "policy.business_hours.end", "a time of the day, i.e.: 18:00", hours.End)
+	}
+
+	approvals := policy.Approvals
+	v.check(approvals.Required >= 0 && approvals.Required <= len(approvals.Approvers),
+		"policy.approvals.required", fmt.Sprintf("at most the %d approvers", len(approvals.Approvers)), fmt.Sprint(approvals.Required))
+	for i, approver := range approvals.Approvers {
+		v.check(isAddress(approver.Address), fmt.Sprintf("policy.approvals.approvers[%d].address", i), addressFormat, approver.Address)
+	}
+}
+
+// isAddress reports whether a value is a 0x prefixed hexadecimal address
+func isAddress(value string) bool {
+	return strings.HasPrefix(value, "0x") && ethcommon.IsHexAddress(value)
+}
+
+// RequireContract checks the node and the contract addresses are set, for the commands using the contract
+func RequireContract() error {
+	v := &validator{}
+	v.check(App.Blockchain.Address != "", "blockchain.address", "the node URL, i.e.: http://127.0.0.1:8545", "")
+	path := "contract.address"
+	if App.Network != "" {
+		path = "networks." + App.Network + ".contract_address"
+	}
+	v.check(App.Contract.Address != "", path, addressFormat+", please deploy the contract or set it", "")
+	return errors.Join(v.errs...)
+}
+
+// ValidateField checks the value of a single setting, i.e.: an answer of the config init command
+func ValidateField(path string, value string) error {
+	v := &validator{}
+	switch path {
+	case "blockchain.address":
+		v.node(path, value, "http", "https", "ws", "wss")
+	case "blockchain.ws":
+		v.node(path, value, "ws", "wss")
+	case "blockchain.pk":
+		v.check(value == "" || secrets.Scheme(value) != "" || privateKeyRegex.MatchString(strings.TrimPrefix(value, "0x")),
+			path, "64 hexadecimal characters or a secret reference, i.e.: env:WALLET_PK", "")
+	case "blockchain.chain_id":
+		chainID, err := strconv.ParseUint(value, 10, 64)
+		v.check(value == "" || (err == nil && chainID != 0), path, "a chain ID, i.e.: 1", value)
+	case "contract.address", "ens.registry":
+		v.address(path, value)
+	case "metrics.address", "server.address", "grpc.address":
+		v.listen(path, value)
+	}
+	return errors.Join(v.errs...)
+}
@@ -0,0 +1,255 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package contracts
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// TestENSRegistryMetaData contains all meta data concerning the TestENSRegistry contract.
+var TestENSRegistryMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"resolver\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"address\",\"name\":\"_resolver\",\"type\":\"address\"}],\"name\":\"setResolver\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]",
+	Bin: "0x341561000a57600080fd5b610092806100186000396000f3341561000a57600080fd5b6004361061002f5760003560e01c80631896f70a146100345780630178b8bf14610070575b600080fd5b6044361061002f576024358073ffffffffffffffffffffffffffffffffffffffff1681141561002f576004356000526000602052604060002055005b6024361061002f57600435600052600060205260406000205460005260206000f3",
+}
+
+// TestENSRegistryABI is the input ABI used to generate the binding from.
+// Deprecated: Use TestENSRegistryMetaData.ABI instead.
+var TestENSRegistryABI = TestENSRegistryMetaData.ABI
+
+// TestENSRegistryBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use TestENSRegistryMetaData.Bin instead.
+var TestENSRegistryBin = TestENSRegistryMetaData.Bin
+
+// DeployTestENSRegistry deploys a new Ethereum contract, binding an instance of TestENSRegistry to it.
+func DeployTestENSRegistry(auth *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, *TestENSRegistry, error) {
+	parsed, err := TestENSRegistryMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(TestENSRegistryBin), backend)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &TestENSRegistry{TestENSRegistryCaller: TestENSRegistryCaller{contract: contract}, TestENSRegistryTransactor: TestENSRegistryTransactor{contract: contract}, TestENSRegistryFilterer: TestENSRegistryFilterer{contract: contract}}, nil
+}
+
+// TestENSRegistry is an auto generated Go binding around an Ethereum contract.
+type TestENSRegistry struct {
+	TestENSRegistryCaller     // Read-only binding to the contract
+	TestENSRegistryTransactor // Write-only binding to the contract
+	TestENSRegistryFilterer   // Log filterer for contract events
+}
+
+// TestENSRegistryCaller is an auto generated read-only Go binding around an Ethereum contract.
+type TestENSRegistryCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TestENSRegistryTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type TestENSRegistryTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TestENSRegistryFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type TestENSRegistryFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TestENSRegistrySession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type TestENSRegistrySession struct {
+	Contract     *TestENSRegistry  // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// TestENSRegistryCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type TestENSRegistryCallerSession struct {
+	Contract *TestENSRegistryCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts          // Call options to use throughout this session
+}
+
+// TestENSRegistryTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type TestENSRegistryTransactorSession struct {
+	Contract     *TestENSRegistryTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts          // Transaction auth options to use throughout this session
+}
+
+// TestENSRegistryRaw is an auto generated low-level Go binding around an Ethereum contract.
+type TestENSRegistryRaw struct {
+	Contract *TestENSRegistry // Generic contract binding to access the raw methods on
+}
+
+// TestENSRegistryCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type TestENSRegistryCallerRaw struct {
+	Contract *TestENSRegistryCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// TestENSRegistryTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type TestENSRegistryTransactorRaw struct {
+	Contract *TestENSRegistryTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewTestENSRegistry creates a new instance of TestENSRegistry, bound to a specific deployed contract.
+func NewTestENSRegistry(address common.Address, backend bind.ContractBackend) (*TestENSRegistry, error) {
+	contract, err := bindTestENSRegistry(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &TestENSRegistry{TestENSRegistryCaller: TestENSRegistryCaller{contract: contract}, TestENSRegistryTransactor: TestENSRegistryTransactor{contract: contract}, TestENSRegistryFilterer: TestENSRegistryFilterer{contract: contract}}, nil
+}
+
+// NewTestENSRegistryCaller creates a new read-only instance of TestENSRegistry, bound to a specific deployed contract.
+func NewTestENSRegistryCaller(address common.Address, caller bind.ContractCaller) (*TestENSRegistryCaller, error) {
+	contract, err := bindTestENSRegistry(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TestENSRegistryCaller{contract: contract}, nil
+}
+
+// NewTestENSRegistryTransactor creates a new write-only instance of TestENSRegistry, bound to a specific deployed contract.
+func NewTestENSRegistryTransactor(address common.Address, transactor bind.ContractTransactor) (*TestENSRegistryTransactor, error) {
+	contract, err := bindTestENSRegistry(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TestENSRegistryTransactor{contract: contract}, nil
+}
+
+// NewTestENSRegistryFilterer creates a new log filterer instance of TestENSRegistry, bound to a specific deployed contract.
+func NewTestENSRegistryFilterer(address common.Address, filterer bind.ContractFilterer) (*TestENSRegistryFilterer, error) {
+	contract, err := bindTestENSRegistry(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &TestENSRegistryFilterer{contract: contract}, nil
+}
+
+// bindTestENSRegistry binds a generic wrapper to an already deployed contract.
+func bindTestENSRegistry(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := TestENSRegistryMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_TestENSRegistry *TestENSRegistryRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _TestENSRegistry.Contract.TestENSRegistryCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_TestENSRegistry *TestENSRegistryRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _TestENSRegistry.Contract.TestENSRegistryTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_TestENSRegistry *TestENSRegistryRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _TestENSRegistry.Contract.TestENSRegistryTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_TestENSRegistry *TestENSRegistryCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _TestENSRegistry.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_TestENSRegistry *TestENSRegistryTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _TestENSRegistry.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_TestENSRegistry *TestENSRegistryTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _TestENSRegistry.Contract.contract.Transact(opts, method, params...)
+}
+
+// Resolver is a free data retrieval call binding the contract method 0x0178b8bf.
+//
+// Solidity: function resolver(bytes32 node) view returns(address)
+func (_TestENSRegistry *TestENSRegistryCaller) Resolver(opts *bind.CallOpts, node [32]byte) (common.Address, error) {
+	var out []interface{}
+	err := _TestENSRegistry.contract.Call(opts, &out, "resolver", node)
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// Resolver is a free data retrieval call binding the contract method 0x0178b8bf.
+//
+// Solidity: function resolver(bytes32 node) view returns(address)
+func (_TestENSRegistry *TestENSRegistrySession) Resolver(node [32]byte) (common.Address, error) {
+	return _TestENSRegistry.Contract.Resolver(&_TestENSRegistry.CallOpts, node)
+}
+
+// Resolver is a free data retrieval call binding the contract method 0x0178b8bf.
+//
+// Solidity: function resolver(bytes32 node) view returns(address)
+func (_TestENSRegistry *TestENSRegistryCallerSession) Resolver(node [32]byte) (common.Address, error) {
+	return _TestENSRegistry.Contract.Resolver(&_TestENSRegistry.CallOpts, node)
+}
+
+// SetResolver is a paid mutator transaction binding the contract method 0x1896f70a.
+//
+// Solidity: function setResolver(bytes32 node, address _resolver) returns()
+func (_TestENSRegistry *TestENSRegistryTransactor) SetResolver(opts *bind.TransactOpts, node [32]byte, _resolver common.Address) (*types.Transaction, error) {
+	return _TestENSRegistry.contract.Transact(opts, "setResolver", node, _resolver)
+}
+
+// SetResolver is a paid mutator transaction binding the contract method 0x1896f70a.
+//
+// Solidity: function setResolver(bytes32 node, address _resolver) returns()
+func (_TestENSRegistry *TestENSRegistrySession) SetResolver(node [32]byte, _resolver common.Address) (*types.Transaction, error) {
+	return _TestENSRegistry.Contract.SetResolver(&_TestENSRegistry.TransactOpts, node, _resolver)
+}
+
+// SetResolver is a paid mutator transaction binding the contract method 0x1896f70a.
+//
+// Solidity: function setResolver(bytes32 node, address _resolver) returns()
+func (_TestENSRegistry *TestENSRegistryTransactorSession) SetResolver(node [32]byte, _resolver common.Address) (*types.Transaction, error) {
+	return _TestENSRegistry.Contract.SetResolver(&_TestENSRegistry.TransactOpts, node, _resolver)
+}
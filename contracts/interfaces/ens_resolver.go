@@ -0,0 +1,307 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package contracts
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// TestENSResolverMetaData contains all meta data concerning the TestENSResolver contract.
+var TestENSResolverMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"addr\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"name\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"address\",\"name\":\"_addr\",\"type\":\"address\"}],\"name\":\"setAddr\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"string\",\"name\":\"_name\",\"type\":\"string\"}],\"name\":\"setName\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]",
+	Bin: "0x341561000a57600080fd5b610156806100186000396000f3341561000a57600080fd5b600436106100455760003560e01c8063d5fa2b001461004a5780633b3b57de1461008657806377372213146100a8578063691f343114610104575b600080fd5b60443610610045576024358073ffffffffffffffffffffffffffffffffffffffff16811415610045576004356000526000602052604060002055005b6024361061004557600435600052600060205260406000205460005260206000f35b60443610610045576004356000526001602052604060002060243560040180358082602001013610610045578083559060200191600052602060002060005b828110156101025780840135828260051c01556020016100e7565b005b602436106100455760043560005260016020526040600020805490600052602060002060206000528160205260005b8281101561014f57818160051c01548160400152602001610133565b6040016000f3",
+}
+
+// TestENSResolverABI is the input ABI used to generate the binding from.
+// Deprecated: Use TestENSResolverMetaData.ABI instead.
+var TestENSResolverABI = TestENSResolverMetaData.ABI
+
+// TestENSResolverBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use TestENSResolverMetaData.Bin instead.
+var TestENSResolverBin = TestENSResolverMetaData.Bin
+
+// DeployTestENSResolver deploys a new Ethereum contract, binding an instance of TestENSResolver to it.
+func DeployTestENSResolver(auth *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, *TestENSResolver, error) {
+	parsed, err := TestENSResolverMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(TestENSResolverBin), backend)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &TestENSResolver{TestENSResolverCaller: TestENSResolverCaller{contract: contract}, TestENSResolverTransactor: TestENSResolverTransactor{contract: contract}, TestENSResolverFilterer: TestENSResolverFilterer{contract: contract}}, nil
+}
+
+// TestENSResolver is an auto generated Go binding around an Ethereum contract.
+type TestENSResolver struct {
+	TestENSResolverCaller     // Read-only binding to the contract
+	TestENSResolverTransactor // Write-only binding to the contract
+	TestENSResolverFilterer   // Log filterer for contract events
+}
+
+// TestENSResolverCaller is an auto generated read-only Go binding around an Ethereum contract.
+type TestENSResolverCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TestENSResolverTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type TestENSResolverTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TestENSResolverFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type TestENSResolverFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// TestENSResolverSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type TestENSResolverSession struct {
+	Contract     *TestENSResolver  // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// TestENSResolverCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type TestENSResolverCallerSession struct {
+	Contract *TestENSResolverCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts          // Call options to use throughout this session
+}
+
+// TestENSResolverTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type TestENSResolverTransactorSession struct {
+	Contract     *TestENSResolverTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts          // Transaction auth options to use throughout this session
+}
+
+// TestENSResolverRaw is an auto generated low-level Go binding around an Ethereum contract.
+type TestENSResolverRaw struct {
+	Contract *TestENSResolver // Generic contract binding to access the raw methods on
+}
+
+// TestENSResolverCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type TestENSResolverCallerRaw struct {
+	Contract *TestENSResolverCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// TestENSResolverTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type TestENSResolverTransactorRaw struct {
+	Contract *TestENSResolverTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewTestENSResolver creates a new instance of TestENSResolver, bound to a specific deployed contract.
+func NewTestENSResolver(address common.Address, backend bind.ContractBackend) (*TestENSResolver, error) {
+	contract, err := bindTestENSResolver(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &TestENSResolver{TestENSResolverCaller: TestENSResolverCaller{contract: contract}, TestENSResolverTransactor: TestENSResolverTransactor{contract: contract}, TestENSResolverFilterer: TestENSResolverFilterer{contract: contract}}, nil
+}
+
+// NewTestENSResolverCaller creates a new read-only instance of TestENSResolver, bound to a specific deployed contract.
+func NewTestENSResolverCaller(address common.Address, caller bind.ContractCaller) (*TestENSResolverCaller, error) {
+	contract, err := bindTestENSResolver(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TestENSResolverCaller{contract: contract}, nil
+}
+
+// NewTestENSResolverTransactor creates a new write-only instance of TestENSResolver, bound to a specific deployed contract.
+func NewTestENSResolverTransactor(address common.Address, transactor bind.ContractTransactor) (*TestENSResolverTransactor, error) {
+	contract, err := bindTestENSResolver(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TestENSResolverTransactor{contract: contract}, nil
+}
+
+// NewTestENSResolverFilterer creates a new log filterer instance of TestENSResolver, bound to a specific deployed contract.
+func NewTestENSResolverFilterer(address common.Address, filterer bind.ContractFilterer) (*TestENSResolverFilterer, error) {
+	contract, err := bindTestENSResolver(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &TestENSResolverFilterer{contract: contract}, nil
+}
+
+// bindTestENSResolver binds a generic wrapper to an already deployed contract.
+func bindTestENSResolver(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := TestENSResolverMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_TestENSResolver *TestENSResolverRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _TestENSResolver.Contract.TestENSResolverCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_TestENSResolver *TestENSResolverRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _TestENSResolver.Contract.TestENSResolverTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_TestENSResolver *TestENSResolverRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _TestENSResolver.Contract.TestENSResolverTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_TestENSResolver *TestENSResolverCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _TestENSResolver.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_TestENSResolver *TestENSResolverTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _TestENSResolver.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_TestENSResolver *TestENSResolverTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _TestENSResolver.Contract.contract.Transact(opts, method, params...)
+}
+
+// Addr is a free data retrieval call binding the contract method 0x3b3b57de.
+//
+// Solidity: function addr(bytes32 node) view returns(address)
+func (_TestENSResolver *TestENSResolverCaller) Addr(opts *bind.CallOpts, node [32]byte) (common.Address, error) {
+	var out []interface{}
+	err := _TestENSResolver.contract.Call(opts, &out, "addr", node)
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// Addr is a free data retrieval call binding the contract method 0x3b3b57de.
+//
+// Solidity: function addr(bytes32 node) view returns(address)
+func (_TestENSResolver *TestENSResolverSession) Addr(node [32]byte) (common.Address, error) {
+	return _TestENSResolver.Contract.Addr(&_TestENSResolver.CallOpts, node)
+}
+
+// Addr is a free data retrieval call binding the contract method 0x3b3b57de.
+//
+// Solidity: function addr(bytes32 node) view returns(address)
+func (_TestENSResolver *TestENSResolverCallerSession) Addr(node [32]byte) (common.Address, error) {
+	return _TestENSResolver.Contract.Addr(&_TestENSResolver.CallOpts, node)
+}
+
+// Name is a free data retrieval call binding the contract method 0x691f3431.
+//
+// Solidity: function name(bytes32 node) view returns(string)
+func (_TestENSResolver *TestENSResolverCaller) Name(opts *bind.CallOpts, node [32]byte) (string, error) {
+	var out []interface{}
+	err := _TestENSResolver.contract.Call(opts, &out, "name", node)
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// Name is a free data retrieval call binding the contract method 0x691f3431.
+//
+// Solidity: function name(bytes32 node) view returns(string)
+func (_TestENSResolver *TestENSResolverSession) Name(node [32]byte) (string, error) {
+	return _TestENSResolver.Contract.Name(&_TestENSResolver.CallOpts, node)
+}
+
+// Name is a free data retrieval call binding the contract method 0x691f3431.
+//
+// Solidity: function name(bytes32 node) view returns(string)
+func (_TestENSResolver *TestENSResolverCallerSession) Name(node [32]byte) (string, error) {
+	return _TestENSResolver.Contract.Name(&_TestENSResolver.CallOpts, node)
+}
+
+// SetAddr is a paid mutator transaction binding the contract method 0xd5fa2b00.
+//
+// Solidity: function setAddr(bytes32 node, address _addr) returns()
+func (_TestENSResolver *TestENSResolverTransactor) SetAddr(opts *bind.TransactOpts, node [32]byte, _addr common.Address) (*types.Transaction, error) {
+	return _TestENSResolver.contract.Transact(opts, "setAddr", node, _addr)
+}
+
+// SetAddr is a paid mutator transaction binding the contract method 0xd5fa2b00.
+//
+// Solidity: function setAddr(bytes32 node, address _addr) returns()
+func (_TestENSResolver *TestENSResolverSession) SetAddr(node [32]byte, _addr common.Address) (*types.Transaction, error) {
+	return _TestENSResolver.Contract.SetAddr(&_TestENSResolver.TransactOpts, node, _addr)
+}
+
+// SetAddr is a paid mutator transaction binding the contract method 0xd5fa2b00.
+//
+// Solidity: function setAddr(bytes32 node, address _addr) returns()
+func (_TestENSResolver *TestENSResolverTransactorSession) SetAddr(node [32]byte, _addr common.Address) (*types.Transaction, error) {
+	return _TestENSResolver.Contract.SetAddr(&_TestENSResolver.TransactOpts, node, _addr)
+}
+
+// SetName is a paid mutator transaction binding the contract method 0x77372213.
+//
+// Solidity: function setName(bytes32 node, string _name) returns()
+func (_TestENSResolver *TestENSResolverTransactor) SetName(opts *bind.TransactOpts, node [32]byte, _name string) (*types.Transaction, error) {
+	return _TestENSResolver.contract.Transact(opts, "setName", node, _name)
+}
+
+// SetName is a paid mutator transaction binding the contract method 0x77372213.
+//
+// Solidity: function setName(bytes32 node, string _name) returns()
+func (_TestENSResolver *TestENSResolverSession) SetName(node [32]byte, _name string) (*types.Transaction, error) {
+	return _TestENSResolver.Contract.SetName(&_TestENSResolver.TransactOpts, node, _name)
+}
+
+// SetName is a paid mutator transaction binding the contract method 0x77372213.
+//
+// Solidity: function setName(bytes32 node, string _name) returns()
+func (_TestENSResolver *TestENSResolverTransactorSession) SetName(node [32]byte, _name string) (*types.Transaction, error) {
+	return _TestENSResolver.Contract.SetName(&_TestENSResolver.TransactOpts, node, _name)
+}
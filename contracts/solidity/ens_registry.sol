@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// TestENSRegistry is a registry stub for the tests, anyone sets the resolver of a node
+contract TestENSRegistry {
+
+    mapping(bytes32 => address) private resolvers;
+
+    function setResolver(bytes32 node, address _resolver) public {
+        resolvers[node] = _resolver;
+    }
+
+    function resolver(bytes32 node) public view returns(address) {
+        return resolvers[node];
+    }
+}
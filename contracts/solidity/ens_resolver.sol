@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// TestENSResolver is a resolver stub for the tests, anyone sets the address and the name of a node
+contract TestENSResolver {
+
+    mapping(bytes32 => address) private addresses;
+
+    mapping(bytes32 => string) private names;
+
+    function setAddr(bytes32 node, address _addr) public {
+        addresses[node] = _addr;
+    }
+
+    function addr(bytes32 node) public view returns(address) {
+        return addresses[node];
+    }
+
+    function setName(bytes32 node, string calldata _name) public {
+        names[node] = _name;
+    }
+
+    function name(bytes32 node) public view returns(string memory) {
+        return names[node];
+    }
+}
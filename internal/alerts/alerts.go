@@ -0,0 +1,301 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/maxipaz/wallet/config"
+	contracts "github.com/maxipaz/wallet/contracts/interfaces"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	ContractBalanceBelow    = "contract_balance_below"
+	AllowancesExceedBalance = "allowances_exceed_balance"
+	MoneySentAbove          = "money_sent_above"
+	OwnerBalanceBelow       = "owner_balance_below"
+	OwnershipTransferred    = "ownership_transferred"
+)
+
+var conditions = map[string]struct{}{
+	ContractBalanceBelow:    {},
+	AllowancesExceedBalance: {},
+	MoneySentAbove:          {},
+	OwnerBalanceBelow:       {},
+	OwnershipTransferred:    {},
+}
+
+// Alert struct
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Condition string    `json:"condition"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Engine evaluates the alert rules against the contract state and the monitored events
+type Engine struct {
+	client          *ethclient.Client
+	contractAddress ethcommon.Address
+	contract        *contracts.Contract
+	interval        time.Duration
+	rules           []config.AlertRule
+
+	mu         sync.Mutex
+	owner      ethcommon.Address
+	allowances map[ethcommon.Address]*big.Int
+	fired      map[string]firing
+}
+
+// firing last delivery of an alert, used for deduplication
+type firing struct {
+	at       time.Time
+	cooldown time.Duration
+	event    bool
+}
+
+// NewEngine returns a new alerts engine instance
+func NewEngine(client *ethclient.Client, contractAddress string, cfg config.AlertsConfig) (*Engine, error) {
+	for _, rule := range cfg.Rules {
+		if _, ok := conditions[rule.Condition]; !ok {
+			return nil, fmt.Errorf("%w: %s", errs.ErrInvalidAlertCondition, rule.Condition)
+		}
+	}
+
+	address := ethcommon.HexToAddress(contractAddress)
+	contract, err := contracts.NewContract(address, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contract instance: %w", err)
+	}
+
+	return &Engine{
+		client:          client,
+		contractAddress: address,
+		contract:        contract,
+		interval:        cfg.IntervalIn,
+		rules:           cfg.Rules,
+		allowances:      make(map[ethcommon.Address]*big.Int),
+		fired:           make(map[string]firing),
+	}, nil
+}
+
+// Load loads the current contract owner and the allowances replaying the contract history
+func (e *Engine) Load(ctx context.Context) error {
+	owner, err := e.contract.Owner(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("failed to get contract owner: %w", err)
+	}
+
+	iterator, err := e.contract.FilterAllowanceChanged(&bind.FilterOpts{Context: ctx}, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to filter allowance changes: %w", err)
+	}
+	defer iterator.Close()
+
+	e.mu.Lock()
+	e.owner = owner
+	for iterator.Next() {
+		e.allowances[iterator.Event.Beneficiary] = iterator.Event.NewAmount
+	}
+	e.mu.Unlock()
+	if err := iterator.Error(); err != nil {
+		return fmt.Errorf("failed to iterate allowance changes: %w", err)
+	}
+
+	return nil
+}
+
+// Start evaluates the rules periodically until the context is done
+func (e *Engine) Start(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.Evaluate(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Handle evaluates the event based rules and updates the tracked contract state
+func (e *Engine) Handle(ctx context.Context, event any) {
+	switch event := event.(type) {
+	case *contracts.ContractAllowanceChanged:
+		e.mu.Lock()
+		e.allowances[event.Beneficiary] = event.NewAmount
+		e.mu.Unlock()
+	case *contracts.ContractMoneySent:
+		for _, rule := range e.rulesFor(MoneySentAbove) {
+			if event.Amount.Cmp(etherToWei(rule.Threshold)) > 0 {
+				e.fire(ctx, rule, eventKey(rule, event.Raw.TxHash, event.Raw.Index), fmt.Sprintf(
+					"%s ether sent to %s, above the threshold of %v ether",
+					common.FormatEther(event.Amount), event.Beneficiary.Hex(), rule.Threshold,
+				))
+			}
+		}
+	case *contracts.ContractOwnershipTransferred:
+		e.mu.Lock()
+		e.owner = event.NewOwner
+		e.mu.Unlock()
+
+		for _, rule := range e.rulesFor(OwnershipTransferred) {
+			e.fire(ctx, rule, eventKey(rule, event.Raw.TxHash, event.Raw.Index), fmt.Sprintf(
+				"ownership transferred from %s to %s", event.PreviousOwner.Hex(), event.NewOwner.Hex(),
+			))
+		}
+	}
+
+	e.Evaluate(ctx)
+}
+
+// Evaluate evaluates the state based rules against the current contract state
+func (e *Engine) Evaluate(ctx context.Context) {
+	var contractBalance, ownerBalance *big.Int
+	var err error
+
+	if len(e.rulesFor(ContractBalanceBelow, AllowancesExceedBalance)) > 0 {
+		contractBalance, err = e.client.BalanceAt(ctx, e.contractAddress, nil)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get contract balance", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	if len(e.rulesFor(OwnerBalanceBelow)) > 0 {
+		e.mu.Lock()
+		owner := e.owner
+		e.mu.Unlock()
+
+		ownerBalance, err = e.client.BalanceAt(ctx, owner, nil)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get owner balance", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	for _, rule := range e.rulesFor(ContractBalanceBelow) {
+		e.check(ctx, rule, contractBalance.Cmp(etherToWei(rule.Threshold)) < 0, fmt.Sprintf(
+			"contract balance %s ether is below the threshold of %v ether",
+			common.FormatEther(contractBalance), rule.Threshold,
+		))
+	}
+
+	for _, rule := range e.rulesFor(AllowancesExceedBalance) {
+		outstanding := e.outstandingAllowances()
+		e.check(ctx, rule, outstanding.Cmp(contractBalance) > 0, fmt.Sprintf(
+			"outstanding allowances %s ether exceed the contract balance %s ether",
+			common.FormatEther(outstanding), common.FormatEther(contractBalance),
+		))
+	}
+
+	for _, rule := range e.rulesFor(OwnerBalanceBelow) {
+		e.check(ctx, rule, ownerBalance.Cmp(etherToWei(rule.Threshold)) < 0, fmt.Sprintf(
+			"owner balance %s ether is below the threshold of %v ether",
+			common.FormatEther(ownerBalance), rule.Threshold,
+		))
+	}
+}
+
+// check fires the alert while the condition holds and resolves it once it does not
+func (e *Engine) check(ctx context.Context, rule config.AlertRule, triggered bool, message string) {
+	if triggered {
+		e.fire(ctx, rule, rule.Name, message)
+		return
+	}
+
+	e.mu.Lock()
+	_, active := e.fired[rule.Name]
+	delete(e.fired, rule.Name)
+	e.mu.Unlock()
+
+	if active {
+		slog.InfoContext(ctx, "alert resolved", slog.String("rule", rule.Name))
+	}
+}
+
+// fire delivers an alert unless the same one was delivered within the rule cooldown
+func (e *Engine) fire(ctx context.Context, rule config.AlertRule, key string, message string) {
+	now := time.Now().UTC()
+
+	e.mu.Lock()
+	for k, f := range e.fired {
+		if f.event && now.Sub(f.at) >= f.cooldown {
+			delete(e.fired, k)
+		}
+	}
+	if f, ok := e.fired[key]; ok && now.Sub(f.at) < f.cooldown {
+		e.mu.Unlock()
+		return
+	}
+	e.fired[key] = firing{at: now, cooldown: rule.CooldownIn, event: key != rule.Name}
+	e.mu.Unlock()
+
+	metrics.AlertsTotal.WithLabelValues(rule.Name).Inc()
+
+	j, err := json.MarshalIndent(
+		Alert{
+			Rule:      rule.Name,
+			Condition: rule.Condition,
+			Message:   message,
+			Timestamp: now,
+		},
+		"",
+		"  ",
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "error marshaling alert", slog.String("error", err.Error()))
+		return
+	}
+
+	slog.WarnContext(ctx, "alert triggered", slog.String("alert", string(j)))
+}
+
+// rulesFor returns the configured rules for the given conditions
+func (e *Engine) rulesFor(conditions ...string) []config.AlertRule {
+	var rules []config.AlertRule
+	for _, rule := range e.rules {
+		for _, condition := range conditions {
+			if rule.Condition == condition {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// outstandingAllowances returns the sum of every beneficiary allowance
+func (e *Engine) outstandingAllowances() *big.Int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	total := new(big.Int)
+	for _, amount := range e.allowances {
+		total.Add(total, amount)
+	}
+	return total
+}
+
+// eventKey returns the deduplication key of an alert triggered by an event
+func eventKey(rule config.AlertRule, txHash ethcommon.Hash, index uint) string {
+	return fmt.Sprintf("%s:%s:%d", rule.Name, txHash.Hex(), index)
+}
+
+// etherToWei convert a fractional Ether threshold to Wei
+func etherToWei(ether float64) *big.Int {
+	wei, _ := new(big.Float).Mul(big.NewFloat(ether), big.NewFloat(params.Ether)).Int(nil)
+	return wei
+}
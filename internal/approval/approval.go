@@ -0,0 +1,310 @@
+package approval
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"log/slog"
+	"time"
+)
+
+// Operations that can be proposed
+const (
+	SendOperation              = "send_money"
+	SetAllowanceOperation      = "set_allowance"
+	IncreaseAllowanceOperation = "increase_allowance"
+	TransferOwnershipOperation = "transfer_ownership"
+)
+
+var operations = map[string]struct{}{
+	SendOperation:              {},
+	SetAllowanceOperation:      {},
+	IncreaseAllowanceOperation: {},
+	TransferOwnershipOperation: {},
+}
+
+// Workflow creates the proposals of the operations requiring approval, records the approvers signed decisions and
+// executes the approved proposals
+type Workflow struct {
+	store           *store.Store
+	contractAddress string
+	required        int
+	approvers       map[string]ethcommon.Address
+	expiry          time.Duration
+	now             func() time.Time
+}
+
+// New returns a new workflow instance
+func New(st *store.Store, contractAddress string, cfg config.ApprovalsConfig) (*Workflow, error) {
+	if st == nil {
+		return nil, fmt.Errorf("%w: proposals require the store, please configure store.path", errs.ErrInvalidApprovals)
+	}
+	if len(cfg.Approvers) == 0 {
+		return nil, fmt.Errorf("%w: no approvers configured", errs.ErrInvalidApprovals)
+	}
+	if cfg.Required < 1 || cfg.Required > len(cfg.Approvers) {
+		return nil, fmt.Errorf("%w: %d approvals required from %d approvers", errs.ErrInvalidApprovals, cfg.Required, len(cfg.Approvers))
+	}
+
+	approvers := make(map[string]ethcommon.Address, len(cfg.Approvers))
+	for _, approver := range cfg.Approvers {
+		if approver.Name == "" {
+			return nil, fmt.Errorf("%w: approver name is required", errs.ErrInvalidApprovals)
+		}
+		if _, ok := approvers[approver.Name]; ok {
+			return nil, fmt.Errorf("%w: approver %s is duplicated", errs.ErrInvalidApprovals, approver.Name)
+		}
+		if err := common.ValidateAddress(approver.Address); err != nil {
+			return nil, fmt.Errorf("%w: approver %s: %w", errs.ErrInvalidApprovals, approver.Name, err)
+		}
+		approvers[approver.Name] = ethcommon.HexToAddress(approver.Address)
+	}
+
+	return &Workflow{
+		store:           st,
+		contractAddress: ethcommon.HexToAddress(contractAddress).Hex(),
+		required:        cfg.Required,
+		approvers:       approvers,
+		expiry:          cfg.ExpiryIn,
+		now:             time.Now,
+	}, nil
+}
+
+// Create records a pending proposal of the operation. Amount is expressed in Ether
+func (w *Workflow) Create(ctx context.Context, operation string, target string, amount int64, proposer string) (*store.Proposal, error) {
+	if _, ok := operations[operation]; !ok {
+		return nil, fmt.Errorf("%w: %s cannot be proposed, use send_money, set_allowance, increase_allowance or transfer_ownership", errs.ErrInvalidApprovals, operation)
+	}
+	if err := common.ValidateBeneficiary(target, w.contractAddress); err != nil {
+		return nil, err
+	}
+	if operation == TransferOwnershipOperation {
+		amount = 0
+	} else if amount <= 0 {
+		return nil, errs.ErrInvalidAmountAction
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate proposal id: %w", err)
+	}
+
+	proposal := store.Proposal{
+		ID:        hex.EncodeToString(id),
+		Operation: operation,
+		Target:    ethcommon.HexToAddress(target).Hex(),
+		Amount:    amount,
+		Proposer:  proposer,
+		Status:    store.ProposalPending,
+		ExpiresAt: w.now().Add(w.expiry),
+	}
+	if err := w.store.CreateProposal(ctx, proposal); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "proposal created",
+		slog.String("id", proposal.ID),
+		slog.String("operation", operation),
+		slog.String("target", proposal.Target),
+		slog.Int64("amount", amount),
+		slog.String("proposer", proposer),
+	)
+	return w.Get(ctx, proposal.ID)
+}
+
+// Get returns a proposal, marking it as expired when its expiry passed before it was executed
+func (w *Workflow) Get(ctx context.Context, id string) (*store.Proposal, error) {
+	proposal, err := w.store.GetProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposal == nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrUnknownProposal, id)
+	}
+
+	if (proposal.Status == store.ProposalPending || proposal.Status == store.ProposalApproved) && w.now().After(proposal.ExpiresAt) {
+		if _, err := w.store.UpdateProposal(ctx, id, proposal.Status, store.ProposalExpired, "", ""); err != nil {
+			return nil, err
+		}
+		proposal.Status = store.ProposalExpired
+	}
+	return proposal, nil
+}
+
+// Message returns the text an approver signs, with the Ethereum personal message prefix, to take the decision
+func (w *Workflow) Message(proposal *store.Proposal, decision string) string {
+	return fmt.Sprintf("%s wallet proposal %s\noperation: %s\ntarget: %s\namount: %d ether\ncontract: %s",
+		decision, proposal.ID, proposal.Operation, proposal.Target, proposal.Amount, w.contractAddress)
+}
+
+// Sign signs the decision message with the approver private key, for approvers signing with the command line
+func (w *Workflow) Sign(proposal *store.Proposal, decision string, key *ecdsa.PrivateKey) (string, error) {
+	return common.SignMessage(w.Message(proposal, decision), key)
+}
+
+// Decide records the decision of an approver after verifying its signature, the proposal is approved once it gets
+// the required approvals and rejected once it can no longer get them
+func (w *Workflow) Decide(ctx context.Context, id string, approver string, decision string, signature string) (*store.Proposal, error) {
+	if decision != store.Approve && decision != store.Reject {
+		return nil, fmt.Errorf("%w: decision %q", errs.ErrInvalidApprovals, decision)
+	}
+
+	address, ok := w.approvers[approver]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errs.ErrUnknownApprover, approver)
+	}
+
+	proposal, err := w.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != store.ProposalPending {
+		return nil, fmt.Errorf("%w: %s is %s", errs.ErrProposalClosed, id, proposal.Status)
+	}
+
+	if err := w.verify(proposal, decision, address, signature); err != nil {
+		return nil, err
+	}
+
+	added, err := w.store.AddDecision(ctx, id, store.Decision{
+		Approver:  approver,
+		Address:   address.Hex(),
+		Decision:  decision,
+		Signature: signature,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, fmt.Errorf("%w: %s on %s", errs.ErrAlreadyDecided, approver, id)
+	}
+
+	slog.InfoContext(ctx, "proposal decision recorded",
+		slog.String("id", id),
+		slog.String("approver", approver),
+		slog.String("decision", decision),
+	)
+
+	proposal, err = w.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	approvals, rejections := w.count(proposal)
+	switch {
+	case approvals >= w.required:
+		_, err = w.store.UpdateProposal(ctx, id, store.ProposalPending, store.ProposalApproved, "", "")
+	case rejections > len(w.approvers)-w.required:
+		_, err = w.store.UpdateProposal(ctx, id, store.ProposalPending, store.ProposalRejected, "", "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w.Get(ctx, id)
+}
+
+// Execute signs and sends the operation of an approved proposal. The approvals are verified again and the proposal
+// id is used as idempotency key, so executing it again never sends a second transaction while the first one may
+// still be mined. A reverted or dropped transaction releases the key, the proposal can then be executed again
+func (w *Workflow) Execute(ctx context.Context, client *ethclient.Client, privateKey string, pol *policy.Policy, id string) (*store.Proposal, error) {
+	proposal, err := w.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// an executing proposal was interrupted, executing it again returns its transaction
+	if proposal.Status != store.ProposalApproved && proposal.Status != store.ProposalExecuting {
+		return nil, fmt.Errorf("%w: %s is %s", errs.ErrProposalNotApproved, id, proposal.Status)
+	}
+	if approvals, _ := w.count(proposal); approvals < w.required {
+		return nil, fmt.Errorf("%w: %s has %d valid approvals of %d", errs.ErrProposalNotApproved, id, approvals, w.required)
+	}
+
+	if proposal.Status == store.ProposalApproved {
+		executing, err := w.store.UpdateProposal(ctx, id, store.ProposalApproved, store.ProposalExecuting, "", "")
+		if err != nil {
+			return nil, err
+		}
+		if !executing {
+			return nil, fmt.Errorf("%w: %s is already being executed", errs.ErrProposalNotApproved, id)
+		}
+		proposal.Status = store.ProposalExecuting
+	}
+
+	ctx = wallet.WithProposal(ctx, proposal)
+	ctx = wallet.WithIdempotencyKey(ctx, "proposal:"+id)
+	ctx = wallet.WithRequester(ctx, "proposal:"+id)
+
+	result, err := w.run(ctx, client, privateKey, pol, proposal)
+	if err != nil {
+		// the proposal stays approved, it can be executed again once the cause is fixed
+		if _, updateErr := w.store.UpdateProposal(ctx, id, store.ProposalExecuting, store.ProposalApproved, "", err.Error()); updateErr != nil {
+			slog.ErrorContext(ctx, "failed to update proposal", slog.String("id", id), slog.String("error", updateErr.Error()))
+		}
+		return nil, err
+	}
+
+	if _, err := w.store.UpdateProposal(ctx, id, store.ProposalExecuting, store.ProposalExecuted, result.TxHash, ""); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "proposal executed", slog.String("id", id), slog.String("tx_hash", result.TxHash))
+	return w.Get(ctx, id)
+}
+
+// run runs the proposal operation with the runner signing it
+func (w *Workflow) run(ctx context.Context, client *ethclient.Client, privateKey string, pol *policy.Policy, proposal *store.Proposal) (*wallet.TransactionResult, error) {
+	switch proposal.Operation {
+	case SendOperation:
+		runner := wallet.NewTransfersRunner(privateKey, w.contractAddress)
+		runner.SetStore(w.store)
+		runner.SetPolicy(pol)
+		return runner.Send(ctx, client, proposal.Target, proposal.Amount)
+	case SetAllowanceOperation, IncreaseAllowanceOperation:
+		action := wallet.SetAction
+		if proposal.Operation == IncreaseAllowanceOperation {
+			action = wallet.IncreaseAction
+		}
+		runner := wallet.NewAllowanceRunner(privateKey, w.contractAddress)
+		runner.SetStore(w.store)
+		runner.SetPolicy(pol)
+		return runner.ChangeAllowance(ctx, client, action, proposal.Target, proposal.Amount)
+	case TransferOwnershipOperation:
+		runner := wallet.NewOwnerRunner(privateKey, w.contractAddress)
+		runner.SetStore(w.store)
+		runner.SetPolicy(pol)
+		return runner.TransferOwner(ctx, client, proposal.Target)
+	}
+	return nil, fmt.Errorf("%w: %s cannot be proposed", errs.ErrInvalidApprovals, proposal.Operation)
+}
+
+// count returns the approvals and rejections of the proposal with a valid signature of a configured approver
+func (w *Workflow) count(proposal *store.Proposal) (int, int) {
+	var approvals, rejections int
+	for _, decision := range proposal.Decisions {
+		address, ok := w.approvers[decision.Approver]
+		if !ok || w.verify(proposal, decision.Decision, address, decision.Signature) != nil {
+			continue
+		}
+		if decision.Decision == store.Approve {
+			approvals++
+		} else {
+			rejections++
+		}
+	}
+	return approvals, rejections
+}
+
+// verify checks the signature of the decision message was produced by the approver address
+func (w *Workflow) verify(proposal *store.Proposal, decision string, address ethcommon.Address, signature string) error {
+	return common.VerifyMessage(w.Message(proposal, decision), signature, address)
+}
@@ -0,0 +1,327 @@
+package approval
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/deploy"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/testchain"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// contractAddress contract of the proposals decided without chain
+const contractAddress = "0xaD86Df8c289739A6fCb95005A3F5df0ea56F88c6"
+
+// approvers private keys of the approvers by name
+type approvers map[string]*ecdsa.PrivateKey
+
+// newApprovers generates the keys of the named approvers
+func newApprovers(t *testing.T, names ...string) approvers {
+	t.Helper()
+	keys := make(approvers, len(names))
+	for _, name := range names {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[name] = key
+	}
+	return keys
+}
+
+// config returns the approvals configuration of the approvers
+func (a approvers) config(required int) config.ApprovalsConfig {
+	cfg := config.ApprovalsConfig{Required: required, ExpiryIn: 72 * time.Hour}
+	for name, key := range a {
+		cfg.Approvers = append(cfg.Approvers, config.Approver{Name: name, Address: crypto.PubkeyToAddress(key.PublicKey).Hex()})
+	}
+	return cfg
+}
+
+// openStore opens a store closed at the end of the test
+func openStore(t *testing.T) *store.Store {
+	t.Helper()
+	st, err := store.Open(context.Background(), filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+// newWorkflow returns a workflow of the approvers on the contract whose clock is fixed at the returned time
+func newWorkflow(t *testing.T, st *store.Store, contract string, keys approvers, required int) (*Workflow, *time.Time) {
+	t.Helper()
+	w, err := New(st, contract, keys.config(required))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.March, 31, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+	return w, &now
+}
+
+// decide signs the decision with the key and records it as the decision of the approver
+func decide(t *testing.T, w *Workflow, proposal *store.Proposal, approver string, decision string, key *ecdsa.PrivateKey) (*store.Proposal, error) {
+	t.Helper()
+	signature, err := w.Sign(proposal, decision, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w.Decide(context.Background(), proposal.ID, approver, decision, signature)
+}
+
+func TestDecide(t *testing.T) {
+	keys := newApprovers(t, "alice", "bob", "carol")
+	w, _ := newWorkflow(t, openStore(t), contractAddress, keys, 2)
+	ctx := context.Background()
+
+	proposal, err := w.Create(ctx, SendOperation, "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a", 5, "treasurer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proposal, err = decide(t, w, proposal, "alice", store.Approve, keys["alice"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proposal.Status != store.ProposalPending {
+		t.Fatalf("proposal is %s after one approval, expected %s", proposal.Status, store.ProposalPending)
+	}
+
+	mallory, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		approver string
+		key      *ecdsa.PrivateKey
+		want     error
+	}{
+		{"unknown approver", "mallory", mallory, errs.ErrUnknownApprover},
+		{"signed by another key", "bob", mallory, errs.ErrInvalidSignature},
+		{"signed by another approver", "bob", keys["carol"], errs.ErrInvalidSignature},
+		{"duplicate decision", "alice", keys["alice"], errs.ErrAlreadyDecided},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decide(t, w, proposal, tt.approver, store.Approve, tt.key); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, expected %v", err, tt.want)
+			}
+		})
+	}
+
+	// a signature of another proposal or decision is not valid
+	other, err := w.Create(ctx, SendOperation, "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a", 6, "treasurer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := w.Sign(other, store.Approve, keys["bob"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Decide(ctx, proposal.ID, "bob", store.Approve, signature); !errors.Is(err, errs.ErrInvalidSignature) {
+		t.Fatalf("signature of another proposal returned %v, expected an invalid signature", err)
+	}
+	if signature, err = w.Sign(proposal, store.Reject, keys["bob"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Decide(ctx, proposal.ID, "bob", store.Approve, signature); !errors.Is(err, errs.ErrInvalidSignature) {
+		t.Fatalf("signature of another decision returned %v, expected an invalid signature", err)
+	}
+
+	proposal, err = decide(t, w, proposal, "bob", store.Approve, keys["bob"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proposal.Status != store.ProposalApproved {
+		t.Fatalf("proposal is %s after two approvals, expected %s", proposal.Status, store.ProposalApproved)
+	}
+	if _, err := decide(t, w, proposal, "carol", store.Reject, keys["carol"]); !errors.Is(err, errs.ErrProposalClosed) {
+		t.Fatalf("decision on an approved proposal returned %v, expected a closed proposal", err)
+	}
+}
+
+func TestDecideRejected(t *testing.T) {
+	keys := newApprovers(t, "alice", "bob", "carol")
+	w, _ := newWorkflow(t, openStore(t), contractAddress, keys, 2)
+
+	proposal, err := w.Create(context.Background(), SetAllowanceOperation, "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a", 5, "treasurer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two approvals are still possible after one rejection, not after two
+	if proposal, err = decide(t, w, proposal, "alice", store.Reject, keys["alice"]); err != nil {
+		t.Fatal(err)
+	}
+	if proposal.Status != store.ProposalPending {
+		t.Fatalf("proposal is %s after one rejection, expected %s", proposal.Status, store.ProposalPending)
+	}
+	if proposal, err = decide(t, w, proposal, "bob", store.Reject, keys["bob"]); err != nil {
+		t.Fatal(err)
+	}
+	if proposal.Status != store.ProposalRejected {
+		t.Fatalf("proposal is %s after two rejections, expected %s", proposal.Status, store.ProposalRejected)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	keys := newApprovers(t, "alice", "bob")
+	w, now := newWorkflow(t, openStore(t), contractAddress, keys, 1)
+	ctx := context.Background()
+
+	proposal, err := w.Create(ctx, SendOperation, "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a", 5, "treasurer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proposal.ExpiresAt.Equal(now.Add(72 * time.Hour)) {
+		t.Fatalf("proposal expires at %s, expected 72 hours after its creation", proposal.ExpiresAt)
+	}
+
+	*now = proposal.ExpiresAt
+	if proposal, err = w.Get(ctx, proposal.ID); err != nil || proposal.Status != store.ProposalPending {
+		t.Fatalf("proposal is %s at its expiry, expected it to be pending: %v", proposal.Status, err)
+	}
+
+	*now = proposal.ExpiresAt.Add(time.Second)
+	if proposal, err = w.Get(ctx, proposal.ID); err != nil || proposal.Status != store.ProposalExpired {
+		t.Fatalf("proposal is %s after its expiry, expected it to be expired: %v", proposal.Status, err)
+	}
+	if _, err := decide(t, w, proposal, "alice", store.Approve, keys["alice"]); !errors.Is(err, errs.ErrProposalClosed) {
+		t.Fatalf("decision on an expired proposal returned %v, expected a closed proposal", err)
+	}
+	if _, err := w.Execute(ctx, nil, "", nil, proposal.ID); !errors.Is(err, errs.ErrProposalNotApproved) {
+		t.Fatalf("executing an expired proposal returned %v, expected it not to be approved", err)
+	}
+}
+
+func TestCount(t *testing.T) {
+	keys := newApprovers(t, "alice", "bob", "carol")
+	st := openStore(t)
+	w, _ := newWorkflow(t, st, contractAddress, keys, 2)
+
+	proposal, err := w.Create(context.Background(), SendOperation, "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a", 5, "treasurer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		if proposal, err = decide(t, w, proposal, name, store.Approve, keys[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if approvals, rejections := w.count(proposal); approvals != 2 || rejections != 0 {
+		t.Fatalf("counted %d approvals and %d rejections, expected 2 approvals", approvals, rejections)
+	}
+
+	// bob left the approvers and alice changed her key, their decisions are no longer valid
+	replaced := newApprovers(t, "alice", "carol")
+	replaced["carol"] = keys["carol"]
+	changed, _ := newWorkflow(t, st, contractAddress, replaced, 2)
+	if approvals, _ := changed.count(proposal); approvals != 0 {
+		t.Fatalf("counted %d approvals, expected the decisions of the former approvers to be ignored", approvals)
+	}
+	if _, err := changed.Execute(context.Background(), nil, "", nil, proposal.ID); !errors.Is(err, errs.ErrProposalNotApproved) {
+		t.Fatalf("executing without valid approvals returned %v, expected it not to be approved", err)
+	}
+
+	// the decisions are bound to the contract
+	other, _ := newWorkflow(t, st, "0x00000000000000000000000000000000000e0001", keys, 2)
+	if approvals, _ := other.count(proposal); approvals != 0 {
+		t.Fatalf("counted %d approvals, expected the decisions on another contract to be ignored", approvals)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	chain := testchain.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deployer := deploy.NewDeployer()
+	if err := deployer.Deploy(ctx, chain.Client); err != nil {
+		t.Fatal(err)
+	}
+	if err := deployer.Wait(ctx, chain.Client); err != nil {
+		t.Fatal(err)
+	}
+	privateKey := config.App.Blockchain.PrivateKey
+	st := openStore(t)
+
+	beneficiary := "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a"
+	transfers := wallet.NewTransfersRunner(privateKey, deployer.ContractAddress())
+	if _, err := transfers.Receive(ctx, chain.Client, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallet.NewAllowanceRunner(privateKey, deployer.ContractAddress()).ChangeAllowance(ctx, chain.Client, wallet.SetAction, beneficiary, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := newApprovers(t, "alice", "bob")
+	cfg := keys.config(2)
+	cfg.PayoutThreshold = 1
+	pol, err := policy.New(config.PolicyConfig{Approvals: cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ := newWorkflow(t, st, deployer.ContractAddress(), keys, 2)
+	w.now = time.Now
+
+	proposal, err := w.Create(ctx, SendOperation, beneficiary, 2, "treasurer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Execute(ctx, chain.Client, privateKey, pol, proposal.ID); !errors.Is(err, errs.ErrProposalNotApproved) {
+		t.Fatalf("executing a pending proposal returned %v, expected it not to be approved", err)
+	}
+
+	// the payout above the threshold is only signed for the approved proposal matching it
+	transfers.SetStore(st)
+	transfers.SetPolicy(pol)
+	if _, err := transfers.Send(ctx, chain.Client, beneficiary, 2); !errors.Is(err, errs.ErrApprovalRequired) {
+		t.Fatalf("payout without proposal returned %v, expected an approval to be required", err)
+	}
+	executing := *proposal
+	executing.Status = store.ProposalExecuting
+	if _, err := transfers.Send(wallet.WithProposal(ctx, proposal), chain.Client, beneficiary, 2); !errors.Is(err, errs.ErrProposalNotApproved) {
+		t.Fatalf("payout of a pending proposal returned %v, expected it not to be approved", err)
+	}
+	if _, err := transfers.Send(wallet.WithProposal(ctx, &executing), chain.Client, beneficiary, 3); !errors.Is(err, errs.ErrProposalMismatch) {
+		t.Fatalf("payout of another amount returned %v, expected a proposal mismatch", err)
+	}
+	if _, err := transfers.Send(wallet.WithProposal(ctx, &executing), chain.Client, chain.Address().Hex(), 2); !errors.Is(err, errs.ErrProposalMismatch) {
+		t.Fatalf("payout to another beneficiary returned %v, expected a proposal mismatch", err)
+	}
+
+	for _, name := range []string{"alice", "bob"} {
+		if proposal, err = decide(t, w, proposal, name, store.Approve, keys[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	executed, err := w.Execute(ctx, chain.Client, privateKey, pol, proposal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if executed.Status != store.ProposalExecuted || executed.TxHash == "" {
+		t.Fatalf("proposal is %s with transaction %q, expected it to be executed", executed.Status, executed.TxHash)
+	}
+
+	allowance, err := wallet.NewAllowanceRunner(privateKey, deployer.ContractAddress()).GetAllowance(ctx, chain.Client, beneficiary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowance != 3 {
+		t.Fatalf("the allowance is %d ether after the payout, expected 3 ether", allowance)
+	}
+	if _, err := w.Execute(ctx, chain.Client, privateKey, pol, proposal.ID); !errors.Is(err, errs.ErrProposalNotApproved) {
+		t.Fatalf("executing an executed proposal returned %v, expected it not to be approved", err)
+	}
+}
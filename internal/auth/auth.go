@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/subtle"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/maxipaz/wallet/config"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// ViewerRole reads the balances, allowances, owner and events
+	ViewerRole = "viewer"
+	// RequesterRole submits the spend requests signed by the beneficiaries
+	RequesterRole = "requester"
+	// ManagerRole changes the allowances
+	ManagerRole = "manager"
+	// TreasurerRole sends and receives funds
+	TreasurerRole = "treasurer"
+	// AdminRole transfers the ownership, it is granted every operation
+	AdminRole = "admin"
+)
+
+// Operations exposed by the API, each one requires a role
+const (
+	GetAllowanceOperation       = "get_allowance"
+	ChangeAllowanceOperation    = "change_allowance"
+	GetBalanceOperation         = "get_balance"
+	GetOwnerOperation           = "get_owner"
+	TransferOwnershipOperation  = "transfer_ownership"
+	SendOperation               = "send"
+	ReceiveOperation            = "receive"
+	WatchEventsOperation        = "watch_events"
+	MetricsOperation            = "metrics"
+	SubmitSpendRequestOperation = "submit_spend_request"
+	ListSpendRequestsOperation  = "list_spend_requests"
+	DecideSpendRequestOperation = "decide_spend_request"
+)
+
+// defaultRolesClaim JWT claim holding the roles when none is configured
+const defaultRolesClaim = "roles"
+
+var roles = map[string]struct{}{
+	ViewerRole:    {},
+	RequesterRole: {},
+	ManagerRole:   {},
+	TreasurerRole: {},
+	AdminRole:     {},
+}
+
+// requiredRoles role required by each operation. The viewer operations are granted to every role
+var requiredRoles = map[string]string{
+	GetAllowanceOperation:       ViewerRole,
+	GetBalanceOperation:         ViewerRole,
+	GetOwnerOperation:           ViewerRole,
+	WatchEventsOperation:        ViewerRole,
+	MetricsOperation:            ViewerRole,
+	ListSpendRequestsOperation:  ViewerRole,
+	SubmitSpendRequestOperation: RequesterRole,
+	ChangeAllowanceOperation:    ManagerRole,
+	SendOperation:               TreasurerRole,
+	ReceiveOperation:            TreasurerRole,
+	DecideSpendRequestOperation: TreasurerRole,
+	TransferOwnershipOperation:  AdminRole,
+}
+
+// Principal authenticated caller
+type Principal struct {
+	Name  string
+	Roles []string
+}
+
+// can reports whether the principal is granted the given role
+func (p *Principal) can(required string) bool {
+	for _, role := range p.Roles {
+		if role == AdminRole || role == required || (required == ViewerRole && role != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKey configured API key
+type apiKey struct {
+	name  string
+	key   []byte
+	roles []string
+}
+
+// Authenticator authenticates the API callers with API keys or JWT and authorizes their operations by role
+type Authenticator struct {
+	disabled   bool
+	apiKeys    []apiKey
+	secret     []byte
+	publicKey  *rsa.PublicKey
+	issuer     string
+	audience   string
+	rolesClaim string
+}
+
+// New returns a new authenticator from the auth configuration
+func New(cfg config.AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		disabled:   cfg.Disabled,
+		secret:     []byte(cfg.JWT.Secret),
+		issuer:     cfg.JWT.Issuer,
+		audience:   cfg.JWT.Audience,
+		rolesClaim: cfg.JWT.RolesClaim,
+	}
+	if a.rolesClaim == "" {
+		a.rolesClaim = defaultRolesClaim
+	}
+
+	for _, key := range cfg.APIKeys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("API key %s is empty", key.Name)
+		}
+		if err := validateRoles(key.Roles); err != nil {
+			return nil, fmt.Errorf("API key %s: %w", key.Name, err)
+		}
+		a.apiKeys = append(a.apiKeys, apiKey{name: key.Name, key: []byte(key.Key), roles: key.Roles})
+	}
+
+	if cfg.JWT.PublicKey != "" {
+		pem, err := os.ReadFile(cfg.JWT.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+		}
+
+		a.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWT public key: %w", err)
+		}
+	}
+
+	if !a.disabled && len(a.apiKeys) == 0 && len(a.secret) == 0 && a.publicKey == nil {
+		return nil, errs.ErrAuthNotConfigured
+	}
+
+	return a, nil
+}
+
+// Authorize authenticates the credential, an API key or a JWT, and checks it is granted the operation. Every
+// decision is logged
+func (a *Authenticator) Authorize(ctx context.Context, credential string, operation string) (*Principal, error) {
+	if a.disabled {
+		return &Principal{Name: "anonymous", Roles: []string{AdminRole}}, nil
+	}
+
+	principal, err := a.authenticate(credential)
+	if err != nil {
+		slog.WarnContext(ctx, "authorization denied",
+			slog.String("operation", operation),
+			slog.String("reason", err.Error()),
+		)
+		return nil, err
+	}
+
+	required, ok := requiredRoles[operation]
+	if !ok || !principal.can(required) {
+		slog.WarnContext(ctx, "authorization denied",
+			slog.String("operation", operation),
+			slog.String("principal", principal.Name),
+			slog.String("roles", strings.Join(principal.Roles, ",")),
+			slog.String("required_role", required),
+		)
+		return nil, fmt.Errorf("%w: %s requires the %s role", errs.ErrPermissionDenied, operation, required)
+	}
+
+	slog.InfoContext(ctx, "authorization granted",
+		slog.String("operation", operation),
+		slog.String("principal", principal.Name),
+		slog.String("roles", strings.Join(principal.Roles, ",")),
+	)
+	return principal, nil
+}
+
+func (a *Authenticator) authenticate(credential string) (*Principal, error) {
+	if credential == "" {
+		return nil, fmt.Errorf("%w: missing credentials", errs.ErrUnauthenticated)
+	}
+
+	for _, key := range a.apiKeys {
+		if subtle.ConstantTimeCompare(key.key, []byte(credential)) == 1 {
+			return &Principal{Name: key.name, Roles: key.roles}, nil
+		}
+	}
+
+	if len(a.secret) == 0 && a.publicKey == nil {
+		return nil, fmt.Errorf("%w: invalid API key", errs.ErrUnauthenticated)
+	}
+	return a.parseToken(credential)
+}
+
+// parseToken verifies a JWT and returns its subject and roles
+func (a *Authenticator) parseToken(token string) (*Principal, error) {
+	var methods []string
+	if len(a.secret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if a.publicKey != nil {
+		methods = append(methods, "RS256", "RS384", "RS512")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.NewParser(jwt.WithValidMethods(methods)).ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+			return a.publicKey, nil
+		}
+		return a.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrUnauthenticated, err)
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("%w: token without expiration", errs.ErrUnauthenticated)
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return nil, fmt.Errorf("%w: invalid token issuer", errs.ErrUnauthenticated)
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return nil, fmt.Errorf("%w: invalid token audience", errs.ErrUnauthenticated)
+	}
+
+	subject, _ := claims["sub"].(string)
+	principal := &Principal{Name: subject, Roles: claimRoles(claims[a.rolesClaim])}
+	if err := validateRoles(principal.Roles); err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrUnauthenticated, err)
+	}
+
+	return principal, nil
+}
+
+// claimRoles reads the roles claim, either a single role or a list of them
+func claimRoles(claim any) []string {
+	switch claim := claim.(type) {
+	case string:
+		return strings.Fields(claim)
+	case []any:
+		result := make([]string, 0, len(claim))
+		for _, role := range claim {
+			if role, ok := role.(string); ok {
+				result = append(result, role)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func validateRoles(values []string) error {
+	if len(values) == 0 {
+		return fmt.Errorf("%w: at least one role is required", errs.ErrInvalidRole)
+	}
+	for _, role := range values {
+		if _, ok := roles[role]; !ok {
+			return fmt.Errorf("%w: %s", errs.ErrInvalidRole, role)
+		}
+	}
+	return nil
+}
+
+// Credential extracts the credential from an authorization header value, "Bearer <token>", falling back to the API
+// key header value
+func Credential(authorization string, apiKey string) string {
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return apiKey
+}
@@ -0,0 +1,564 @@
+package batch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Kinds of the batches
+const (
+	// AllowanceBatch changes allowances, its rows use the set, increase or reduce actions
+	AllowanceBatch = "allowance"
+	// TransferBatch sends payouts, its rows use the send action
+	TransferBatch = "transfer"
+)
+
+// Status of the rows in the results
+const (
+	// RowMined the row transaction was mined successfully
+	RowMined = "mined"
+	// RowFailed the row transaction reverted or could not be submitted
+	RowFailed = "failed"
+	// RowPending the row transaction was broadcast but its receipt was not read
+	RowPending = "pending"
+	// RowNotSubmitted the row was not submitted because a previous row failed
+	RowNotSubmitted = "not_submitted"
+)
+
+// defaultActions action of the rows without one
+var defaultActions = map[string]string{
+	AllowanceBatch: wallet.SetAction,
+	TransferBatch:  wallet.SendAction,
+}
+
+// actions actions allowed in each kind of batch
+var actions = map[string]map[string]struct{}{
+	AllowanceBatch: {wallet.SetAction: {}, wallet.IncreaseAction: {}, wallet.ReduceAction: {}},
+	TransferBatch:  {wallet.SendAction: {}},
+}
+
+// resultsHeader columns of the results file
+var resultsHeader = []string{"batch", "line", "address", "amount", "action", "memo", "status", "tx_hash", "block_number", "error"}
+
+// Row operation of a batch file, the amount is expressed in Ether
+type Row struct {
+	Line    int    `json:"line"`
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+	Action  string `json:"action"`
+	Memo    string `json:"memo,omitempty"`
+}
+
+// Result outcome of a row
+type Result struct {
+	Row
+	Status      string `json:"status"`
+	TxHash      string `json:"tx_hash,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// same reports whether the result belongs to the given row
+func (r Result) same(row Row) bool {
+	return r.Line == row.Line && r.Address == row.Address && r.Amount == row.Amount && r.Action == row.Action
+}
+
+// Batch rows read from a CSV file
+type Batch struct {
+	// ID identifies the run of the batch, the rows idempotency keys are derived from it. It is kept in the results,
+	// running the batch again with them resumes it
+	ID   string
+	Kind string
+	Rows []Row
+}
+
+// Read reads the rows of a CSV file with the address, amount, action and memo columns, the last two being optional.
+// A first row starting with address is read as the header. The addresses can be names of the address book. Every
+// invalid row is reported at once
+func Read(path string, kind string, book *contacts.Book) (*Batch, error) {
+	allowed, ok := actions[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown kind %s", errs.ErrInvalidBatch, kind)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidBatch, err)
+	}
+	defer file.Close()
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate batch id: %w", err)
+	}
+	batch := &Batch{ID: hex.EncodeToString(id), Kind: kind}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var invalid []error
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidBatch, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(batch.Rows) == 0 && len(invalid) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "address") {
+			continue
+		}
+
+		row, err := parseRow(record, line, kind, allowed, book)
+		if err != nil {
+			invalid = append(invalid, err)
+			continue
+		}
+		batch.Rows = append(batch.Rows, row)
+	}
+
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("%w:\n%w", errs.ErrInvalidBatch, errors.Join(invalid...))
+	}
+	if len(batch.Rows) == 0 {
+		return nil, fmt.Errorf("%w: no rows", errs.ErrInvalidBatch)
+	}
+	return batch, nil
+}
+
+// parseRow parses and validates a CSV record
+func parseRow(record []string, line int, kind string, allowed map[string]struct{}, book *contacts.Book) (Row, error) {
+	if len(record) < 2 || len(record) > 4 {
+		return Row{}, fmt.Errorf("line %d: expected address, amount, action and memo columns, got %d", line, len(record))
+	}
+
+	row := Row{Line: line, Action: defaultActions[kind]}
+	address, err := book.Resolve(strings.TrimSpace(record[0]))
+	if err != nil {
+		return Row{}, fmt.Errorf("line %d: %w", line, err)
+	}
+	if err := common.ValidateAddress(address); err != nil {
+		return Row{}, fmt.Errorf("line %d: %w", line, err)
+	}
+	row.Address = ethcommon.HexToAddress(address).Hex()
+
+	amount, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+	if err != nil || amount <= 0 {
+		return Row{}, fmt.Errorf("line %d: %w", line, errs.ErrInvalidAmountAction)
+	}
+	row.Amount = amount
+
+	if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+		row.Action = strings.ToLower(strings.TrimSpace(record[2]))
+	}
+	if _, ok := allowed[row.Action]; !ok {
+		return Row{}, fmt.Errorf("line %d: action %s is not allowed in %s batches", line, row.Action, kind)
+	}
+
+	if len(record) > 3 {
+		row.Memo = strings.TrimSpace(record[3])
+	}
+	return row, nil
+}
+
+// ReadResults reads a results file written by WriteResults and returns them with the id of the batch run, nil when
+// it does not exist
+func ReadResults(path string) ([]Result, string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open results: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read results: %w", err)
+	}
+
+	var (
+		results []Result
+		id      string
+	)
+	for i, record := range records {
+		if i == 0 || len(record) != len(resultsHeader) {
+			continue
+		}
+		id = record[0]
+		line, _ := strconv.Atoi(record[1])
+		amount, _ := strconv.ParseInt(record[3], 10, 64)
+		blockNumber, _ := strconv.ParseUint(record[8], 10, 64)
+		results = append(results, Result{
+			Row:         Row{Line: line, Address: record[2], Amount: amount, Action: record[4], Memo: record[5]},
+			Status:      record[6],
+			TxHash:      record[7],
+			BlockNumber: blockNumber,
+			Error:       record[9],
+		})
+	}
+	return results, id, nil
+}
+
+// WriteResults writes the results of the batch as CSV, replacing the file atomically
+func WriteResults(path string, batch *Batch, results []Result) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write(resultsHeader)
+	for _, result := range results {
+		blockNumber := ""
+		if result.BlockNumber > 0 {
+			blockNumber = strconv.FormatUint(result.BlockNumber, 10)
+		}
+		_ = writer.Write([]string{
+			batch.ID,
+			strconv.Itoa(result.Line),
+			result.Address,
+			strconv.FormatInt(result.Amount, 10),
+			result.Action,
+			result.Memo,
+			result.Status,
+			result.TxHash,
+			blockNumber,
+			result.Error,
+		})
+	}
+	writer.Flush()
+
+	if err := errors.Join(writer.Error(), file.Close()); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
+
+// Progress reports the result of a row once it is known, along with the results of all the rows
+type Progress func(result Result, results []Result)
+
+// Runner validates and submits the rows of the batches
+type Runner struct {
+	policy          *policy.Policy
+	store           *store.Store
+	allowance       *wallet.Allowance
+	balance         wallet.Balance
+	transfers       wallet.Transfers
+	contractAddress string
+}
+
+// New returns a new runner instance. The store is required, it records the rows idempotency keys resuming an
+// interrupted batch without submitting a row twice
+func New(st *store.Store, privateKey string, contractAddress string, pol *policy.Policy) (*Runner, error) {
+	if st == nil {
+		return nil, errs.ErrBatchStoreRequired
+	}
+
+	allowance := wallet.NewAllowanceRunner(privateKey, contractAddress)
+	allowance.SetStore(st)
+	allowance.SetPolicy(pol)
+
+	transfers := wallet.NewTransfersRunner(privateKey, contractAddress)
+	transfers.SetStore(st)
+	transfers.SetPolicy(pol)
+
+	return &Runner{
+		policy:          pol,
+		store:           st,
+		allowance:       allowance,
+		balance:         wallet.NewBalanceRunner(privateKey, contractAddress),
+		transfers:       transfers,
+		contractAddress: contractAddress,
+	}, nil
+}
+
+// Validate checks the rows left to submit against the spending policy and the on-chain state before any of them is
+// submitted: the payouts of each beneficiary must fit in its allowance and all of them in the contract balance, an
+// allowance reduction must not exceed the current allowance. Every invalid row is reported at once
+func (r *Runner) Validate(ctx context.Context, client *ethclient.Client, batch *Batch, previous []Result) error {
+	// the rows submitted by a previous run already changed the on-chain state, their idempotency keys resume them
+	rows := unsubmitted(batch.Rows, previous)
+
+	var invalid []error
+	for _, row := range rows {
+		if err := common.ValidateBeneficiary(row.Address, r.contractAddress); err != nil {
+			invalid = append(invalid, fmt.Errorf("line %d: %w", row.Line, err))
+		}
+	}
+
+	switch batch.Kind {
+	case TransferBatch:
+		invalid = append(invalid, r.validateTransfers(ctx, client, rows)...)
+	case AllowanceBatch:
+		invalid = append(invalid, r.validateAllowances(ctx, client, rows)...)
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("%w:\n%w", errs.ErrInvalidBatch, errors.Join(invalid...))
+	}
+	return nil
+}
+
+func (r *Runner) validateTransfers(ctx context.Context, client *ethclient.Client, rows []Row) []error {
+	balance, err := r.balance.GetContractBalance(ctx, client)
+	if err != nil {
+		return []error{err}
+	}
+
+	allowances, err := r.allowance.GetAllowances(ctx, client, addresses(rows, ""))
+	if err != nil {
+		return []error{err}
+	}
+
+	var (
+		invalid []error
+		total   int64
+		// the caps account for the previous rows, none of them is in the outbox yet
+		pending policy.Payouts
+	)
+	for _, row := range rows {
+		if err := r.checkPolicy(ctx, "send_money", row, row.Amount, &pending); err != nil {
+			invalid = append(invalid, err)
+		}
+		pending.Add(row.Address, row.Amount)
+
+		remaining := allowances[row.Address]
+		if row.Amount > remaining {
+			invalid = append(invalid, fmt.Errorf("line %d: %d ether exceeds the %d ether left in the allowance of %s",
+				row.Line, row.Amount, remaining, row.Address))
+		}
+		allowances[row.Address] = remaining - row.Amount
+		total += row.Amount
+	}
+
+	if total > balance {
+		invalid = append(invalid, fmt.Errorf("the payouts total %d ether, the contract balance is %d ether", total, balance))
+	}
+	return invalid
+}
+
+func (r *Runner) validateAllowances(ctx context.Context, client *ethclient.Client, rows []Row) []error {
+	// the increases are checked against the allowance they result in
+	allowances, err := r.allowance.GetAllowances(ctx, client, addresses(rows, ""))
+	if err != nil {
+		return []error{err}
+	}
+
+	var invalid []error
+	seen := make(map[string]int)
+	for _, row := range rows {
+		if line, ok := seen[row.Address]; ok {
+			invalid = append(invalid, fmt.Errorf("line %d: %s is already changed on line %d, the changes of an address must be in separate batches",
+				row.Line, row.Address, line))
+			continue
+		}
+		seen[row.Address] = row.Line
+
+		switch row.Action {
+		case wallet.SetAction:
+			invalid = appendError(invalid, r.checkPolicy(ctx, "set_allowance", row, row.Amount, nil))
+		case wallet.IncreaseAction:
+			invalid = appendError(invalid, r.checkPolicy(ctx, "increase_allowance", row, allowances[row.Address]+row.Amount, nil))
+		case wallet.ReduceAction:
+			if current := allowances[row.Address]; row.Amount > current {
+				invalid = append(invalid, fmt.Errorf("line %d: reduction of %d ether exceeds the %d ether allowance of %s",
+					row.Line, row.Amount, current, row.Address))
+			}
+		}
+	}
+	return invalid
+}
+
+// addresses returns the distinct addresses of the rows, only of the rows with the given action when not empty
+func addresses(rows []Row, action string) []string {
+	var list []string
+	seen := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		if _, ok := seen[row.Address]; ok || (action != "" && row.Action != action) {
+			continue
+		}
+		seen[row.Address] = struct{}{}
+		list = append(list, row.Address)
+	}
+	return list
+}
+
+// checkPolicy checks a row against the spending policy, the operations requiring approval cannot be batched. The
+// amount is the payout, or the allowance resulting from the allowance change. The caps of the payouts account for the
+// pending ones
+func (r *Runner) checkPolicy(ctx context.Context, operation string, row Row, amount int64, pending *policy.Payouts) error {
+	if r.policy == nil {
+		return nil
+	}
+
+	var err error
+	if operation == "send_money" {
+		err = r.policy.CheckPayout(ctx, r.store, pending, row.Address, row.Amount)
+	} else {
+		err = r.policy.CheckAllowance(ctx, row.Address, amount)
+	}
+	if err == nil && r.policy.RequiresApproval(operation, amount) {
+		err = fmt.Errorf("%w: %s of %d ether to %s", errs.ErrApprovalRequired, operation, row.Amount, row.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("line %d: %w", row.Line, err)
+	}
+	return nil
+}
+
+// Run submits the rows not mined in the previous results one after the other with consecutive nonces, without
+// waiting for each one to be mined, then waits for their receipts. Each row uses an idempotency key derived from the
+// batch id and its line, so running an interrupted batch again never submits a row twice. The first row failing to
+// be submitted stops the submission, the following rows are left to a later run
+func (r *Runner) Run(ctx context.Context, client *ethclient.Client, batch *Batch, previous []Result, progress Progress) ([]Result, error) {
+	results := make([]Result, len(batch.Rows))
+	var todo []int
+	for i, row := range batch.Rows {
+		results[i] = Result{Row: row, Status: RowNotSubmitted}
+		if result, ok := previousResult(row, previous); ok && result.Status == RowMined {
+			results[i] = Result{Row: row, Status: RowMined, TxHash: result.TxHash, BlockNumber: result.BlockNumber}
+			continue
+		}
+		todo = append(todo, i)
+	}
+	if len(todo) == 0 {
+		return results, nil
+	}
+
+	if err := r.pipeline(ctx, client, batch.Kind); err != nil {
+		return results, err
+	}
+
+	var (
+		submitted []int
+		pendings  = make(map[int]*wallet.PendingTransaction)
+		stopErr   error
+	)
+	for _, i := range todo {
+		row := batch.Rows[i]
+		ctxRow := wallet.WithIdempotencyKey(ctx, fmt.Sprintf("batch:%s:%d", batch.ID, row.Line))
+
+		var (
+			pending *wallet.PendingTransaction
+			err     error
+		)
+		if batch.Kind == TransferBatch {
+			pending, err = r.transfers.SubmitSend(ctxRow, client, row.Address, row.Amount)
+		} else {
+			pending, err = r.allowance.SubmitChangeAllowance(ctxRow, client, row.Action, row.Address, row.Amount)
+		}
+		if err != nil {
+			results[i].Status, results[i].Error = RowFailed, err.Error()
+			if pending != nil && pending.TxHash != "" {
+				// the transaction of a previous run reverted, it did not leave a nonce gap
+				results[i].TxHash = pending.TxHash
+				progress(results[i], results)
+				continue
+			}
+			progress(results[i], results)
+			stopErr = fmt.Errorf("line %d: %w", row.Line, err)
+			break
+		}
+
+		results[i].Status, results[i].TxHash = RowPending, pending.TxHash
+		pendings[i] = pending
+		submitted = append(submitted, i)
+		progress(results[i], results)
+	}
+
+	for _, i := range submitted {
+		var (
+			result *wallet.TransactionResult
+			err    error
+		)
+		if batch.Kind == TransferBatch {
+			result, err = r.transfers.Wait(ctx, client, pendings[i])
+		} else {
+			result, err = r.allowance.Wait(ctx, client, pendings[i])
+		}
+
+		switch {
+		case err != nil && result == nil:
+			// the receipt was not read, the transaction may still be mined
+			results[i].Error = err.Error()
+		case err != nil || result.Status == 0:
+			results[i].Status, results[i].BlockNumber = RowFailed, result.BlockNumber
+			results[i].Error = errs.ErrTransactionFailed.Error()
+		default:
+			results[i].Status, results[i].BlockNumber = RowMined, result.BlockNumber
+		}
+		progress(results[i], results)
+	}
+
+	if stopErr != nil {
+		return results, stopErr
+	}
+	for _, result := range results {
+		if result.Status != RowMined {
+			return results, errs.ErrBatchIncomplete
+		}
+	}
+	return results, nil
+}
+
+// pipeline makes the runner of the batch sign its transactions with consecutive nonces
+func (r *Runner) pipeline(ctx context.Context, client *ethclient.Client, kind string) error {
+	if kind == TransferBatch {
+		return r.transfers.Pipeline(ctx, client)
+	}
+	return r.allowance.Pipeline(ctx, client)
+}
+
+// unsubmitted returns the rows without a transaction in the previous results
+func unsubmitted(rows []Row, previous []Result) []Row {
+	var result []Row
+	for _, row := range rows {
+		if p, ok := previousResult(row, previous); !ok || p.TxHash == "" {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// previousResult returns the previous result of the row
+func previousResult(row Row, previous []Result) (Result, bool) {
+	for _, result := range previous {
+		if result.same(row) {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+func appendError(invalid []error, err error) []error {
+	if err != nil {
+		return append(invalid, err)
+	}
+	return invalid
+}
@@ -0,0 +1,329 @@
+package budget
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"github.com/spf13/viper"
+	"os"
+	"time"
+)
+
+// Kinds of the allowance changes
+const (
+	// CreateChange grants an allowance to an address without one
+	CreateChange = "create"
+	// IncreaseChange increases the current allowance
+	IncreaseChange = "increase"
+	// ReduceChange reduces the current allowance
+	ReduceChange = "reduce"
+	// ZeroChange removes the current allowance
+	ZeroChange = "zero"
+	// UnchangedChange the current allowance is the desired one
+	UnchangedChange = "unchanged"
+)
+
+// Budget desired allowance of an address, expressed in Ether
+type Budget struct {
+	Address   string `mapstructure:"address"`
+	Name      string `mapstructure:"name"`
+	Allowance int64  `mapstructure:"allowance"`
+}
+
+// Change allowance change of an address, the amounts are expressed in Ether
+type Change struct {
+	Address string `json:"address"`
+	Name    string `json:"name,omitempty"`
+	Kind    string `json:"kind"`
+	Current int64  `json:"current"`
+	Desired int64  `json:"desired"`
+}
+
+// Delta returns the difference between the desired and the current allowance
+func (c Change) Delta() int64 {
+	return c.Desired - c.Current
+}
+
+// action returns the allowance action and amount applying the change
+func (c Change) action() (string, int64) {
+	switch c.Kind {
+	case CreateChange:
+		return wallet.SetAction, c.Desired
+	case IncreaseChange:
+		return wallet.IncreaseAction, c.Delta()
+	default:
+		return wallet.ReduceAction, -c.Delta()
+	}
+}
+
+// Plan changes bringing the on-chain allowances to the desired ones
+type Plan struct {
+	ID              string    `json:"id"`
+	Contract        string    `json:"contract"`
+	Source          string    `json:"source"`
+	CreatedAt       time.Time `json:"created_at"`
+	ContractBalance int64     `json:"contract_balance"`
+	Changes         []Change  `json:"changes"`
+}
+
+// TotalDelta returns the sum of the allowance changes
+func (p *Plan) TotalDelta() int64 {
+	var total int64
+	for _, change := range p.Changes {
+		total += change.Delta()
+	}
+	return total
+}
+
+// TotalAllowance returns the sum of the desired allowances
+func (p *Plan) TotalAllowance() int64 {
+	var total int64
+	for _, change := range p.Changes {
+		total += change.Desired
+	}
+	return total
+}
+
+// Count returns the number of changes of each kind
+func (p *Plan) Count() map[string]int {
+	count := make(map[string]int)
+	for _, change := range p.Changes {
+		count[change.Kind]++
+	}
+	return count
+}
+
+// fingerprint returns the hash identifying the plan, its contract and changes. The creation time keeps two plans with
+// the same changes from sharing their idempotency keys
+func (p *Plan) fingerprint() string {
+	data, _ := json.Marshal(struct {
+		Contract  string    `json:"contract"`
+		CreatedAt time.Time `json:"created_at"`
+		Changes   []Change  `json:"changes"`
+	}{p.Contract, p.CreatedAt, p.Changes})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Progress reports a change once it is applied, the result is nil when it was already applied
+type Progress func(index int, change Change, result *wallet.TransactionResult)
+
+// Planner plans and applies the allowance changes of the budgets
+type Planner struct {
+	store           *store.Store
+	allowance       *wallet.Allowance
+	balance         wallet.Balance
+	contractAddress string
+}
+
+// NewPlanner returns a new planner instance, the private key signs the changes. The store records the idempotency
+// keys resuming an interrupted apply, it is only required to apply a plan
+func NewPlanner(st *store.Store, privateKey string, contractAddress string, pol *policy.Policy) *Planner {
+	allowance := wallet.NewAllowanceRunner(privateKey, contractAddress)
+	allowance.SetPolicy(pol)
+	if st != nil {
+		allowance.SetStore(st)
+	}
+
+	return &Planner{
+		store:           st,
+		allowance:       allowance,
+		balance:         wallet.NewBalanceRunner(privateKey, contractAddress),
+		contractAddress: ethcommon.HexToAddress(contractAddress).Hex(),
+	}
+}
+
+// Load reads the desired allowances from a YAML, JSON or TOML file listing them under allowances, the addresses can be
+// names of the address book
+func Load(path string, book *contacts.Book) ([]Budget, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidBudget, err)
+	}
+
+	var file struct {
+		Allowances []Budget `mapstructure:"allowances"`
+	}
+	if err := v.Unmarshal(&file); err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidBudget, err)
+	}
+
+	seen := make(map[ethcommon.Address]struct{}, len(file.Allowances))
+	for i, budget := range file.Allowances {
+		resolved, err := book.Resolve(budget.Address)
+		if err != nil {
+			return nil, fmt.Errorf("%w: allowance %d: %w", errs.ErrInvalidBudget, i+1, err)
+		}
+		budget.Address = resolved
+		file.Allowances[i].Address = resolved
+
+		if err := common.ValidateAddress(budget.Address); err != nil {
+			return nil, fmt.Errorf("%w: allowance %d: %w", errs.ErrInvalidBudget, i+1, err)
+		}
+		if budget.Allowance < 0 {
+			return nil, fmt.Errorf("%w: allowance of %s is negative", errs.ErrInvalidBudget, budget.Address)
+		}
+
+		address := ethcommon.HexToAddress(budget.Address)
+		if _, ok := seen[address]; ok {
+			return nil, fmt.Errorf("%w: %s is listed more than once", errs.ErrInvalidBudget, budget.Address)
+		}
+		seen[address] = struct{}{}
+	}
+	return file.Allowances, nil
+}
+
+// Plan reads the current allowances and the contract balance and returns the changes bringing the allowances to the
+// budgets
+func (p *Planner) Plan(ctx context.Context, client *ethclient.Client, source string, budgets []Budget) (*Plan, error) {
+	balance, err := p.balance.GetContractBalance(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Contract:        p.contractAddress,
+		Source:          source,
+		CreatedAt:       time.Now().UTC().Truncate(time.Second),
+		ContractBalance: balance,
+		Changes:         make([]Change, 0, len(budgets)),
+	}
+
+	addresses := make([]string, 0, len(budgets))
+	for _, budget := range budgets {
+		if err := common.ValidateBeneficiary(budget.Address, p.contractAddress); err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidBudget, err)
+		}
+		addresses = append(addresses, ethcommon.HexToAddress(budget.Address).Hex())
+	}
+	allowances, err := p.allowance.GetAllowances(ctx, client, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, budget := range budgets {
+		address := addresses[i]
+		change := Change{Address: address, Name: budget.Name, Current: allowances[address], Desired: budget.Allowance}
+		switch {
+		case change.Desired == change.Current:
+			change.Kind = UnchangedChange
+		case change.Current == 0:
+			change.Kind = CreateChange
+		case change.Desired == 0:
+			change.Kind = ZeroChange
+		case change.Desired > change.Current:
+			change.Kind = IncreaseChange
+		default:
+			change.Kind = ReduceChange
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	plan.ID = plan.fingerprint()
+	return plan, nil
+}
+
+// Save writes the plan as JSON, for apply to execute exactly the reviewed changes
+func Save(plan *Plan, path string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// Open reads a plan written by Save, rejecting it when it was modified
+func Open(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidPlan, err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidPlan, err)
+	}
+	if plan.ID != plan.fingerprint() {
+		return nil, fmt.Errorf("%w: the changes do not match the plan id %s", errs.ErrInvalidPlan, plan.ID)
+	}
+	return &plan, nil
+}
+
+// Apply signs the changes of the plan in order. Each change is skipped once the allowance has its desired value and
+// uses an idempotency key derived from the plan id, so applying an interrupted plan again resumes it without changing
+// an allowance twice. A reverted or dropped change releases its key and is signed again by the next apply. A change
+// whose allowance moved since the plan was made stops the apply
+func (p *Planner) Apply(ctx context.Context, client *ethclient.Client, plan *Plan, progress Progress) error {
+	if p.store == nil {
+		return errs.ErrPlanStoreRequired
+	}
+	if plan.Contract != p.contractAddress {
+		return fmt.Errorf("%w: the plan was made for contract %s", errs.ErrInvalidPlan, plan.Contract)
+	}
+
+	for i, change := range plan.Changes {
+		if change.Kind == UnchangedChange {
+			continue
+		}
+
+		current, err := p.allowance.GetAllowance(ctx, client, change.Address)
+		if err != nil {
+			return err
+		}
+		if current == change.Desired {
+			progress(i, change, nil)
+			continue
+		}
+
+		if current != change.Current {
+			// a change interrupted before it was mined still has the planned allowance, any other value is a drift
+			return fmt.Errorf("%w: allowance of %s is %d, the plan expected %d, please plan again",
+				errs.ErrPlanDrift, change.Address, current, change.Current)
+		}
+
+		action, amount := change.action()
+		ctxChange := wallet.WithIdempotencyKey(ctx, "allowance-plan:"+plan.ID+":"+change.Address)
+		result, err := p.allowance.ChangeAllowance(ctxChange, client, action, change.Address, amount)
+		if err != nil {
+			return fmt.Errorf("failed to %s allowance of %s: %w", change.Kind, change.Address, err)
+		}
+		progress(i, change, result)
+	}
+	return nil
+}
+
+// Verify reads the allowances of the plan and returns the changes whose allowance is not the desired one, with the
+// current value read
+func (p *Planner) Verify(ctx context.Context, client *ethclient.Client, plan *Plan) ([]Change, error) {
+	addresses := make([]string, 0, len(plan.Changes))
+	for _, change := range plan.Changes {
+		addresses = append(addresses, change.Address)
+	}
+	allowances, err := p.allowance.GetAllowances(ctx, client, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []Change
+	for _, change := range plan.Changes {
+		if current := allowances[change.Address]; current != change.Desired {
+			change.Current = current
+			mismatches = append(mismatches, change)
+		}
+	}
+	return mismatches, nil
+}
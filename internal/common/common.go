@@ -4,21 +4,33 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/maxipaz/wallet/config"
 	contracts "github.com/maxipaz/wallet/contracts/interfaces"
 	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/metrics"
 	"math/big"
+	"os/user"
 	"regexp"
+	"strings"
+	"time"
 )
 
 // GetSigner get the signer for sign transactions
 func GetSigner(ctx context.Context, client *ethclient.Client) (*bind.TransactOpts, error) {
-	privateKey, err := crypto.HexToECDSA(config.App.Blockchain.PrivateKey)
+	defer metrics.ObserveRPC("get_signer", time.Now())
+
+	key, err := config.PrivateKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := crypto.HexToECDSA(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert hex to ECDSA: %w", err)
 	}
@@ -39,25 +51,47 @@ func GetSigner(ctx context.Context, client *ethclient.Client) (*bind.TransactOpt
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chain ID: %w", err)
 	}
+	if expected := config.App.Blockchain.ChainID; expected != 0 && chainID.Uint64() != expected {
+		return nil, fmt.Errorf("%w: the node reports chain %d, the configuration expects chain %d", errs.ErrChainIDMismatch, chainID, expected)
+	}
 
 	signer, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create signer: %w", err)
 	}
 
-	gasPrice, err := client.SuggestGasPrice(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get suggested gas price: %w", err)
-	}
-
 	signer.Nonce = big.NewInt(int64(nonce))
 	signer.Value = big.NewInt(config.App.Contract.DefaultWeiFounds)
 	signer.GasLimit = 0 // automatically estimates gas limit
-	signer.GasPrice = gasPrice
+
+	fees := config.App.Blockchain.Fees
+	switch {
+	case fees.MaxFeePerGas > 0 || fees.MaxPriorityFeePerGas > 0:
+		// the missing fee is computed by the binding from the node suggestion and the base fee
+		signer.GasFeeCap = GweiToWei(fees.MaxFeePerGas)
+		signer.GasTipCap = GweiToWei(fees.MaxPriorityFeePerGas)
+	case fees.GasPrice > 0:
+		signer.GasPrice = GweiToWei(fees.GasPrice)
+	default:
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get suggested gas price: %w", err)
+		}
+		signer.GasPrice = gasPrice
+	}
 
 	return signer, nil
 }
 
+// GweiToWei convert Gwei to Wei, nil for the zero value
+func GweiToWei(gwei float64) *big.Int {
+	if gwei <= 0 {
+		return nil
+	}
+	wei, _ := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(params.GWei)).Int(nil)
+	return wei
+}
+
 // GetContract get an instance of the deployed contract
 func GetContract(ctx context.Context, client *ethclient.Client, contractAddress string) (*contracts.Contract, error) {
 	err := ValidateContractAddress(ctx, client, contractAddress)
@@ -78,7 +112,9 @@ func ValidateContractAddress(ctx context.Context, client *ethclient.Client, addr
 	}
 
 	contractAddress := common.HexToAddress(address)
+	start := time.Now()
 	bytecode, err := client.CodeAt(ctx, contractAddress, nil)
+	metrics.ObserveRPC("code_at", start)
 	if err != nil {
 		return err
 	}
@@ -90,15 +126,59 @@ func ValidateContractAddress(ctx context.Context, client *ethclient.Client, addr
 	return nil
 }
 
-// ValidateAddress validate address format
+// addressRegex hexadecimal address format
+var addressRegex = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
+
+// burnAddresses addresses without known private key, the funds sent to them are lost
+var burnAddresses = map[common.Address]string{
+	{}: "the zero address",
+	common.HexToAddress("0x000000000000000000000000000000000000dEaD"): "a burn address",
+	common.HexToAddress("0xdEAD000000000000000042069420694206942069"): "a burn address",
+}
+
+// ValidateAddress validate address format. The EIP-55 checksum is checked when the address is mixed case, the lower
+// and upper case addresses carry no checksum
 func ValidateAddress(address string) error {
-	regex := regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
-	if ok := regex.MatchString(address); !ok {
+	if ok := addressRegex.MatchString(address); !ok {
 		return errs.ErrInvalidAddress
 	}
+
+	digits := address[2:]
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && common.HexToAddress(address).Hex() != address {
+		return errs.ErrInvalidChecksum
+	}
 	return nil
 }
 
+// ValidateBeneficiary validate an address receiving allowances, payouts or the ownership. The zero address, the burn
+// addresses and the contract itself are rejected
+func ValidateBeneficiary(address string, contractAddress string) error {
+	if err := ValidateAddress(address); err != nil {
+		return err
+	}
+
+	target := common.HexToAddress(address)
+	if reason, ok := burnAddresses[target]; ok {
+		return fmt.Errorf("%w: %s is %s", errs.ErrForbiddenBeneficiary, target.Hex(), reason)
+	}
+	if target == common.HexToAddress(contractAddress) {
+		return fmt.Errorf("%w: %s is the contract itself", errs.ErrForbiddenBeneficiary, target.Hex())
+	}
+	return nil
+}
+
+// HasCode reports whether a contract is deployed at the address. The contract recipients of a payout may revert, the
+// contract sends the funds with transfer() which only forwards 2300 gas
+func HasCode(ctx context.Context, client *ethclient.Client, address string) (bool, error) {
+	defer metrics.ObserveRPC("code_at", time.Now())
+
+	code, err := client.CodeAt(ctx, common.HexToAddress(address), nil)
+	if err != nil {
+		return false, err
+	}
+	return len(code) > 0, nil
+}
+
 // EtherToWei convert Ether to Wei
 func EtherToWei(eth *big.Int) *big.Int {
 	return new(big.Int).Mul(eth, big.NewInt(params.Ether))
@@ -108,3 +188,50 @@ func EtherToWei(eth *big.Int) *big.Int {
 func WeiToEther(wei *big.Int) *big.Int {
 	return new(big.Int).Div(wei, big.NewInt(params.Ether))
 }
+
+// FormatEther format a Wei amount as a decimal Ether string
+func FormatEther(wei *big.Int) string {
+	if wei == nil {
+		return "0"
+	}
+	value := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.Ether))
+	return value.Text('f', -1)
+}
+
+// CurrentUser returns the name of the operating system user running the command
+func CurrentUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return "unknown"
+}
+
+// SignMessage signs the message with the Ethereum personal message prefix, as personal_sign does
+func SignMessage(message string, key *ecdsa.PrivateKey) (string, error) {
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign message: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(signature), nil
+}
+
+// VerifyMessage checks the personal_sign signature of the message was produced by the given address
+func VerifyMessage(message string, signature string, address common.Address) error {
+	sig, err := hexutil.Decode(strings.TrimSpace(signature))
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return fmt.Errorf("%w: malformed signature", errs.ErrInvalidSignature)
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidSignature, err)
+	}
+	if signer := crypto.PubkeyToAddress(*publicKey); signer != address {
+		return fmt.Errorf("%w: signed by %s instead of %s", errs.ErrInvalidSignature, signer.Hex(), address.Hex())
+	}
+	return nil
+}
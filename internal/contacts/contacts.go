@@ -0,0 +1,239 @@
+package contacts
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/spf13/pflag"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// AddressAnnotation flag annotation of the flags accepting addresses, their contact names and tags are resolved to
+// addresses before the commands run and completed by the shell completion
+const AddressAnnotation = "wallet_address"
+
+// nameRegex valid contact name or tag, it cannot be confused with an address
+var nameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]*$`)
+
+// Lookup resolves the names which are not in the address book, i.e.: ENS names
+type Lookup interface {
+	// IsName reports whether the value is a name of the lookup
+	IsName(value string) bool
+	// Resolve returns the address of a name
+	Resolve(name string) (string, error)
+	// Name returns the name of an address, empty when it has none
+	Name(address string) string
+}
+
+// Book address book, mapping the contact names and tags to addresses
+type Book struct {
+	contacts []store.Contact
+	names    map[string]string
+	tags     map[string][]string
+	labels   map[string]string
+	lookup   Lookup
+}
+
+// defaultBook address book of the command being run, empty until set
+var defaultBook = NewBook(nil)
+
+// NewBook returns an address book with the given contacts
+func NewBook(contacts []store.Contact) *Book {
+	b := &Book{
+		contacts: contacts,
+		names:    make(map[string]string, len(contacts)),
+		tags:     make(map[string][]string),
+		labels:   make(map[string]string, len(contacts)),
+	}
+	for _, contact := range contacts {
+		b.names[strings.ToLower(contact.Name)] = contact.Address
+		b.labels[strings.ToLower(contact.Address)] = contact.Name
+		for _, tag := range contact.Tags {
+			key := strings.ToLower(tag)
+			b.tags[key] = append(b.tags[key], contact.Address)
+		}
+	}
+	return b
+}
+
+// Load reads the address book of the store, it is empty when the store is not configured
+func Load(ctx context.Context, path string) (*Book, error) {
+	if path == "" {
+		return NewBook(nil), nil
+	}
+
+	st, err := store.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	contacts, err := st.ListContacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewBook(contacts), nil
+}
+
+// SetLookup sets the lookup of the names and addresses which are not in the address book
+func (b *Book) SetLookup(lookup Lookup) {
+	b.lookup = lookup
+}
+
+// ValidateName checks a contact name or tag
+func ValidateName(name string) error {
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("%w: %q, names and tags start with a letter followed by letters, digits, '_', '.' or '-'", errs.ErrInvalidContact, name)
+	}
+	return nil
+}
+
+// Contacts returns the contacts of the book ordered by name
+func (b *Book) Contacts() []store.Contact {
+	return b.contacts
+}
+
+// Resolve returns the address of a value accepted anywhere an address is: an address, a contact name or a tag naming a
+// single contact, or a name of the lookup. The names and tags are compared without case
+func (b *Book) Resolve(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	if strings.HasPrefix(value, "0x") {
+		return value, common.ValidateAddress(value)
+	}
+	if address, ok := b.names[strings.ToLower(value)]; ok {
+		return address, nil
+	}
+	if _, ok := b.tags[strings.ToLower(value)]; !ok && b.lookup != nil && b.lookup.IsName(value) {
+		return b.lookup.Resolve(value)
+	}
+
+	switch addresses := b.tags[strings.ToLower(value)]; len(addresses) {
+	case 0:
+		if !nameRegex.MatchString(value) {
+			return "", fmt.Errorf("%w: %s", errs.ErrInvalidAddress, value)
+		}
+		return "", fmt.Errorf("%w: %s, please add it with contacts add", errs.ErrUnknownContact, value)
+	case 1:
+		return addresses[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s", errs.ErrAmbiguousContact, value)
+	}
+}
+
+// ResolveAll resolves the values of a list of addresses, the tags are expanded to the addresses of their contacts
+func (b *Book) ResolveAll(values []string) ([]string, error) {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if addresses := b.tags[strings.ToLower(value)]; len(addresses) > 1 {
+			if _, ok := b.names[strings.ToLower(value)]; !ok {
+				result = append(result, addresses...)
+				continue
+			}
+		}
+
+		address, err := b.Resolve(value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, address)
+	}
+	return result, nil
+}
+
+// Label returns the contact name of an address, or its name from the lookup, empty when unknown
+func (b *Book) Label(address string) string {
+	if label, ok := b.labels[strings.ToLower(address)]; ok || b.lookup == nil || common.ValidateAddress(address) != nil {
+		return label
+	}
+	return b.lookup.Name(address)
+}
+
+// Display returns the address followed by its contact name, if any
+func (b *Book) Display(address string) string {
+	return Labelled(address, b.Label(address))
+}
+
+// Labelled returns the address followed by a label already looked up, if any
+func Labelled(address string, label string) string {
+	if label != "" {
+		return fmt.Sprintf("%s (%s)", address, label)
+	}
+	return address
+}
+
+// Completions returns the contact names and tags, completing the address flags
+func (b *Book) Completions() []string {
+	result := make([]string, 0, len(b.names)+len(b.tags))
+	seen := make(map[string]struct{})
+	for _, contact := range b.contacts {
+		result = append(result, contact.Name)
+		seen[strings.ToLower(contact.Name)] = struct{}{}
+	}
+	for _, contact := range b.contacts {
+		for _, tag := range contact.Tags {
+			if _, ok := seen[strings.ToLower(tag)]; !ok {
+				result = append(result, tag)
+				seen[strings.ToLower(tag)] = struct{}{}
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// SetDefault sets the address book used by the package functions
+func SetDefault(b *Book) {
+	defaultBook = b
+}
+
+// Default returns the address book of the command being run
+func Default() *Book {
+	return defaultBook
+}
+
+// Label returns the contact name of an address in the default address book
+func Label(address string) string {
+	return defaultBook.Label(address)
+}
+
+// Display returns the address followed by its contact name in the default address book
+func Display(address string) string {
+	return defaultBook.Display(address)
+}
+
+// MarkAddressFlag marks a flag as accepting addresses, contact names and tags
+func MarkAddressFlag(flags *pflag.FlagSet, name string) {
+	_ = flags.SetAnnotation(name, AddressAnnotation, []string{"true"})
+}
+
+// ResolveFlags replaces the contact names and tags of the changed address flags by their addresses
+func (b *Book) ResolveFlags(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if _, ok := flag.Annotations[AddressAnnotation]; !ok || !flag.Changed || err != nil {
+			return
+		}
+
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			var addresses []string
+			if addresses, err = b.ResolveAll(slice.GetSlice()); err == nil {
+				err = slice.Replace(addresses)
+			}
+		} else {
+			var address string
+			if address, err = b.Resolve(flag.Value.String()); err == nil {
+				err = flag.Value.Set(address)
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("flag %s: %w", flag.Name, err)
+		}
+	})
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	contracts "github.com/maxipaz/wallet/contracts/interfaces"
 	"github.com/maxipaz/wallet/internal/common"
@@ -16,6 +17,9 @@ type Deployer struct {
 	address     ethcommon.Address
 	transaction *types.Transaction
 	contract    *contracts.Contract
+	from        ethcommon.Address
+	chainID     uint64
+	blockNumber uint64
 }
 
 // NewDeployer returns a new runner instance
@@ -23,7 +27,7 @@ func NewDeployer() *Deployer {
 	return new(Deployer)
 }
 
-// Deploy deploys a new Ethereum contract
+// Deploy signs and broadcasts the deployment of a new Ethereum contract, see Wait
 func (d *Deployer) Deploy(ctx context.Context, client *ethclient.Client) error {
 	signer, err := common.GetSigner(ctx, client)
 	if err != nil {
@@ -35,14 +39,28 @@ func (d *Deployer) Deploy(ctx context.Context, client *ethclient.Client) error {
 		return fmt.Errorf("failed to deploy contract: %w", err)
 	}
 
-	slog.DebugContext(ctx, "waiting for contract to be deployed...", slog.String("address", address.Hex()))
-	if _, err := bind.WaitDeployed(ctx, client, tx); err != nil {
-		return fmt.Errorf("failed to wait deployed: %w", err)
-	}
-
 	d.address = address
 	d.transaction = tx
 	d.contract = contract
+	d.from = signer.From
+	d.chainID = tx.ChainId().Uint64()
+
+	return nil
+}
+
+// Wait waits for the deployment broadcast by Deploy to be mined. On failure the contract address and the transaction
+// are still known, the deployment may be mined later
+func (d *Deployer) Wait(ctx context.Context, client *ethclient.Client) error {
+	slog.DebugContext(ctx, "waiting for contract to be deployed...", slog.String("address", d.address.Hex()))
+	if _, err := bind.WaitDeployed(ctx, client, d.transaction); err != nil {
+		return fmt.Errorf("failed to wait deployed: %w", err)
+	}
+
+	receipt, err := client.TransactionReceipt(ctx, d.transaction.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get deployment receipt: %w", err)
+	}
+	d.blockNumber = receipt.BlockNumber.Uint64()
 
 	return nil
 }
@@ -51,3 +69,28 @@ func (d *Deployer) Deploy(ctx context.Context, client *ethclient.Client) error {
 func (d *Deployer) ContractAddress() string {
 	return d.address.Hex()
 }
+
+// TxHash returns the hash of the deployment transaction
+func (d *Deployer) TxHash() string {
+	return d.transaction.Hash().Hex()
+}
+
+// BlockNumber returns the block including the deployment
+func (d *Deployer) BlockNumber() uint64 {
+	return d.blockNumber
+}
+
+// From returns the address of the deployer account
+func (d *Deployer) From() string {
+	return d.from.Hex()
+}
+
+// ChainID returns the chain ID of the network the contract was deployed on
+func (d *Deployer) ChainID() uint64 {
+	return d.chainID
+}
+
+// BytecodeHash returns the keccak256 hash of the deployed creation bytecode, contracts/bin/SharedWallet.bin
+func BytecodeHash() string {
+	return crypto.Keccak256Hash(ethcommon.FromHex(contracts.ContractBin)).Hex()
+}
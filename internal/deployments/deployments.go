@@ -0,0 +1,89 @@
+package deployments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Deployment deployment of the contract recorded in the deployments file
+type Deployment struct {
+	// Network name of the network profile selected when deploying, empty without profile
+	Network     string `json:"network"`
+	ChainID     uint64 `json:"chain_id"`
+	Address     string `json:"address"`
+	TxHash      string `json:"tx_hash"`
+	BlockNumber uint64 `json:"block_number"`
+	Deployer    string `json:"deployer"`
+	// BytecodeHash keccak256 hash of the creation bytecode, the content of contracts/bin/SharedWallet.bin
+	BytecodeHash string    `json:"bytecode_hash"`
+	DeployedAt   time.Time `json:"deployed_at"`
+}
+
+// Load returns the deployments recorded in the file, oldest first. A missing file has no deployments
+func Load(path string) ([]Deployment, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployments: %w", err)
+	}
+
+	var list []Deployment
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errs.ErrInvalidDeployments, path, err)
+	}
+	return list, nil
+}
+
+// Append records a deployment at the end of the file, the file is replaced atomically
+func Append(path string, deployment Deployment) error {
+	list, err := Load(path)
+	if err != nil {
+		return err
+	}
+	list = append(list, deployment)
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to record deployment: %w", err)
+	}
+	return nil
+}
+
+// Find returns the latest deployment of a contract address or of a transaction hash
+func Find(list []Deployment, ref string) (*Deployment, error) {
+	for i := len(list) - 1; i >= 0; i-- {
+		if strings.EqualFold(list[i].Address, ref) || strings.EqualFold(list[i].TxHash, ref) {
+			return &list[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errs.ErrUnknownDeployment, ref)
+}
@@ -0,0 +1,312 @@
+package ens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"github.com/maxipaz/wallet/internal/store"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MainnetRegistry address of the ENS registry on mainnet and on the main testnets
+const MainnetRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+// reverseSuffix parent name of the reverse records
+const reverseSuffix = "addr.reverse"
+
+// failureTTL time during which a failed reverse lookup is not retried, the labels of the addresses are then empty
+const failureTTL = time.Minute
+
+// contractsABI functions of the registry and the resolvers used by the lookups
+const contractsABI = `[
+	{"type":"function","name":"resolver","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]},
+	{"type":"function","name":"addr","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]},
+	{"type":"function","name":"name","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"string"}]}
+]`
+
+var parsedABI = mustParseABI()
+
+func mustParseABI() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(contractsABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// Resolver resolves ENS names to addresses through the registry and its resolvers, and addresses to their primary
+// name. The names are resolved on every call, the primary names are cached in memory and, when given, in the store for
+// the cache TTL
+type Resolver struct {
+	ctx      context.Context
+	registry ethcommon.Address
+	url      string
+	timeout  time.Duration
+	st       *store.Store
+	ttl      time.Duration
+
+	// OnResolve is called with each name resolved to an address, the commands show it before signing
+	OnResolve func(name string, address string)
+
+	mu      sync.Mutex
+	client  *ethclient.Client
+	cache   map[string]cacheEntry
+	retryAt time.Time
+}
+
+// cacheEntry result of a reverse lookup kept in memory until it expires
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// New returns a resolver using the registry at the given address. The node is dialed on the first lookup not cached
+func New(ctx context.Context, registry string, url string, timeout time.Duration, st *store.Store, ttl time.Duration) *Resolver {
+	return &Resolver{
+		ctx:      ctx,
+		registry: ethcommon.HexToAddress(registry),
+		url:      url,
+		timeout:  timeout,
+		st:       st,
+		ttl:      ttl,
+		cache:    make(map[string]cacheEntry),
+	}
+}
+
+// Close closes the connection to the node, if any
+func (r *Resolver) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		r.client.Close()
+		r.client = nil
+	}
+}
+
+// IsName reports whether a value looks like an ENS name, i.e.: alice.eth
+func (r *Resolver) IsName(value string) bool {
+	return strings.Contains(value, ".") && !strings.HasPrefix(value, "0x")
+}
+
+// Namehash returns the EIP-137 node of a name. The names are lower cased, the full ENSIP-15 normalization is not applied
+func Namehash(name string) ethcommon.Hash {
+	var node ethcommon.Hash
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node.Bytes(), crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// Resolve returns the address of an ENS name, it fails when the name has no resolver or no address. The name is not
+// cached, it is resolved right before the commands sign anything with the record set at that time
+func (r *Resolver) Resolve(name string) (string, error) {
+	name = strings.ToLower(name)
+	if strings.Contains(name, "..") || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return "", fmt.Errorf("%w: %s", errs.ErrInvalidENSName, name)
+	}
+
+	resolved, err := r.forward(name)
+	if err != nil {
+		return "", err
+	}
+	if resolved == (ethcommon.Address{}) {
+		return "", fmt.Errorf("%w: %s", errs.ErrUnresolvedENSName, name)
+	}
+
+	address := resolved.Hex()
+	if r.OnResolve != nil {
+		r.OnResolve(name, address)
+	}
+	return address, nil
+}
+
+// Name returns the primary name of an address, empty when it has none or when it cannot be looked up. The name is
+// only returned when it resolves back to the address. The addresses without name are cached like the others, a failed
+// lookup is cached for failureTTL and suspends the lookups of the other addresses as long, so that the labels of the
+// displayed rows do not query an unavailable node over and over
+func (r *Resolver) Name(address string) string {
+	key := r.key("addr", strings.ToLower(address))
+	if name, ok := r.cached(key); ok {
+		return name
+	}
+
+	r.mu.Lock()
+	suspended := time.Now().Before(r.retryAt)
+	r.mu.Unlock()
+	if suspended {
+		return ""
+	}
+
+	name, err := r.reverse(ethcommon.HexToAddress(address))
+	if errors.Is(err, errs.ErrUnresolvedENSName) {
+		// the resolver of the address does not implement the records, the address has no name
+		name, err = "", nil
+	}
+	if err != nil {
+		slog.DebugContext(r.ctx, "failed to look up ens name", slog.String("address", address), slog.String("error", err.Error()))
+		r.mu.Lock()
+		r.retryAt = time.Now().Add(failureTTL)
+		r.cache[key] = cacheEntry{expires: r.retryAt}
+		r.mu.Unlock()
+		return ""
+	}
+	r.save(key, name)
+	return name
+}
+
+// forward reads the resolver of the name from the registry, then the address from the resolver
+func (r *Resolver) forward(name string) (ethcommon.Address, error) {
+	node := Namehash(name)
+	resolver, err := r.callAddress(r.registry, "resolver", node)
+	if err != nil {
+		return ethcommon.Address{}, fmt.Errorf("failed to get ens resolver of %s: %w", name, err)
+	}
+	if resolver == (ethcommon.Address{}) {
+		return ethcommon.Address{}, nil
+	}
+
+	address, err := r.callAddress(resolver, "addr", node)
+	if err != nil {
+		return ethcommon.Address{}, fmt.Errorf("failed to resolve ens name %s: %w", name, err)
+	}
+	return address, nil
+}
+
+// reverse reads the name of the reverse record of the address and checks it resolves back to the address
+func (r *Resolver) reverse(address ethcommon.Address) (string, error) {
+	node := Namehash(strings.ToLower(address.Hex()[2:]) + "." + reverseSuffix)
+	resolver, err := r.callAddress(r.registry, "resolver", node)
+	if err != nil || resolver == (ethcommon.Address{}) {
+		return "", err
+	}
+
+	out, err := r.call(resolver, "name", node)
+	if err != nil {
+		return "", err
+	}
+	name, _ := out[0].(string)
+	if name == "" {
+		return "", nil
+	}
+
+	forward, err := r.forward(name)
+	if err != nil {
+		return "", err
+	}
+	if forward != address {
+		return "", nil
+	}
+	return name, nil
+}
+
+// callAddress calls a view function returning an address
+func (r *Resolver) callAddress(contract ethcommon.Address, method string, node ethcommon.Hash) (ethcommon.Address, error) {
+	out, err := r.call(contract, method, node)
+	if err != nil {
+		return ethcommon.Address{}, err
+	}
+	address, _ := out[0].(ethcommon.Address)
+	return address, nil
+}
+
+// call calls a view function of the registry or of a resolver with a node
+func (r *Resolver) call(contract ethcommon.Address, method string, node ethcommon.Hash) ([]any, error) {
+	data, err := parsedABI.Pack(method, node)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	metrics.ObserveRPC("ens_"+method, start)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: %s has no code or no %s function", errs.ErrUnresolvedENSName, contract.Hex(), method)
+	}
+	return parsedABI.Unpack(method, result)
+}
+
+// dial returns the client, dialing the node on the first call
+func (r *Resolver) dial() (*ethclient.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		return r.client, nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, r.url)
+	if err != nil {
+		return nil, err
+	}
+	r.client = client
+	return client, nil
+}
+
+// key cache key of a lookup, the registry is part of it so that the networks do not share their results
+func (r *Resolver) key(kind string, value string) string {
+	return strings.ToLower(r.registry.Hex()) + "/" + kind + "/" + value
+}
+
+// cached returns the result of a lookup from the memory or from the store, when not older than the cache TTL
+func (r *Resolver) cached(key string) (string, bool) {
+	r.mu.Lock()
+	entry, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.value, true
+	}
+	if r.st == nil {
+		return "", false
+	}
+
+	record, err := r.st.GetENSRecord(r.ctx, key)
+	if err != nil || record == nil || time.Since(record.ResolvedAt) > r.ttl {
+		return "", false
+	}
+
+	r.mu.Lock()
+	r.cache[key] = cacheEntry{value: record.Value, expires: record.ResolvedAt.Add(r.ttl)}
+	r.mu.Unlock()
+	return record.Value, true
+}
+
+// save caches the result of a lookup for the cache TTL
+func (r *Resolver) save(key string, value string) {
+	r.mu.Lock()
+	r.cache[key] = cacheEntry{value: value, expires: time.Now().Add(r.ttl)}
+	r.mu.Unlock()
+
+	if r.st != nil {
+		if err := r.st.SaveENSRecord(r.ctx, key, value); err != nil {
+			slog.WarnContext(r.ctx, "failed to cache ens record", slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package ens
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	contracts "github.com/maxipaz/wallet/contracts/interfaces"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/testchain"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	alice    = ethcommon.HexToAddress("0x2c2205f5547D6d881D0c5D30c8e4bF67E2b51D63")
+	mallory  = ethcommon.HexToAddress("0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a")
+	nameless = ethcommon.HexToAddress("0x00000000000000000000000000000000000b0b00")
+)
+
+// testChain simulated chain with the registry and the resolver stubs of contracts/solidity
+type testChain struct {
+	*testchain.Chain
+	registry ethcommon.Address
+}
+
+// reverseNode returns the node of the reverse record of the address
+func reverseNode(address ethcommon.Address) ethcommon.Hash {
+	return Namehash(strings.ToLower(address.Hex()[2:]) + "." + reverseSuffix)
+}
+
+// startChain starts a simulated chain and deploys the registry and the resolver. alice.eth resolves to alice, which
+// is its primary name, mallory claims alice.eth as its primary name and nameless has no reverse record
+func startChain(t *testing.T) *testChain {
+	chain := testchain.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	chainID, err := chain.Client.ChainID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := bind.NewKeyedTransactorWithChainID(chain.Key, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.Context = ctx
+	// the transactions are sent before the previous ones are mined, they are not estimated on the latest block
+	signer.GasLimit = 500_000
+
+	var transactions []*types.Transaction
+	sent := func(tx *types.Transaction, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	registryAddress, tx, registry, err := contracts.DeployTestENSRegistry(signer, chain.Client)
+	sent(tx, err)
+	resolverAddress, tx, resolver, err := contracts.DeployTestENSResolver(signer, chain.Client)
+	sent(tx, err)
+
+	sent(registry.SetResolver(signer, Namehash("alice.eth"), resolverAddress))
+	sent(resolver.SetAddr(signer, Namehash("alice.eth"), alice))
+	for _, address := range []ethcommon.Address{alice, mallory} {
+		sent(registry.SetResolver(signer, reverseNode(address), resolverAddress))
+		sent(resolver.SetName(signer, reverseNode(address), "alice.eth"))
+	}
+
+	for _, tx := range transactions {
+		receipt, err := bind.WaitMined(ctx, chain.Client, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			t.Fatalf("transaction %s reverted", tx.Hash().Hex())
+		}
+	}
+	return &testChain{Chain: chain, registry: registryAddress}
+}
+
+// newResolver returns a resolver of the registry on the node, closed at the end of the test
+func newResolver(t *testing.T, registry ethcommon.Address, url string, st *store.Store) *Resolver {
+	r := New(context.Background(), registry.Hex(), url, 5*time.Second, st, time.Hour)
+	t.Cleanup(r.Close)
+	return r
+}
+
+// calls returns the number of calls made to the registry and the resolvers so far
+func calls(t *testing.T) uint64 {
+	var total uint64
+	for _, method := range []string{"resolver", "addr", "name"} {
+		var metric dto.Metric
+		if err := metrics.RPCDuration.WithLabelValues("ens_" + method).(prometheus.Metric).Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		total += metric.GetHistogram().GetSampleCount()
+	}
+	return total
+}
+
+func TestResolve(t *testing.T) {
+	chain := startChain(t)
+	r := newResolver(t, chain.registry, chain.URL, nil)
+
+	var shown []string
+	r.OnResolve = func(name string, address string) {
+		shown = append(shown, name+" "+address)
+	}
+
+	// the names are resolved on every call, never from the cache
+	for range 2 {
+		before := calls(t)
+		address, err := r.Resolve("Alice.eth")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != alice.Hex() {
+			t.Fatalf("alice.eth resolved to %s, expected %s", address, alice.Hex())
+		}
+		if got := calls(t) - before; got != 2 {
+			t.Fatalf("resolving made %d calls, expected the registry and the resolver calls", got)
+		}
+	}
+	if len(shown) != 2 || shown[0] != "alice.eth "+alice.Hex() {
+		t.Fatalf("shown resolutions are %q", shown)
+	}
+
+	if _, err := r.Resolve("bob.eth"); !errors.Is(err, errs.ErrUnresolvedENSName) {
+		t.Fatalf("name without resolver returned %v, expected an unresolved name error", err)
+	}
+	if _, err := r.Resolve("alice..eth"); !errors.Is(err, errs.ErrInvalidENSName) {
+		t.Fatalf("malformed name returned %v, expected an invalid name error", err)
+	}
+}
+
+func TestName(t *testing.T) {
+	chain := startChain(t)
+	st, err := store.Open(context.Background(), filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	r := newResolver(t, chain.registry, chain.URL, st)
+
+	tests := []struct {
+		name    string
+		address ethcommon.Address
+		want    string
+	}{
+		{"primary name", alice, "alice.eth"},
+		{"name not resolving back", mallory, ""},
+		{"no reverse record", nameless, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Name(tt.address.Hex()); got != tt.want {
+				t.Fatalf("got %q, expected %q", got, tt.want)
+			}
+
+			// the names and their absence are cached
+			before := calls(t)
+			if got := r.Name(tt.address.Hex()); got != tt.want {
+				t.Fatalf("cached lookup got %q, expected %q", got, tt.want)
+			}
+			if got := calls(t) - before; got != 0 {
+				t.Fatalf("cached lookup made %d calls", got)
+			}
+		})
+	}
+
+	// the store keeps the names for the other resolvers, the node is not reachable
+	unreachable := newResolver(t, chain.registry, filepath.Join(t.TempDir(), "missing.ipc"), st)
+	if got := unreachable.Name(alice.Hex()); got != "alice.eth" {
+		t.Fatalf("stored lookup got %q, expected alice.eth", got)
+	}
+}
+
+func TestNameUnavailable(t *testing.T) {
+	chain := startChain(t)
+	r := newResolver(t, chain.registry, filepath.Join(t.TempDir(), "missing.ipc"), nil)
+
+	if got := r.Name(alice.Hex()); got != "" {
+		t.Fatalf("got %q, expected no name when the node is unreachable", got)
+	}
+	if !r.retryAt.After(time.Now()) {
+		t.Fatal("the lookups are not suspended after a failure")
+	}
+
+	// the lookups stay suspended once the node is back
+	r.url = chain.URL
+	before := calls(t)
+	if got := r.Name(mallory.Hex()); got != "" {
+		t.Fatalf("got %q, expected no name while the lookups are suspended", got)
+	}
+	if got := calls(t) - before; got != 0 {
+		t.Fatalf("suspended lookup made %d calls", got)
+	}
+
+	// the failure is cached for the address until it expires
+	r.retryAt = time.Time{}
+	if got := r.Name(alice.Hex()); got != "" {
+		t.Fatalf("got %q, expected the failure to be cached", got)
+	}
+	key := r.key("addr", strings.ToLower(alice.Hex()))
+	r.cache[key] = cacheEntry{expires: time.Now().Add(-time.Second)}
+	if got := r.Name(alice.Hex()); got != "alice.eth" {
+		t.Fatalf("got %q, expected alice.eth once the failure expired", got)
+	}
+}
@@ -0,0 +1,226 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Exit codes of the commands, distinguishing the kinds of failures for the scripts
+const (
+	// ExitFailure any other failure
+	ExitFailure = 1
+	// ExitValidation invalid flags, arguments, configuration or input
+	ExitValidation = 2
+	// ExitRPC the node could not be reached or an RPC call failed
+	ExitRPC = 3
+	// ExitReverted the transaction reverted or failed
+	ExitReverted = 4
+	// ExitPolicy the operation was rejected by the spending policy or requires approval
+	ExitPolicy = 5
+)
+
+// Codes of the errors without a sentinel
+const (
+	CodeUnknown  = "error"
+	CodeRPC      = "rpc_error"
+	CodeReverted = "execution_reverted"
+	CodeTimeout  = "timeout"
+)
+
+// code stable code and exit code of a sentinel error
+type code struct {
+	err  error
+	code string
+	exit int
+}
+
+// codes stable codes of the sentinel errors, they are part of the commands output and must not change
+var codes = []code{
+	{ErrInvalidKey, "invalid_key", ExitValidation},
+	{ErrInvalidAddress, "invalid_address", ExitValidation},
+	{ErrInvalidContractAddress, "invalid_contract_address", ExitValidation},
+	{ErrInvalidAllowanceAction, "invalid_allowance_action", ExitValidation},
+	{ErrInvalidAmountAction, "invalid_amount", ExitValidation},
+	{ErrInvalidTransferAction, "invalid_transfer_action", ExitValidation},
+	{ErrInvalidBalanceAction, "invalid_balance_action", ExitValidation},
+	{ErrInvalidOwnershipAction, "invalid_ownership_action", ExitValidation},
+	{ErrMissingTargetAddress, "missing_target_address", ExitValidation},
+	{ErrSingleTargetAddress, "single_target_address", ExitValidation},
+	{ErrTransactionFailed, "transaction_failed", ExitReverted},
+	{ErrInvalidRequestBody, "invalid_request_body", ExitValidation},
+	{ErrTransactionDropped, "transaction_dropped", ExitRPC},
+	{ErrIdempotencyStoreRequired, "idempotency_store_required", ExitValidation},
+	{ErrIdempotencyKeyConflict, "idempotency_key_conflict", ExitValidation},
+	{ErrIdempotencyKeyInProgress, "idempotency_key_in_progress", ExitFailure},
+	{ErrUnauthenticated, "unauthenticated", ExitFailure},
+	{ErrPermissionDenied, "permission_denied", ExitFailure},
+	{ErrInvalidRole, "invalid_role", ExitValidation},
+	{ErrAuthNotConfigured, "auth_not_configured", ExitValidation},
+	{ErrInvalidAlertCondition, "invalid_alert_condition", ExitValidation},
+	{ErrInvalidReportFormat, "invalid_report_format", ExitValidation},
+	{ErrInvalidReportPeriod, "invalid_report_period", ExitValidation},
+	{ErrReconciliationMismatch, "reconciliation_mismatch", ExitFailure},
+	{ErrInvalidSchedule, "invalid_schedule", ExitValidation},
+	{ErrUnknownSchedule, "unknown_schedule", ExitValidation},
+	{ErrScheduleStoreRequired, "schedule_store_required", ExitValidation},
+	{ErrInvalidPolicy, "invalid_policy", ExitValidation},
+	{ErrPolicyViolation, "policy_violation", ExitPolicy},
+	{ErrPolicyStoreRequired, "policy_store_required", ExitValidation},
+	{ErrApprovalRequired, "approval_required", ExitPolicy},
+	{ErrInvalidApprovals, "invalid_approvals", ExitValidation},
+	{ErrUnknownProposal, "unknown_proposal", ExitValidation},
+	{ErrUnknownApprover, "unknown_approver", ExitValidation},
+	{ErrInvalidSignature, "invalid_signature", ExitValidation},
+	{ErrAlreadyDecided, "already_decided", ExitValidation},
+	{ErrProposalClosed, "proposal_closed", ExitValidation},
+	{ErrProposalNotApproved, "proposal_not_approved", ExitPolicy},
+	{ErrProposalMismatch, "proposal_mismatch", ExitPolicy},
+	{ErrInvalidSpendRequest, "invalid_spend_request", ExitValidation},
+	{ErrUnknownSpendRequest, "unknown_spend_request", ExitValidation},
+	{ErrDuplicateSpendRequest, "duplicate_spend_request", ExitValidation},
+	{ErrSpendRequestClosed, "spend_request_closed", ExitValidation},
+	{ErrSpendStoreRequired, "spend_store_required", ExitValidation},
+	{ErrInvalidBudget, "invalid_budget", ExitValidation},
+	{ErrInvalidPlan, "invalid_plan", ExitValidation},
+	{ErrPlanDrift, "plan_drift", ExitValidation},
+	{ErrPlanStoreRequired, "plan_store_required", ExitValidation},
+	{ErrPlanNotApplied, "plan_not_applied", ExitFailure},
+	{ErrInvalidBatch, "invalid_batch", ExitValidation},
+	{ErrBatchStoreRequired, "batch_store_required", ExitValidation},
+	{ErrBatchIncomplete, "batch_incomplete", ExitFailure},
+	{ErrInvalidUsage, "invalid_usage", ExitValidation},
+	{ErrInvalidConfig, "invalid_config", ExitValidation},
+	{ErrInvalidOutputFormat, "invalid_output_format", ExitValidation},
+	{ErrInvalidContact, "invalid_contact", ExitValidation},
+	{ErrUnknownContact, "unknown_contact", ExitValidation},
+	{ErrAmbiguousContact, "ambiguous_contact", ExitValidation},
+	{ErrDuplicateContact, "duplicate_contact", ExitValidation},
+	{ErrContactsStoreRequired, "contacts_store_required", ExitValidation},
+	{ErrInvalidENSName, "invalid_ens_name", ExitValidation},
+	{ErrUnresolvedENSName, "unresolved_ens_name", ExitValidation},
+	{ErrInvalidChecksum, "invalid_address_checksum", ExitValidation},
+	{ErrForbiddenBeneficiary, "forbidden_beneficiary", ExitValidation},
+	{ErrChainIDMismatch, "chain_id_mismatch", ExitValidation},
+	{ErrSecretUnavailable, "secret_unavailable", ExitValidation},
+	{ErrInvalidDeployments, "invalid_deployments", ExitFailure},
+	{ErrUnknownDeployment, "unknown_deployment", ExitValidation},
+	{ErrOutboxStoreRequired, "outbox_store_required", ExitValidation},
+}
+
+// notFound errors of a missing resource, the APIs answer them as not found instead of invalid input
+var notFound = []error{
+	ErrUnknownSchedule,
+	ErrUnknownProposal,
+	ErrUnknownSpendRequest,
+	ErrUnknownContact,
+	ErrUnknownDeployment,
+}
+
+// conflicts errors of a request conflicting with the state of a resource or with a concurrent request
+var conflicts = []error{
+	ErrIdempotencyKeyConflict,
+	ErrIdempotencyKeyInProgress,
+	ErrAlreadyDecided,
+	ErrProposalClosed,
+	ErrDuplicateSpendRequest,
+	ErrSpendRequestClosed,
+	ErrDuplicateContact,
+}
+
+// internal errors of the configuration of the process serving a request, the caller cannot fix them
+var internal = []error{
+	ErrInvalidKey,
+	ErrInvalidContractAddress,
+	ErrChainIDMismatch,
+	ErrSecretUnavailable,
+	ErrIdempotencyStoreRequired,
+	ErrPolicyStoreRequired,
+	ErrSpendStoreRequired,
+	ErrOutboxStoreRequired,
+}
+
+// IsNotFound reports whether the error is about a missing resource
+func IsNotFound(err error) bool {
+	return isAny(err, notFound)
+}
+
+// IsConflict reports whether the error is about a request conflicting with the state of a resource
+func IsConflict(err error) bool {
+	return isAny(err, conflicts)
+}
+
+// IsInternal reports whether the error comes from the configuration of the process rather than from the request
+func IsInternal(err error) bool {
+	return isAny(err, internal)
+}
+
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// rpcError error returned by the node, see the go-ethereum rpc.Error interface
+type rpcError interface {
+	ErrorCode() int
+}
+
+// revertedCode JSON-RPC error code of a reverted call
+const revertedCode = 3
+
+// Classify returns the stable code of an error and the exit code of its kind. The outermost sentinel error of the
+// chain gives the code, the errors returned by the node or the network are classified by their type
+func Classify(err error) (string, int) {
+	if c, ok := lookup(err); ok {
+		return c.code, c.exit
+	}
+
+	var rpcErr rpcError
+	if errors.As(err, &rpcErr) {
+		if rpcErr.ErrorCode() == revertedCode || strings.Contains(err.Error(), "execution reverted") {
+			return CodeReverted, ExitReverted
+		}
+		return CodeRPC, ExitRPC
+	}
+	if strings.Contains(err.Error(), "execution reverted") {
+		return CodeReverted, ExitReverted
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout, ExitRPC
+	case errors.As(err, &netErr):
+		return CodeRPC, ExitRPC
+	}
+	return CodeUnknown, ExitFailure
+}
+
+// lookup walks the error tree depth first and returns the code of the first sentinel error found
+func lookup(err error) (code, bool) {
+	if err == nil {
+		return code{}, false
+	}
+	for _, c := range codes {
+		if err == c.err {
+			return c, true
+		}
+	}
+
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() error }:
+		return lookup(wrapped.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, e := range wrapped.Unwrap() {
+			if c, ok := lookup(e); ok {
+				return c, true
+			}
+		}
+	}
+	return code{}, false
+}
@@ -3,10 +3,73 @@ package errors
 import "errors"
 
 var (
-	ErrInvalidKey             = errors.New("invalid key")
-	ErrInvalidAddress         = errors.New("invalid address")
-	ErrInvalidContractAddress = errors.New("invalid contract address")
-	ErrInvalidAllowanceAction = errors.New("invalid allowance action")
-	ErrInvalidAmountAction    = errors.New("amount should be a positive value")
-	ErrInvalidTransferAction  = errors.New("invalid transfer action")
+	ErrInvalidKey               = errors.New("invalid key")
+	ErrInvalidAddress           = errors.New("invalid address")
+	ErrInvalidContractAddress   = errors.New("invalid contract address")
+	ErrInvalidAllowanceAction   = errors.New("invalid allowance action")
+	ErrInvalidAmountAction      = errors.New("amount should be a positive value")
+	ErrInvalidTransferAction    = errors.New("invalid transfer action")
+	ErrInvalidBalanceAction     = errors.New("invalid balance action")
+	ErrInvalidOwnershipAction   = errors.New("invalid ownership action")
+	ErrMissingTargetAddress     = errors.New("target address is required")
+	ErrSingleTargetAddress      = errors.New("this action takes a single target address")
+	ErrTransactionFailed        = errors.New("transaction failed")
+	ErrInvalidRequestBody       = errors.New("invalid request body")
+	ErrTransactionDropped       = errors.New("transaction is no longer known by the node")
+	ErrIdempotencyStoreRequired = errors.New("idempotency keys require the store, please configure store.path")
+	ErrIdempotencyKeyConflict   = errors.New("idempotency key already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with the same idempotency key is in progress")
+	ErrUnauthenticated          = errors.New("unauthenticated")
+	ErrPermissionDenied         = errors.New("permission denied")
+	ErrInvalidRole              = errors.New("invalid role")
+	ErrAuthNotConfigured        = errors.New("no API key or JWT key configured, set auth.disabled to serve without authentication")
+	ErrInvalidAlertCondition    = errors.New("invalid alert condition")
+	ErrInvalidReportFormat      = errors.New("invalid report format")
+	ErrInvalidReportPeriod      = errors.New("invalid report period")
+	ErrReconciliationMismatch   = errors.New("on-chain state does not match the events history")
+	ErrInvalidSchedule          = errors.New("invalid schedule")
+	ErrUnknownSchedule          = errors.New("unknown schedule")
+	ErrScheduleStoreRequired    = errors.New("schedules require the store, please configure store.path")
+	ErrInvalidPolicy            = errors.New("invalid spending policy")
+	ErrPolicyViolation          = errors.New("spending policy violation")
+	ErrPolicyStoreRequired      = errors.New("spending caps require the store, please configure store.path")
+	ErrApprovalRequired         = errors.New("operation requires approval, please create a proposal")
+	ErrInvalidApprovals         = errors.New("invalid approvals configuration")
+	ErrUnknownProposal          = errors.New("unknown proposal")
+	ErrUnknownApprover          = errors.New("unknown approver")
+	ErrInvalidSignature         = errors.New("invalid signature")
+	ErrAlreadyDecided           = errors.New("approver already decided on the proposal")
+	ErrProposalClosed           = errors.New("proposal is no longer open")
+	ErrProposalNotApproved      = errors.New("proposal is not approved")
+	ErrProposalMismatch         = errors.New("operation does not match the approved proposal")
+	ErrInvalidSpendRequest      = errors.New("invalid spend request")
+	ErrUnknownSpendRequest      = errors.New("unknown spend request")
+	ErrDuplicateSpendRequest    = errors.New("beneficiary already submitted a spend request with the same reference")
+	ErrSpendRequestClosed       = errors.New("spend request is no longer pending")
+	ErrSpendStoreRequired       = errors.New("spend requests require the store, please configure store.path")
+	ErrInvalidBudget            = errors.New("invalid budgets file")
+	ErrInvalidPlan              = errors.New("invalid allowance plan")
+	ErrPlanDrift                = errors.New("allowance changed since the plan was made")
+	ErrPlanStoreRequired        = errors.New("applying a plan requires the store, please configure store.path")
+	ErrPlanNotApplied           = errors.New("allowances do not match the plan")
+	ErrInvalidBatch             = errors.New("invalid batch")
+	ErrBatchStoreRequired       = errors.New("batches require the store, please configure store.path")
+	ErrBatchIncomplete          = errors.New("some rows of the batch were not mined, see the results")
+	ErrInvalidUsage             = errors.New("invalid usage")
+	ErrInvalidConfig            = errors.New("invalid configuration")
+	ErrInvalidOutputFormat      = errors.New("invalid output format")
+	ErrInvalidContact           = errors.New("invalid contact")
+	ErrUnknownContact           = errors.New("unknown contact")
+	ErrAmbiguousContact         = errors.New("tag names several contacts, please use a contact name or an address")
+	ErrDuplicateContact         = errors.New("a contact with the same name or address exists")
+	ErrContactsStoreRequired    = errors.New("the address book requires the store, please configure store.path")
+	ErrInvalidENSName           = errors.New("invalid ENS name")
+	ErrUnresolvedENSName        = errors.New("ENS name has no address")
+	ErrInvalidChecksum          = errors.New("invalid address checksum, please check the address or write it in lower case")
+	ErrForbiddenBeneficiary     = errors.New("address cannot be a beneficiary")
+	ErrChainIDMismatch          = errors.New("chain ID mismatch, refusing to sign")
+	ErrSecretUnavailable        = errors.New("secret could not be resolved")
+	ErrInvalidDeployments       = errors.New("invalid deployments file")
+	ErrUnknownDeployment        = errors.New("unknown deployment")
+	ErrOutboxStoreRequired      = errors.New("the outbox requires the store, please configure store.path")
 )
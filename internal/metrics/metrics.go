@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+const (
+	namespace       = "wallet"
+	shutdownTimeout = 5 * time.Second
+)
+
+var (
+	// EventsTotal number of contract events processed by the monitor, by event type
+	EventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "events_total",
+		Help:      "Number of contract events processed by the monitor.",
+	}, []string{"event"})
+
+	// LastProcessedBlock block number of the last contract event processed by the monitor
+	LastProcessedBlock = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "last_processed_block",
+		Help:      "Block number of the last contract event processed by the monitor.",
+	})
+
+	// HeadBlock latest block number seen by the monitor
+	HeadBlock = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "head_block",
+		Help:      "Latest chain head block number seen by the monitor.",
+	})
+
+	// BlockLag number of blocks between the chain head and the last processed block
+	BlockLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "block_lag",
+		Help:      "Number of blocks between the chain head and the last processed contract event.",
+	})
+
+	// SubscriptionReconnects number of times an event subscription was re-established
+	SubscriptionReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "subscription_reconnects_total",
+		Help:      "Number of times an event subscription was re-established after a failure.",
+	}, []string{"subscription"})
+
+	// ContractBalance contract balance in ether
+	ContractBalance = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "contract",
+		Name:      "balance_ether",
+		Help:      "Contract balance in ether.",
+	})
+
+	// BeneficiaryAllowance allowance of each beneficiary in ether
+	BeneficiaryAllowance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "contract",
+		Name:      "allowance_ether",
+		Help:      "Allowance assigned to a beneficiary in ether.",
+	}, []string{"beneficiary"})
+
+	// AlertsTotal number of alerts delivered by the monitor, by rule
+	AlertsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "alerts_total",
+		Help:      "Number of alerts delivered by the monitor.",
+	}, []string{"rule"})
+
+	// RPCDuration latency of the RPC calls performed by the runners
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "runner",
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of the RPC calls performed by the runners.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// ConfirmationDuration time elapsed between sending a transaction and getting it mined
+	ConfirmationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "runner",
+		Name:      "tx_confirmation_seconds",
+		Help:      "Time elapsed between sending a transaction and getting it mined.",
+		Buckets:   []float64{1, 2, 5, 10, 15, 30, 60, 120, 300},
+	}, []string{"operation"})
+
+	registry = prometheus.NewRegistry()
+)
+
+func init() {
+	registry.MustRegister(
+		EventsTotal,
+		LastProcessedBlock,
+		HeadBlock,
+		BlockLag,
+		SubscriptionReconnects,
+		ContractBalance,
+		BeneficiaryAllowance,
+		AlertsTotal,
+		RPCDuration,
+		ConfirmationDuration,
+	)
+}
+
+// Handler returns the HTTP handler exposing the registered metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve exposes the /metrics endpoint on the given address until the context is done
+func Serve(ctx context.Context, address string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: shutdownTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctxShutdown)
+	}()
+
+	slog.DebugContext(ctx, "serving metrics", slog.String("address", address))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// ObserveRPC records the latency of an RPC operation started at the given time
+func ObserveRPC(operation string, start time.Time) {
+	RPCDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// ObserveConfirmation records the confirmation time of a transaction sent at the given time
+func ObserveConfirmation(operation string, start time.Time) {
+	ConfirmationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// WeiToEther convert Wei to a fractional Ether value suitable for gauges
+func WeiToEther(wei *big.Int) float64 {
+	if wei == nil {
+		return 0
+	}
+	value, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.Ether)).Float64()
+	return value
+}
@@ -0,0 +1,139 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	TableFormat = "table"
+	JSONFormat  = "json"
+	YAMLFormat  = "yaml"
+)
+
+// Formats supported output formats
+var Formats = map[string]struct{}{
+	TableFormat: {},
+	JSONFormat:  {},
+	YAMLFormat:  {},
+}
+
+// Format format of the results and errors, set by the global output flag
+var Format = TableFormat
+
+// Validate checks the output format
+func Validate() error {
+	if _, ok := Formats[Format]; !ok {
+		invalid := Format
+		// the error itself is written with the default format
+		Format = TableFormat
+		return fmt.Errorf("%w: %s, please use table, json or yaml", errs.ErrInvalidOutputFormat, invalid)
+	}
+	return nil
+}
+
+// Structured reports whether the output is JSON or YAML, read by scripts rather than people
+func Structured() bool {
+	return Format == JSONFormat || Format == YAMLFormat
+}
+
+// Print writes the result of a command to the standard output. The JSON and YAML formats encode the result with its
+// json tags, the table format calls the table function with a tab separated writer
+func Print(result any, table func(w io.Writer)) error {
+	if Structured() {
+		return encode(os.Stdout, result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+// Encode writes the result as JSON or YAML, like Print, to another writer than the standard output, i.e.: a file
+func Encode(w io.Writer, result any) error {
+	return encode(w, result)
+}
+
+// Progressf writes the progress of a long operation. It goes to the standard error with the JSON and YAML formats,
+// keeping the standard output a single document
+func Progressf(format string, args ...any) {
+	var w io.Writer = os.Stdout
+	if Structured() {
+		w = os.Stderr
+	}
+	_, _ = fmt.Fprintf(w, format, args...)
+}
+
+// errorResult error written to the standard error
+type errorResult struct {
+	Error struct {
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+		ExitCode int    `json:"exit_code"`
+	} `json:"error"`
+}
+
+// Error writes the error and its stable code to the standard error and returns the exit code of its kind
+func Error(err error) int {
+	var result errorResult
+	result.Error.Code, result.Error.ExitCode = errs.Classify(err)
+	result.Error.Message = err.Error()
+
+	if !Structured() || encode(os.Stderr, result) != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", result.Error.Code, result.Error.Message)
+	}
+	return result.Error.ExitCode
+}
+
+// encode writes the value as JSON or YAML, with the same field names
+func encode(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	if Format == JSONFormat {
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+
+	// JSON is valid YAML, decoding it into nodes keeps the fields order of the JSON encoding
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	blockStyle(&node)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return encoder.Close()
+}
+
+// blockStyle resets the flow style of the nodes decoded from JSON, the values are quoted only when needed. Addresses
+// and hashes stay quoted, some YAML parsers would read them as hexadecimal numbers
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && strings.HasPrefix(node.Value, "0x") {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// List returns the items, or an empty list when nil, to encode an empty result as an empty list rather than null
+func List[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,313 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/store"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Operations recorded in the outbox checked by the policy
+const (
+	payoutOperation            = "send_money"
+	setAllowanceOperation      = "set_allowance"
+	increaseAllowanceOperation = "increase_allowance"
+	transferOwnershipOperation = "transfer_ownership"
+)
+
+// clockLayout layout of the business hours
+const clockLayout = "15:04"
+
+var weekDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// countedStatuses status of the outbox transactions counted as spent, the ones that will never be mined are ignored
+var countedStatuses = []string{store.TxSigned, store.TxPending, store.TxMined}
+
+// Policy internal spending rules checked before signing, stricter than the contract allowance and balance checks
+type Policy struct {
+	location              *time.Location
+	maxPayout             int64
+	maxAllowance          int64
+	dailyCap              int64
+	monthlyCap            int64
+	beneficiaryDailyCap   int64
+	beneficiaryMonthlyCap int64
+	allowed               map[string]struct{}
+	days                  map[time.Weekday]struct{}
+	start                 time.Duration
+	end                   time.Duration
+	payoutThreshold       int64
+	allowanceThreshold    int64
+	ownershipTransfer     bool
+	now                   func() time.Time
+}
+
+// New returns a new policy instance
+func New(cfg config.PolicyConfig) (*Policy, error) {
+	p := &Policy{
+		location:              time.Local,
+		maxPayout:             cfg.MaxPayout,
+		maxAllowance:          cfg.MaxAllowance,
+		dailyCap:              cfg.DailyCap,
+		monthlyCap:            cfg.MonthlyCap,
+		beneficiaryDailyCap:   cfg.BeneficiaryDailyCap,
+		beneficiaryMonthlyCap: cfg.BeneficiaryMonthlyCap,
+		payoutThreshold:       cfg.Approvals.PayoutThreshold,
+		allowanceThreshold:    cfg.Approvals.AllowanceThreshold,
+		ownershipTransfer:     cfg.Approvals.OwnershipTransfer,
+		now:                   time.Now,
+	}
+
+	for _, amount := range []int64{p.maxPayout, p.maxAllowance, p.dailyCap, p.monthlyCap, p.beneficiaryDailyCap, p.beneficiaryMonthlyCap, p.payoutThreshold, p.allowanceThreshold} {
+		if amount < 0 {
+			return nil, fmt.Errorf("%w: amounts cannot be negative", errs.ErrInvalidPolicy)
+		}
+	}
+
+	if cfg.Timezone != "" {
+		location, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: timezone: %w", errs.ErrInvalidPolicy, err)
+		}
+		p.location = location
+	}
+
+	if len(cfg.AllowedRecipients) > 0 {
+		p.allowed = make(map[string]struct{}, len(cfg.AllowedRecipients))
+		for _, recipient := range cfg.AllowedRecipients {
+			if err := common.ValidateAddress(recipient); err != nil {
+				return nil, fmt.Errorf("%w: allowed recipient %s: %w", errs.ErrInvalidPolicy, recipient, err)
+			}
+			p.allowed[ethcommon.HexToAddress(recipient).Hex()] = struct{}{}
+		}
+	}
+
+	if err := p.setBusinessHours(cfg.BusinessHours); err != nil {
+		return nil, err
+	}
+
+	// a threshold without enough approvers would reject every operation above it
+	approvals := cfg.Approvals
+	if (p.payoutThreshold > 0 || p.allowanceThreshold > 0 || p.ownershipTransfer) &&
+		(approvals.Required < 1 || len(approvals.Approvers) < approvals.Required) {
+		return nil, fmt.Errorf("%w: %d approvals required from %d approvers", errs.ErrInvalidApprovals, approvals.Required, len(approvals.Approvers))
+	}
+	return p, nil
+}
+
+// setBusinessHours parses the business hours, the days default to every day and the hours to the whole day
+func (p *Policy) setBusinessHours(cfg config.BusinessHoursConfig) error {
+	if len(cfg.Days) > 0 {
+		p.days = make(map[time.Weekday]struct{}, len(cfg.Days))
+		for _, day := range cfg.Days {
+			weekDay, ok := weekDays[strings.ToLower(day)]
+			if !ok {
+				return fmt.Errorf("%w: business day %q, use mon, tue, wed, thu, fri, sat or sun", errs.ErrInvalidPolicy, day)
+			}
+			p.days[weekDay] = struct{}{}
+		}
+	}
+
+	if cfg.Start == "" && cfg.End == "" {
+		return nil
+	}
+
+	start, err := time.Parse(clockLayout, cfg.Start)
+	if err != nil {
+		return fmt.Errorf("%w: business hours start: %w", errs.ErrInvalidPolicy, err)
+	}
+	end, err := time.Parse(clockLayout, cfg.End)
+	if err != nil {
+		return fmt.Errorf("%w: business hours end: %w", errs.ErrInvalidPolicy, err)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("%w: business hours end must be after start", errs.ErrInvalidPolicy)
+	}
+
+	p.start = clock(start)
+	p.end = clock(end)
+	return nil
+}
+
+// clock returns the time elapsed since the start of the day
+func clock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// Payouts running totals of payouts not recorded in the outbox yet, i.e. the previous rows of a batch
+type Payouts struct {
+	total         int64
+	beneficiaries map[string]int64
+}
+
+// Add counts a payout in the totals
+func (p *Payouts) Add(target string, amount int64) {
+	if p.beneficiaries == nil {
+		p.beneficiaries = make(map[string]int64)
+	}
+	p.total += amount
+	p.beneficiaries[ethcommon.HexToAddress(target).Hex()] += amount
+}
+
+// of returns the total of the target, of every beneficiary when it is empty
+func (p *Payouts) of(target string) int64 {
+	if p == nil {
+		return 0
+	}
+	if target == "" {
+		return p.total
+	}
+	return p.beneficiaries[ethcommon.HexToAddress(target).Hex()]
+}
+
+// CheckPayout checks a payout against the maximum payout, the allowed recipients, the business hours and the daily
+// and monthly caps. The caps are computed from the payouts recorded in the outbox and the pending ones, when not nil
+func (p *Policy) CheckPayout(ctx context.Context, st *store.Store, pending *Payouts, target string, amount int64) error {
+	if p.maxPayout > 0 && amount > p.maxPayout {
+		return p.reject(ctx, "payout of %d ether to %s exceeds the maximum payout of %d ether", amount, target, p.maxPayout)
+	}
+	if err := p.checkRecipient(ctx, target); err != nil {
+		return err
+	}
+	if err := p.checkBusinessHours(ctx); err != nil {
+		return err
+	}
+
+	now := p.now().In(p.location)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, p.location)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, p.location)
+
+	caps := []struct {
+		cap    int64
+		since  time.Time
+		target string
+		name   string
+	}{
+		{p.dailyCap, day, "", "daily cap"},
+		{p.monthlyCap, month, "", "monthly cap"},
+		{p.beneficiaryDailyCap, day, target, "daily cap of " + target},
+		{p.beneficiaryMonthlyCap, month, target, "monthly cap of " + target},
+	}
+	for _, c := range caps {
+		if c.cap == 0 {
+			continue
+		}
+		if st == nil {
+			return errs.ErrPolicyStoreRequired
+		}
+
+		spent, err := spentSince(ctx, st, c.target, c.since)
+		if err != nil {
+			return err
+		}
+
+		queued := pending.of(c.target)
+		total := new(big.Int).Add(spent, common.EtherToWei(big.NewInt(amount+queued)))
+		if total.Cmp(common.EtherToWei(big.NewInt(c.cap))) > 0 {
+			if queued > 0 {
+				return p.reject(ctx, "payout of %d ether to %s exceeds the %s of %d ether, %s ether already sent and %d ether pending",
+					amount, target, c.name, c.cap, common.FormatEther(spent), queued)
+			}
+			return p.reject(ctx, "payout of %d ether to %s exceeds the %s of %d ether, %s ether already sent",
+				amount, target, c.name, c.cap, common.FormatEther(spent))
+		}
+	}
+	return nil
+}
+
+// CheckAllowance checks an allowance set or increase against the maximum allowance, the allowed recipients and the
+// business hours. The allowance is the one resulting from the change, the current allowance plus the increase.
+// Reducing an allowance lowers the exposure and is not checked
+func (p *Policy) CheckAllowance(ctx context.Context, target string, allowance int64) error {
+	if p.maxAllowance > 0 && allowance > p.maxAllowance {
+		return p.reject(ctx, "allowance of %d ether for %s exceeds the maximum allowance of %d ether", allowance, target, p.maxAllowance)
+	}
+	if err := p.checkRecipient(ctx, target); err != nil {
+		return err
+	}
+	return p.checkBusinessHours(ctx)
+}
+
+// RequiresApproval reports whether the operation must be executed as an approved proposal. The amount of the
+// allowance changes is the allowance they result in, so an allowance cannot be raised in small increases
+func (p *Policy) RequiresApproval(operation string, amount int64) bool {
+	switch operation {
+	case payoutOperation:
+		return p.payoutThreshold > 0 && amount > p.payoutThreshold
+	case setAllowanceOperation, increaseAllowanceOperation:
+		return p.allowanceThreshold > 0 && amount > p.allowanceThreshold
+	case transferOwnershipOperation:
+		return p.ownershipTransfer
+	}
+	return false
+}
+
+// checkRecipient rejects the recipients missing from the allowed list, when there is one
+func (p *Policy) checkRecipient(ctx context.Context, target string) error {
+	if p.allowed == nil {
+		return nil
+	}
+	if _, ok := p.allowed[ethcommon.HexToAddress(target).Hex()]; !ok {
+		return p.reject(ctx, "%s is not an allowed recipient", target)
+	}
+	return nil
+}
+
+// checkBusinessHours rejects the operations outside the business days and hours
+func (p *Policy) checkBusinessHours(ctx context.Context) error {
+	now := p.now().In(p.location)
+	if p.days != nil {
+		if _, ok := p.days[now.Weekday()]; !ok {
+			return p.reject(ctx, "%s is not a business day", now.Weekday())
+		}
+	}
+
+	if p.end == 0 {
+		return nil
+	}
+	if elapsed := clock(now); elapsed < p.start || elapsed >= p.end {
+		return p.reject(ctx, "%s is outside the business hours", now.Format(clockLayout))
+	}
+	return nil
+}
+
+// reject logs the violation and returns it as a policy error
+func (p *Policy) reject(ctx context.Context, format string, args ...any) error {
+	err := fmt.Errorf("%w: "+format, append([]any{errs.ErrPolicyViolation}, args...)...)
+	slog.WarnContext(ctx, "spending policy violation", slog.String("error", err.Error()))
+	return err
+}
+
+// spentSince returns the amount paid out since the given time, to the target when it is not empty
+func spentSince(ctx context.Context, st *store.Store, target string, since time.Time) (*big.Int, error) {
+	transactions, err := st.ListTransactions(ctx, store.TransactionFilter{
+		Statuses:   countedStatuses,
+		Operations: []string{payoutOperation},
+		Target:     target,
+		Since:      since,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	spent := new(big.Int)
+	for _, tx := range transactions {
+		spent.Add(spent, tx.Amount)
+	}
+	return spent, nil
+}
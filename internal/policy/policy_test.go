@@ -0,0 +1,254 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/store"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+const (
+	alice = "0x2c2205f5547D6d881D0c5D30c8e4bF67E2b51D63"
+	bob   = "0x757e4Ef70dEE4Eb2bccCb34D1a2FAC893973153a"
+)
+
+// newYork location of the policies, its days start at 04:00 or 05:00 UTC
+var newYork = mustLoad("America/New_York")
+
+func mustLoad(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// at returns the time in New York
+func at(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, newYork)
+}
+
+// newPolicy returns a policy of the configuration in New York whose clock is fixed at the given time
+func newPolicy(t *testing.T, cfg config.PolicyConfig, now time.Time) *Policy {
+	t.Helper()
+	cfg.Timezone = newYork.String()
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now = func() time.Time { return now }
+	return p
+}
+
+// payoutStore returns a store whose outbox holds the payouts of alice and bob around the end of March 2026
+func payoutStore(t *testing.T) *store.Store {
+	t.Helper()
+	ctx := context.Background()
+	st, err := store.Open(ctx, filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	payouts := []struct {
+		target    string
+		amount    int64
+		createdAt time.Time
+		status    string
+	}{
+		// today, early in the New York day
+		{alice, 3, at(2026, time.March, 31, 0, 30), store.TxMined},
+		// yesterday in New York, already March 31 in UTC
+		{bob, 2, at(2026, time.March, 30, 23, 30), store.TxPending},
+		// last month
+		{alice, 4, at(2026, time.February, 28, 12, 0), store.TxMined},
+		// today, never mined
+		{alice, 5, at(2026, time.March, 31, 1, 0), store.TxReplaced},
+	}
+	for i, payout := range payouts {
+		hash := common.EtherToWei(big.NewInt(int64(i + 1))).Text(16)
+		err := st.SaveTransaction(ctx, store.Transaction{
+			Hash:      hash,
+			Operation: payoutOperation,
+			Target:    payout.target,
+			Amount:    common.EtherToWei(big.NewInt(payout.amount)),
+			Raw:       []byte{0x01},
+			CreatedAt: payout.createdAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := st.SetTransactionStatus(ctx, hash, payout.status, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return st
+}
+
+func TestCheckPayout(t *testing.T) {
+	st := payoutStore(t)
+	now := at(2026, time.March, 31, 23, 30)
+
+	// spent today: 3 ether to alice, this month: 3 ether to alice and 2 ether to bob
+	tests := []struct {
+		name   string
+		cfg    config.PolicyConfig
+		now    time.Time
+		target string
+		amount int64
+		reject string
+	}{
+		{"no rule", config.PolicyConfig{}, now, alice, 100, ""},
+		{"maximum payout", config.PolicyConfig{MaxPayout: 3}, now, alice, 3, ""},
+		{"above maximum payout", config.PolicyConfig{MaxPayout: 3}, now, alice, 4, "maximum payout"},
+		{"daily cap reached", config.PolicyConfig{DailyCap: 5}, now, bob, 2, ""},
+		{"above daily cap", config.PolicyConfig{DailyCap: 5}, now, bob, 3, "daily cap of 5"},
+		{"daily cap of the next day", config.PolicyConfig{DailyCap: 1}, at(2026, time.April, 1, 0, 0), alice, 1, ""},
+		{"daily cap before midnight", config.PolicyConfig{DailyCap: 3}, at(2026, time.March, 31, 23, 59), alice, 1, "daily cap of 3"},
+		{"monthly cap reached", config.PolicyConfig{MonthlyCap: 8}, now, alice, 3, ""},
+		{"above monthly cap", config.PolicyConfig{MonthlyCap: 8}, now, alice, 4, "monthly cap of 8"},
+		{"monthly cap of the next month", config.PolicyConfig{MonthlyCap: 1}, at(2026, time.April, 1, 0, 0), alice, 1, ""},
+		{"beneficiary daily cap", config.PolicyConfig{BeneficiaryDailyCap: 4}, now, alice, 1, ""},
+		{"above beneficiary daily cap", config.PolicyConfig{BeneficiaryDailyCap: 4}, now, alice, 2, "daily cap of " + alice},
+		{"beneficiary daily cap in the timezone", config.PolicyConfig{BeneficiaryDailyCap: 4}, now, bob, 4, ""},
+		{"beneficiary monthly cap", config.PolicyConfig{BeneficiaryMonthlyCap: 5}, now, bob, 3, ""},
+		{"above beneficiary monthly cap", config.PolicyConfig{BeneficiaryMonthlyCap: 5}, now, bob, 4, "monthly cap of " + bob},
+		{"beneficiary monthly cap of the month", config.PolicyConfig{BeneficiaryMonthlyCap: 5}, now, alice, 2, ""},
+		{"allowed recipient", config.PolicyConfig{AllowedRecipients: []string{alice}}, now, strings.ToLower(alice), 1, ""},
+		{"recipient not allowed", config.PolicyConfig{AllowedRecipients: []string{alice}}, now, bob, 1, "not an allowed recipient"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPolicy(t, tt.cfg, tt.now)
+			err := p.CheckPayout(context.Background(), st, nil, tt.target, tt.amount)
+			checkRejection(t, err, tt.reject)
+		})
+	}
+}
+
+func TestCheckPayoutPending(t *testing.T) {
+	st := payoutStore(t)
+	p := newPolicy(t, config.PolicyConfig{DailyCap: 10, BeneficiaryDailyCap: 4}, at(2026, time.March, 31, 12, 0))
+
+	var pending Payouts
+	pending.Add(bob, 2)
+	checkRejection(t, p.CheckPayout(context.Background(), st, &pending, bob, 2), "")
+	checkRejection(t, p.CheckPayout(context.Background(), st, &pending, bob, 3), "daily cap of "+bob)
+
+	// 3 ether already sent today and 7 ether pending
+	pending.Add(strings.ToLower(alice), 5)
+	checkRejection(t, p.CheckPayout(context.Background(), st, &pending, bob, 1), "daily cap of 10")
+}
+
+func TestCheckPayoutWithoutStore(t *testing.T) {
+	p := newPolicy(t, config.PolicyConfig{DailyCap: 5}, at(2026, time.March, 31, 12, 0))
+	if err := p.CheckPayout(context.Background(), nil, nil, alice, 1); !errors.Is(err, errs.ErrPolicyStoreRequired) {
+		t.Fatalf("got %v, expected the store to be required", err)
+	}
+}
+
+func TestBusinessHours(t *testing.T) {
+	cfg := config.PolicyConfig{
+		MaxAllowance:  10,
+		BusinessHours: config.BusinessHoursConfig{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"},
+	}
+
+	// March 31 2026 is a Tuesday
+	tests := []struct {
+		name   string
+		now    time.Time
+		reject string
+	}{
+		{"before opening", at(2026, time.March, 31, 8, 59), "outside the business hours"},
+		{"opening", at(2026, time.March, 31, 9, 0), ""},
+		{"before closing", at(2026, time.March, 31, 16, 59), ""},
+		{"closing", at(2026, time.March, 31, 17, 0), "outside the business hours"},
+		{"saturday", at(2026, time.March, 28, 10, 0), "not a business day"},
+		{"sunday in the timezone", time.Date(2026, time.March, 30, 1, 0, 0, 0, time.UTC), "Sunday is not a business day"},
+		{"monday in the timezone", time.Date(2026, time.March, 30, 13, 0, 0, 0, time.UTC), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPolicy(t, cfg, tt.now)
+			checkRejection(t, p.CheckAllowance(context.Background(), alice, 5), tt.reject)
+			checkRejection(t, p.CheckPayout(context.Background(), nil, nil, alice, 1), tt.reject)
+		})
+	}
+}
+
+func TestCheckAllowance(t *testing.T) {
+	p := newPolicy(t, config.PolicyConfig{MaxAllowance: 10, AllowedRecipients: []string{alice}}, at(2026, time.March, 31, 12, 0))
+
+	checkRejection(t, p.CheckAllowance(context.Background(), alice, 10), "")
+	checkRejection(t, p.CheckAllowance(context.Background(), alice, 11), "maximum allowance of 10")
+	checkRejection(t, p.CheckAllowance(context.Background(), bob, 1), "not an allowed recipient")
+}
+
+func TestRequiresApproval(t *testing.T) {
+	cfg := config.PolicyConfig{Approvals: config.ApprovalsConfig{
+		Required:           1,
+		Approvers:          []config.Approver{{Name: "alice", Address: alice}},
+		PayoutThreshold:    5,
+		AllowanceThreshold: 10,
+	}}
+	p := newPolicy(t, cfg, time.Now())
+
+	tests := []struct {
+		operation string
+		amount    int64
+		want      bool
+	}{
+		{payoutOperation, 5, false},
+		{payoutOperation, 6, true},
+		{setAllowanceOperation, 10, false},
+		{increaseAllowanceOperation, 11, true},
+		{transferOwnershipOperation, 0, false},
+	}
+	for _, tt := range tests {
+		if got := p.RequiresApproval(tt.operation, tt.amount); got != tt.want {
+			t.Errorf("%s of %d requires approval: %t, expected %t", tt.operation, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := map[string]config.PolicyConfig{
+		"negative cap":      {DailyCap: -1},
+		"unknown timezone":  {Timezone: "Mars/Olympus"},
+		"invalid recipient": {AllowedRecipients: []string{"0x123"}},
+		"unknown day":       {BusinessHours: config.BusinessHoursConfig{Days: []string{"monday"}}},
+		"end before start":  {BusinessHours: config.BusinessHoursConfig{Start: "17:00", End: "09:00"}},
+	}
+	for name, cfg := range tests {
+		if _, err := New(cfg); !errors.Is(err, errs.ErrInvalidPolicy) {
+			t.Errorf("%s: got %v, expected an invalid policy", name, err)
+		}
+	}
+
+	thresholdWithoutApprovers := config.PolicyConfig{Approvals: config.ApprovalsConfig{PayoutThreshold: 5, Required: 2}}
+	if _, err := New(thresholdWithoutApprovers); !errors.Is(err, errs.ErrInvalidApprovals) {
+		t.Errorf("threshold without approvers: got %v, expected invalid approvals", err)
+	}
+}
+
+// checkRejection checks the error is a policy violation holding the reason, or nil when the reason is empty
+func checkRejection(t *testing.T, err error, reason string) {
+	t.Helper()
+	if reason == "" {
+		if err != nil {
+			t.Fatalf("got %v, expected no violation", err)
+		}
+		return
+	}
+	if !errors.Is(err, errs.ErrPolicyViolation) || !strings.Contains(err.Error(), reason) {
+		t.Fatalf("got %v, expected a violation of the %s", err, reason)
+	}
+}
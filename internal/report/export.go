@@ -0,0 +1,181 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	CSVFormat      = "csv"
+	JSONFormat     = "json"
+	MarkdownFormat = "markdown"
+)
+
+// Formats supported export formats
+var Formats = map[string]struct{}{
+	CSVFormat:      {},
+	JSONFormat:     {},
+	MarkdownFormat: {},
+}
+
+// spendRowView exported representation of a row, amounts are expressed in Ether
+type spendRowView struct {
+	Beneficiary      string `json:"beneficiary"`
+	Name             string `json:"name,omitempty"`
+	OpeningAllowance string `json:"opening_allowance"`
+	Grants           string `json:"grants"`
+	Reductions       string `json:"reductions"`
+	Payouts          string `json:"payouts"`
+	PayoutCount      int    `json:"payout_count"`
+	ClosingAllowance string `json:"closing_allowance"`
+}
+
+// inflowView exported representation of an inflow, amounts are expressed in Ether
+type inflowView struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name,omitempty"`
+	Amount string `json:"amount"`
+	Count  int    `json:"count"`
+}
+
+// spendReportView exported representation of the report
+type spendReportView struct {
+	Contract      string         `json:"contract"`
+	Since         time.Time      `json:"since"`
+	Until         time.Time      `json:"until"`
+	Beneficiaries []spendRowView `json:"beneficiaries"`
+	Inflows       []inflowView   `json:"inflows"`
+	TotalInflows  string         `json:"total_inflows"`
+}
+
+// Write exports the report in the given format
+func (r *SpendReport) Write(w io.Writer, format string) error {
+	switch format {
+	case CSVFormat:
+		return r.WriteCSV(w)
+	case JSONFormat:
+		return r.WriteJSON(w)
+	case MarkdownFormat:
+		return r.WriteMarkdown(w)
+	}
+	return fmt.Errorf("%w: %s", errs.ErrInvalidReportFormat, format)
+}
+
+// WriteCSV exports the beneficiary rows as CSV, amounts are expressed in Ether
+func (r *SpendReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{
+		"beneficiary", "name", "opening_allowance", "grants", "reductions", "payouts", "payout_count", "closing_allowance",
+	})
+	for _, row := range r.view().Beneficiaries {
+		_ = writer.Write([]string{
+			row.Beneficiary,
+			row.Name,
+			row.OpeningAllowance,
+			row.Grants,
+			row.Reductions,
+			row.Payouts,
+			strconv.Itoa(row.PayoutCount),
+			row.ClosingAllowance,
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// MarshalJSON encodes the report as exported, amounts are expressed in Ether. The global output formats encode it so
+func (r *SpendReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.view())
+}
+
+// WriteJSON exports the report as JSON, amounts are expressed in Ether
+func (r *SpendReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.view())
+}
+
+// WriteMarkdown exports the report as a Markdown statement, amounts are expressed in Ether
+func (r *SpendReport) WriteMarkdown(w io.Writer) error {
+	view := r.view()
+
+	fmt.Fprintf(w, "# Spending statement\n\n")
+	fmt.Fprintf(w, "- Contract: `%s`\n", view.Contract)
+	fmt.Fprintf(w, "- Period: %s to %s\n\n", formatDate(view.Since), formatDate(view.Until))
+
+	fmt.Fprintf(w, "## Beneficiaries\n\n")
+	fmt.Fprintf(w, "| Beneficiary | Name | Opening allowance | Grants | Reductions | Payouts | # | Closing allowance |\n")
+	fmt.Fprintf(w, "|---|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, row := range view.Beneficiaries {
+		fmt.Fprintf(w, "| `%s` | %s | %s | %s | %s | %s | %d | %s |\n",
+			row.Beneficiary,
+			row.Name,
+			row.OpeningAllowance,
+			row.Grants,
+			row.Reductions,
+			row.Payouts,
+			row.PayoutCount,
+			row.ClosingAllowance,
+		)
+	}
+
+	fmt.Fprintf(w, "\n## Inflows\n\n")
+	fmt.Fprintf(w, "| Sender | Name | Amount | # |\n")
+	fmt.Fprintf(w, "|---|---|---:|---:|\n")
+	for _, inflow := range view.Inflows {
+		fmt.Fprintf(w, "| `%s` | %s | %s | %d |\n", inflow.Sender, inflow.Name, inflow.Amount, inflow.Count)
+	}
+	_, err := fmt.Fprintf(w, "\nTotal inflows: %s ETH\n", view.TotalInflows)
+
+	return err
+}
+
+func (r *SpendReport) view() spendReportView {
+	view := spendReportView{
+		Contract:      r.Contract,
+		Since:         r.Since,
+		Until:         r.Until,
+		Beneficiaries: make([]spendRowView, 0, len(r.Rows)),
+		Inflows:       make([]inflowView, 0, len(r.Inflows)),
+		TotalInflows:  common.FormatEther(r.TotalInflows),
+	}
+
+	for _, row := range r.Rows {
+		view.Beneficiaries = append(view.Beneficiaries, spendRowView{
+			Beneficiary:      row.Beneficiary,
+			Name:             row.Name,
+			OpeningAllowance: common.FormatEther(row.OpeningAllowance),
+			Grants:           common.FormatEther(row.Grants),
+			Reductions:       common.FormatEther(row.Reductions),
+			Payouts:          common.FormatEther(row.Payouts),
+			PayoutCount:      row.PayoutCount,
+			ClosingAllowance: common.FormatEther(row.ClosingAllowance),
+		})
+	}
+
+	for _, inflow := range r.Inflows {
+		view.Inflows = append(view.Inflows, inflowView{
+			Sender: inflow.Sender,
+			Name:   inflow.Name,
+			Amount: common.FormatEther(inflow.Amount),
+			Count:  inflow.Count,
+		})
+	}
+
+	return view
+}
+
+// formatDate format a period boundary, the zero time means unbounded
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
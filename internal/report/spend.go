@@ -0,0 +1,159 @@
+package report
+
+import (
+	"context"
+	"github.com/maxipaz/wallet/internal/store"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SpendRow allowance and payouts of a beneficiary during the report period, amounts are expressed in Wei.
+// ClosingAllowance always equals OpeningAllowance + Grants - Reductions - Payouts
+type SpendRow struct {
+	Beneficiary string
+	// Name contact name of the beneficiary in the address book, empty when unknown
+	Name             string
+	OpeningAllowance *big.Int
+	Grants           *big.Int
+	Reductions       *big.Int
+	Payouts          *big.Int
+	PayoutCount      int
+	ClosingAllowance *big.Int
+}
+
+// Inflow funds received by the contract from a sender during the report period, amounts are expressed in Wei
+type Inflow struct {
+	Sender string
+	Name   string
+	Amount *big.Int
+	Count  int
+}
+
+// SpendReport per beneficiary spending report
+type SpendReport struct {
+	Contract     string
+	Since        time.Time
+	Until        time.Time
+	Rows         []SpendRow
+	Inflows      []Inflow
+	TotalInflows *big.Int
+}
+
+// Spend builds the spending report of a contract for the period [since, until) from the recorded events
+func Spend(ctx context.Context, st *store.Store, contract string, since time.Time, until time.Time) (*SpendReport, error) {
+	events, err := st.QueryEvents(ctx, store.EventFilter{
+		Contract: contract,
+		Types:    []string{store.AllowanceChangedEvent, store.MoneySentEvent, store.MoneyReceivedEvent},
+		Until:    until,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contacts, err := st.ListContacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(contacts))
+	for _, contact := range contacts {
+		names[strings.ToLower(contact.Address)] = contact.Name
+	}
+
+	// the allowance reduction performed by sendMoney is emitted in the same transaction as the MoneySent event
+	payoutTxs := make(map[string]struct{})
+	for _, event := range events {
+		if event.Type == store.MoneySentEvent {
+			payoutTxs[event.TxHash+event.Address] = struct{}{}
+		}
+	}
+
+	report := &SpendReport{
+		Contract:     contract,
+		Since:        since,
+		Until:        until,
+		TotalInflows: new(big.Int),
+	}
+	rows := make(map[string]*SpendRow)
+	inflows := make(map[string]*Inflow)
+
+	for _, event := range events {
+		inPeriod := !event.Timestamp.Before(since)
+
+		switch event.Type {
+		case store.AllowanceChangedEvent:
+			row := spendRow(rows, event.Address)
+			if !inPeriod {
+				row.OpeningAllowance = event.Amount
+				row.ClosingAllowance = event.Amount
+				continue
+			}
+
+			row.ClosingAllowance = event.Amount
+			delta := new(big.Int).Sub(event.Amount, event.PrevAmount)
+			switch {
+			case delta.Sign() > 0:
+				row.Grants.Add(row.Grants, delta)
+			case delta.Sign() < 0:
+				if _, ok := payoutTxs[event.TxHash+event.Address]; !ok {
+					row.Reductions.Sub(row.Reductions, delta)
+				}
+			}
+		case store.MoneySentEvent:
+			if !inPeriod {
+				continue
+			}
+			row := spendRow(rows, event.Address)
+			row.Payouts.Add(row.Payouts, event.Amount)
+			row.PayoutCount++
+		case store.MoneyReceivedEvent:
+			if !inPeriod {
+				continue
+			}
+			inflow, ok := inflows[event.Address]
+			if !ok {
+				inflow = &Inflow{Sender: event.Address, Amount: new(big.Int)}
+				inflows[event.Address] = inflow
+			}
+			inflow.Amount.Add(inflow.Amount, event.Amount)
+			inflow.Count++
+			report.TotalInflows.Add(report.TotalInflows, event.Amount)
+		}
+	}
+
+	for _, row := range rows {
+		row.Name = names[strings.ToLower(row.Beneficiary)]
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		return report.Rows[i].Beneficiary < report.Rows[j].Beneficiary
+	})
+
+	for _, inflow := range inflows {
+		inflow.Name = names[strings.ToLower(inflow.Sender)]
+		report.Inflows = append(report.Inflows, *inflow)
+	}
+	sort.Slice(report.Inflows, func(i, j int) bool {
+		return report.Inflows[i].Sender < report.Inflows[j].Sender
+	})
+
+	return report, nil
+}
+
+// spendRow returns the row of a beneficiary, creating it when needed
+func spendRow(rows map[string]*SpendRow, beneficiary string) *SpendRow {
+	row, ok := rows[beneficiary]
+	if !ok {
+		row = &SpendRow{
+			Beneficiary:      beneficiary,
+			OpeningAllowance: new(big.Int),
+			Grants:           new(big.Int),
+			Reductions:       new(big.Int),
+			Payouts:          new(big.Int),
+			ClosingAllowance: new(big.Int),
+		}
+		rows[beneficiary] = row
+	}
+	return row
+}
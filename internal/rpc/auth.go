@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxipaz/wallet/internal/auth"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/wallet"
+	walletv1 "github.com/maxipaz/wallet/proto/wallet/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodOperations operation of each service method, used to authorize the calls
+var methodOperations = map[string]string{
+	walletv1.WalletService_GetAllowance_FullMethodName:       auth.GetAllowanceOperation,
+	walletv1.WalletService_SetAllowance_FullMethodName:       auth.ChangeAllowanceOperation,
+	walletv1.WalletService_IncreaseAllowance_FullMethodName:  auth.ChangeAllowanceOperation,
+	walletv1.WalletService_ReduceAllowance_FullMethodName:    auth.ChangeAllowanceOperation,
+	walletv1.WalletService_GetContractBalance_FullMethodName: auth.GetBalanceOperation,
+	walletv1.WalletService_GetAddressBalance_FullMethodName:  auth.GetBalanceOperation,
+	walletv1.WalletService_GetOwner_FullMethodName:           auth.GetOwnerOperation,
+	walletv1.WalletService_TransferOwnership_FullMethodName:  auth.TransferOwnershipOperation,
+	walletv1.WalletService_Send_FullMethodName:               auth.SendOperation,
+	walletv1.WalletService_Receive_FullMethodName:            auth.ReceiveOperation,
+	walletv1.WalletService_WatchEvents_FullMethodName:        auth.WatchEventsOperation,
+}
+
+// unaryAuthorize rejects the unary calls whose credentials are not granted the method, before it gets the signer
+func (s *Server) unaryAuthorize(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	principal, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(wallet.WithRequester(ctx, "grpc:"+principal.Name), request)
+}
+
+// streamAuthorize rejects the streams whose credentials are not granted the method
+func (s *Server) streamAuthorize(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, err := s.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(server, stream)
+}
+
+// authorize reads the credential from the authorization or x-api-key metadata and checks it is granted the method
+func (s *Server) authorize(ctx context.Context, method string) (*auth.Principal, error) {
+	operation, ok := methodOperations[method]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("%s: unknown method %s", errs.ErrPermissionDenied, method))
+	}
+
+	var authorization, apiKey string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+		if values := md.Get("x-api-key"); len(values) > 0 {
+			apiKey = values[0]
+		}
+	}
+
+	principal, err := s.auth.Authorize(ctx, auth.Credential(authorization, apiKey), operation)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return principal, nil
+}
@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/core/types"
+	contracts "github.com/maxipaz/wallet/contracts/interfaces"
+	walletv1 "github.com/maxipaz/wallet/proto/wallet/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+// subscriberBuffer events buffered per WatchEvents stream, a stream falling further behind is closed
+const subscriberBuffer = 256
+
+// subscriber WatchEvents stream registered to the events broadcast
+type subscriber struct {
+	events chan *walletv1.Event
+	types  map[walletv1.EventType]struct{}
+}
+
+// accepts reports whether the subscriber filters include the event type
+func (s *subscriber) accepts(eventType walletv1.EventType) bool {
+	if len(s.types) == 0 {
+		return true
+	}
+	_, ok := s.types[eventType]
+	return ok
+}
+
+// WatchEvents streams the contract events received by the monitor until the client cancels the call
+func (s *Server) WatchEvents(request *walletv1.WatchEventsRequest, stream walletv1.WalletService_WatchEventsServer) error {
+	sub := &subscriber{
+		events: make(chan *walletv1.Event, subscriberBuffer),
+		types:  make(map[walletv1.EventType]struct{}, len(request.GetTypes())),
+	}
+	for _, eventType := range request.GetTypes() {
+		sub.types[eventType] = struct{}{}
+	}
+
+	s.subscribersMu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.subscribersMu.Unlock()
+	defer s.unsubscribe(sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "events stream fell behind")
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Handle broadcasts a contract event received by the monitor to the WatchEvents streams
+func (s *Server) Handle(ctx context.Context, event any) {
+	message := toEvent(event)
+	if message == nil {
+		return
+	}
+
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+
+	for sub := range s.subscribers {
+		if !sub.accepts(message.Type) {
+			continue
+		}
+
+		select {
+		case sub.events <- message:
+		default:
+			slog.WarnContext(ctx, "closing slow events stream", slog.Int("buffer", subscriberBuffer))
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// unsubscribe removes a subscriber from the events broadcast, unless it was already closed
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// toEvent converts a contract binding event, nil is returned for unknown events
+func toEvent(event any) *walletv1.Event {
+	switch event := event.(type) {
+	case *contracts.ContractAllowanceChanged:
+		message := newEvent(walletv1.EventType_EVENT_TYPE_ALLOWANCE_CHANGED, event.Raw)
+		message.Payload = &walletv1.Event_AllowanceChanged{AllowanceChanged: &walletv1.AllowanceChanged{
+			Sender:      event.Sender.Hex(),
+			Beneficiary: event.Beneficiary.Hex(),
+			PrevAmount:  event.PrevAmount.String(),
+			NewAmount:   event.NewAmount.String(),
+		}}
+		return message
+	case *contracts.ContractMoneySent:
+		message := newEvent(walletv1.EventType_EVENT_TYPE_MONEY_SENT, event.Raw)
+		message.Payload = &walletv1.Event_MoneySent{MoneySent: &walletv1.MoneySent{
+			Beneficiary: event.Beneficiary.Hex(),
+			Amount:      event.Amount.String(),
+		}}
+		return message
+	case *contracts.ContractMoneyReceived:
+		message := newEvent(walletv1.EventType_EVENT_TYPE_MONEY_RECEIVED, event.Raw)
+		message.Payload = &walletv1.Event_MoneyReceived{MoneyReceived: &walletv1.MoneyReceived{
+			Sender: event.From.Hex(),
+			Amount: event.Amount.String(),
+		}}
+		return message
+	case *contracts.ContractOwnershipTransferred:
+		message := newEvent(walletv1.EventType_EVENT_TYPE_OWNERSHIP_TRANSFERRED, event.Raw)
+		message.Payload = &walletv1.Event_OwnershipTransferred{OwnershipTransferred: &walletv1.OwnershipTransferred{
+			PreviousOwner: event.PreviousOwner.Hex(),
+			NewOwner:      event.NewOwner.Hex(),
+		}}
+		return message
+	}
+	return nil
+}
+
+func newEvent(eventType walletv1.EventType, log types.Log) *walletv1.Event {
+	return &walletv1.Event{
+		Type:        eventType,
+		BlockNumber: log.BlockNumber,
+		TxHash:      log.TxHash.Hex(),
+		LogIndex:    uint32(log.Index),
+		Removed:     log.Removed,
+	}
+}
@@ -0,0 +1,87 @@
+package rpc
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/internal/auth"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	walletv1 "github.com/maxipaz/wallet/proto/wallet/v1"
+	"google.golang.org/grpc"
+	"log/slog"
+	"net"
+	"sync"
+)
+
+// Server gRPC service exposing the wallet runners and streaming the events received by the monitor
+type Server struct {
+	walletv1.UnimplementedWalletServiceServer
+
+	client    *ethclient.Client
+	allowance *wallet.Allowance
+	balance   wallet.Balance
+	owner     wallet.Owner
+	transfers wallet.Transfers
+	auth      *auth.Authenticator
+
+	subscribersMu sync.Mutex
+	subscribers   map[*subscriber]struct{}
+}
+
+// New returns a new server instance
+func New(client *ethclient.Client, privateKey string, contractAddress string, authenticator *auth.Authenticator, st *store.Store, pol *policy.Policy) *Server {
+	s := &Server{
+		client:      client,
+		auth:        authenticator,
+		allowance:   wallet.NewAllowanceRunner(privateKey, contractAddress),
+		balance:     wallet.NewBalanceRunner(privateKey, contractAddress),
+		owner:       wallet.NewOwnerRunner(privateKey, contractAddress),
+		transfers:   wallet.NewTransfersRunner(privateKey, contractAddress),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+
+	s.allowance.SetPolicy(pol)
+	s.owner.SetPolicy(pol)
+	s.transfers.SetPolicy(pol)
+
+	// the store records the idempotency keys, the requests using them are rejected without it
+	if st != nil {
+		s.allowance.SetStore(st)
+		s.owner.SetStore(st)
+		s.transfers.SetStore(st)
+	}
+	return s
+}
+
+// Register registers the wallet service in a gRPC server
+func (s *Server) Register(server *grpc.Server) {
+	walletv1.RegisterWalletServiceServer(server, s)
+}
+
+// ServerOptions returns the options enforcing the authorization of the calls
+func (s *Server) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryAuthorize),
+		grpc.ChainStreamInterceptor(s.streamAuthorize),
+	}
+}
+
+// Serve serves the gRPC service on the given address until the context is done
+func (s *Server) Serve(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(s.ServerOptions()...)
+	s.Register(server)
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	slog.DebugContext(ctx, "serving gRPC", slog.String("address", address))
+	return server.Serve(listener)
+}
@@ -0,0 +1,289 @@
+package rpc
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/maxipaz/wallet/config"
+	contracts "github.com/maxipaz/wallet/contracts/interfaces"
+	"github.com/maxipaz/wallet/internal/auth"
+	"github.com/maxipaz/wallet/internal/deploy"
+	"github.com/maxipaz/wallet/internal/testchain"
+	walletv1 "github.com/maxipaz/wallet/proto/wallet/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+const (
+	adminKey  = "admin-key"
+	viewerKey = "viewer-key"
+)
+
+// startServer deploys the contract on a simulated chain and serves the gRPC service on an in-process listener
+func startServer(t *testing.T) (walletv1.WalletServiceClient, *Server, *testchain.Chain) {
+	t.Helper()
+
+	chain := testchain.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deployer := deploy.NewDeployer()
+	if err := deployer.Deploy(ctx, chain.Client); err != nil {
+		t.Fatal(err)
+	}
+	if err := deployer.Wait(ctx, chain.Client); err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator, err := auth.New(config.AuthConfig{APIKeys: []config.APIKey{
+		{Name: "admin", Key: adminKey, Roles: []string{auth.AdminRole}},
+		{Name: "viewer", Key: viewerKey, Roles: []string{auth.ViewerRole}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.App.Contract.Address
+	config.App.Contract.Address = deployer.ContractAddress()
+	t.Cleanup(func() { config.App.Contract.Address = previous })
+
+	service := New(chain.Client, config.App.Blockchain.PrivateKey, config.App.Contract.Address, authenticator, nil, nil)
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(service.ServerOptions()...)
+	service.Register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+	return walletv1.NewWalletServiceClient(conn), service, chain
+}
+
+// withKey returns a context sending the API key in the call metadata
+func withKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "x-api-key", key)
+}
+
+func TestUnaryCalls(t *testing.T) {
+	client, _, chain := startServer(t)
+	ctx, cancel := context.WithTimeout(withKey(context.Background(), adminKey), time.Minute)
+	defer cancel()
+
+	beneficiaryKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	beneficiary := crypto.PubkeyToAddress(beneficiaryKey.PublicKey).Hex()
+
+	owner, err := client.GetOwner(ctx, &walletv1.GetOwnerRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner.GetOwner() != chain.Address().Hex() {
+		t.Fatalf("owner is %s, expected %s", owner.GetOwner(), chain.Address().Hex())
+	}
+
+	if _, err := client.Receive(ctx, &walletv1.ReceiveRequest{Amount: 10}); err != nil {
+		t.Fatal(err)
+	}
+	contractBalance, err := client.GetContractBalance(ctx, &walletv1.GetContractBalanceRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contractBalance.GetBalance() != 10 {
+		t.Fatalf("contract balance is %d, expected 10", contractBalance.GetBalance())
+	}
+
+	changes := []struct {
+		change func(context.Context, *walletv1.ChangeAllowanceRequest, ...grpc.CallOption) (*walletv1.TransactionResult, error)
+		amount int64
+		want   int64
+	}{
+		{client.SetAllowance, 5, 5},
+		{client.IncreaseAllowance, 2, 7},
+		{client.ReduceAllowance, 3, 4},
+	}
+	for _, c := range changes {
+		result, err := c.change(ctx, &walletv1.ChangeAllowanceRequest{Address: beneficiary, Amount: c.amount})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.GetStatus() != types.ReceiptStatusSuccessful {
+			t.Fatalf("%s status is %d", result.GetOperation(), result.GetStatus())
+		}
+
+		allowance, err := client.GetAllowance(ctx, &walletv1.GetAllowanceRequest{Address: beneficiary})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowance.GetAllowance() != c.want {
+			t.Fatalf("allowance after %s is %d, expected %d", result.GetOperation(), allowance.GetAllowance(), c.want)
+		}
+	}
+
+	if _, err := client.Send(ctx, &walletv1.SendRequest{Address: beneficiary, Amount: 3}); err != nil {
+		t.Fatal(err)
+	}
+	balance, err := client.GetAddressBalance(ctx, &walletv1.GetAddressBalanceRequest{Address: beneficiary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.GetBalance() != 3 {
+		t.Fatalf("beneficiary balance is %d, expected 3", balance.GetBalance())
+	}
+
+	if _, err := client.Send(ctx, &walletv1.SendRequest{Address: beneficiary, Amount: 0}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("payout of 0 ether returned %v, expected InvalidArgument", err)
+	}
+
+	for _, address := range []string{"0x0000000000000000000000000000000000000000", config.App.Contract.Address} {
+		if _, err := client.TransferOwnership(ctx, &walletv1.TransferOwnershipRequest{Address: address}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("ownership transfer to %s returned %v, expected InvalidArgument", address, err)
+		}
+	}
+	if _, err := client.TransferOwnership(ctx, &walletv1.TransferOwnershipRequest{Address: beneficiary}); err != nil {
+		t.Fatal(err)
+	}
+	owner, err = client.GetOwner(ctx, &walletv1.GetOwnerRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner.GetOwner() != beneficiary {
+		t.Fatalf("owner is %s, expected %s", owner.GetOwner(), beneficiary)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	client, _, chain := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call func(context.Context) error
+		want codes.Code
+	}{
+		{
+			name: "missing credential",
+			ctx:  ctx,
+			call: func(ctx context.Context) error {
+				_, err := client.GetOwner(ctx, &walletv1.GetOwnerRequest{})
+				return err
+			},
+			want: codes.Unauthenticated,
+		},
+		{
+			name: "unknown key",
+			ctx:  withKey(ctx, "unknown-key"),
+			call: func(ctx context.Context) error {
+				_, err := client.GetOwner(ctx, &walletv1.GetOwnerRequest{})
+				return err
+			},
+			want: codes.Unauthenticated,
+		},
+		{
+			name: "viewer sending",
+			ctx:  withKey(ctx, viewerKey),
+			call: func(ctx context.Context) error {
+				_, err := client.Send(ctx, &walletv1.SendRequest{Address: chain.Address().Hex(), Amount: 1})
+				return err
+			},
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "viewer reading",
+			ctx:  withKey(ctx, viewerKey),
+			call: func(ctx context.Context) error {
+				_, err := client.GetOwner(ctx, &walletv1.GetOwnerRequest{})
+				return err
+			},
+			want: codes.OK,
+		},
+		{
+			name: "unauthenticated stream",
+			ctx:  ctx,
+			call: func(ctx context.Context) error {
+				stream, err := client.WatchEvents(ctx, &walletv1.WatchEventsRequest{})
+				if err != nil {
+					return err
+				}
+				_, err = stream.Recv()
+				return err
+			},
+			want: codes.Unauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call(tt.ctx)); code != tt.want {
+				t.Fatalf("got %s, expected %s", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	client, service, chain := startServer(t)
+	ctx, cancel := context.WithTimeout(withKey(context.Background(), viewerKey), time.Minute)
+	defer cancel()
+
+	stream, err := client.WatchEvents(ctx, &walletv1.WatchEventsRequest{
+		Types: []walletv1.EventType{walletv1.EventType_EVENT_TYPE_MONEY_SENT},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the subscription is registered once the stream reached the handler
+	for {
+		service.subscribersMu.Lock()
+		subscribed := len(service.subscribers) > 0
+		service.subscribersMu.Unlock()
+		if subscribed {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("the stream was never subscribed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	beneficiary := chain.Address()
+	// filtered out by the stream types
+	service.Handle(ctx, &contracts.ContractMoneyReceived{From: beneficiary, Amount: big.NewInt(1), Raw: types.Log{BlockNumber: 1}})
+	service.Handle(ctx, &contracts.ContractMoneySent{Beneficiary: beneficiary, Amount: big.NewInt(2), Raw: types.Log{BlockNumber: 2}})
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GetType() != walletv1.EventType_EVENT_TYPE_MONEY_SENT || event.GetBlockNumber() != 2 {
+		t.Fatalf("got %s event of block %d, expected the money sent event of block 2", event.GetType(), event.GetBlockNumber())
+	}
+	if sent := event.GetMoneySent(); sent.GetBeneficiary() != beneficiary.Hex() || sent.GetAmount() != "2" {
+		t.Fatalf("got payout of %s to %s, expected 2 to %s", sent.GetAmount(), sent.GetBeneficiary(), beneficiary.Hex())
+	}
+}
@@ -0,0 +1,199 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/wallet"
+	walletv1 "github.com/maxipaz/wallet/proto/wallet/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+// GetAllowance returns the allowance of a beneficiary
+func (s *Server) GetAllowance(ctx context.Context, request *walletv1.GetAllowanceRequest) (*walletv1.GetAllowanceResponse, error) {
+	if err := common.ValidateAddress(request.GetAddress()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	allowance, err := s.allowance.GetAllowance(ctx, s.client, request.GetAddress())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return &walletv1.GetAllowanceResponse{Address: request.GetAddress(), Allowance: allowance}, nil
+}
+
+// SetAllowance sets the allowance of a beneficiary
+func (s *Server) SetAllowance(ctx context.Context, request *walletv1.ChangeAllowanceRequest) (*walletv1.TransactionResult, error) {
+	return s.changeAllowance(ctx, wallet.SetAction, request)
+}
+
+// IncreaseAllowance increases the allowance of a beneficiary
+func (s *Server) IncreaseAllowance(ctx context.Context, request *walletv1.ChangeAllowanceRequest) (*walletv1.TransactionResult, error) {
+	return s.changeAllowance(ctx, wallet.IncreaseAction, request)
+}
+
+// ReduceAllowance reduces the allowance of a beneficiary
+func (s *Server) ReduceAllowance(ctx context.Context, request *walletv1.ChangeAllowanceRequest) (*walletv1.TransactionResult, error) {
+	return s.changeAllowance(ctx, wallet.ReduceAction, request)
+}
+
+func (s *Server) changeAllowance(ctx context.Context, action string, request *walletv1.ChangeAllowanceRequest) (*walletv1.TransactionResult, error) {
+	if err := common.ValidateAddress(request.GetAddress()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	if request.GetAmount() <= 0 {
+		return nil, toStatus(ctx, errs.ErrInvalidAmountAction)
+	}
+
+	return s.transact(ctx, func(ctx context.Context) (*wallet.TransactionResult, error) {
+		return s.allowance.ChangeAllowance(ctx, s.client, action, request.GetAddress(), request.GetAmount())
+	})
+}
+
+// GetContractBalance returns the contract balance
+func (s *Server) GetContractBalance(ctx context.Context, _ *walletv1.GetContractBalanceRequest) (*walletv1.GetBalanceResponse, error) {
+	balance, err := s.balance.GetContractBalance(ctx, s.client)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return &walletv1.GetBalanceResponse{Address: wallet.ContractBalance, Balance: balance}, nil
+}
+
+// GetAddressBalance returns the balance of an address
+func (s *Server) GetAddressBalance(ctx context.Context, request *walletv1.GetAddressBalanceRequest) (*walletv1.GetBalanceResponse, error) {
+	if err := common.ValidateAddress(request.GetAddress()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	balance, err := s.balance.GetAddressBalance(ctx, s.client, request.GetAddress())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return &walletv1.GetBalanceResponse{Address: request.GetAddress(), Balance: balance}, nil
+}
+
+// GetOwner returns the contract owner
+func (s *Server) GetOwner(ctx context.Context, _ *walletv1.GetOwnerRequest) (*walletv1.GetOwnerResponse, error) {
+	owner, err := s.owner.GetOwner(ctx, s.client)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return &walletv1.GetOwnerResponse{Owner: owner}, nil
+}
+
+// TransferOwnership transfers the contract ownership
+func (s *Server) TransferOwnership(ctx context.Context, request *walletv1.TransferOwnershipRequest) (*walletv1.TransactionResult, error) {
+	if request.GetAddress() == "" {
+		return nil, toStatus(ctx, errs.ErrMissingTargetAddress)
+	}
+	if err := common.ValidateAddress(request.GetAddress()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return s.transact(ctx, func(ctx context.Context) (*wallet.TransactionResult, error) {
+		return s.owner.TransferOwner(ctx, s.client, request.GetAddress())
+	})
+}
+
+// Send sends money from the contract to a beneficiary
+func (s *Server) Send(ctx context.Context, request *walletv1.SendRequest) (*walletv1.TransactionResult, error) {
+	if request.GetAddress() == "" {
+		return nil, toStatus(ctx, errs.ErrMissingTargetAddress)
+	}
+	if err := common.ValidateAddress(request.GetAddress()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	if request.GetAmount() <= 0 {
+		return nil, toStatus(ctx, errs.ErrInvalidAmountAction)
+	}
+
+	return s.transact(ctx, func(ctx context.Context) (*wallet.TransactionResult, error) {
+		return s.transfers.Send(ctx, s.client, request.GetAddress(), request.GetAmount())
+	})
+}
+
+// Receive funds the contract from the configured account
+func (s *Server) Receive(ctx context.Context, request *walletv1.ReceiveRequest) (*walletv1.TransactionResult, error) {
+	if request.GetAmount() <= 0 {
+		return nil, toStatus(ctx, errs.ErrInvalidAmountAction)
+	}
+
+	return s.transact(ctx, func(ctx context.Context) (*wallet.TransactionResult, error) {
+		return s.transfers.Receive(ctx, s.client, request.GetAmount())
+	})
+}
+
+// transact runs an operation signing a transaction and converts its result. The idempotency key is read from the
+// idempotency-key metadata
+func (s *Server) transact(ctx context.Context, operation func(ctx context.Context) (*wallet.TransactionResult, error)) (*walletv1.TransactionResult, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("idempotency-key"); len(values) > 0 {
+			ctx = wallet.WithCallerIdempotencyKey(ctx, values[0])
+		}
+	}
+
+	result, err := operation(ctx)
+
+	if err != nil {
+		if result != nil {
+			err = fmt.Errorf("%w: %s", err, result.TxHash)
+		}
+		return nil, toStatus(ctx, err)
+	}
+
+	return &walletv1.TransactionResult{
+		Operation:   result.Operation,
+		TxHash:      result.TxHash,
+		BlockNumber: result.BlockNumber,
+		Status:      result.Status,
+		GasUsed:     result.GasUsed,
+	}, nil
+}
+
+// toStatus maps an error to its gRPC status from the kind given by errs.Classify
+func toStatus(ctx context.Context, err error) error {
+	var code codes.Code
+	switch {
+	case errors.Is(err, errs.ErrUnauthenticated):
+		code = codes.Unauthenticated
+	case errors.Is(err, errs.ErrPermissionDenied):
+		code = codes.PermissionDenied
+	case errs.IsNotFound(err), errors.Is(err, errs.ErrTransactionDropped):
+		code = codes.NotFound
+	case errors.Is(err, errs.ErrIdempotencyKeyInProgress):
+		code = codes.Aborted
+	case errs.IsConflict(err):
+		code = codes.AlreadyExists
+	case errs.IsInternal(err):
+		code = codes.Internal
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	default:
+		switch _, exit := errs.Classify(err); exit {
+		case errs.ExitValidation:
+			code = codes.InvalidArgument
+		case errs.ExitPolicy, errs.ExitReverted:
+			code = codes.FailedPrecondition
+		case errs.ExitRPC:
+			code = codes.Unavailable
+		default:
+			code = codes.Internal
+		}
+	}
+
+	if code == codes.Internal || code == codes.Unavailable {
+		slog.ErrorContext(ctx, "request failed", slog.String("error", err.Error()))
+	}
+	return status.Error(code, err.Error())
+}
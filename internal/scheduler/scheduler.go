@@ -0,0 +1,345 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/config"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"github.com/robfig/cron/v3"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lateness delay after the scheduled time from which a run is recorded as a catch up
+const lateness = time.Minute
+
+// actions allowance actions a schedule can apply
+var actions = map[string]struct{}{
+	wallet.SetAction:      {},
+	wallet.IncreaseAction: {},
+}
+
+// Schedule allowance action applied to a list of beneficiaries at the times of a cron expression
+type Schedule struct {
+	config.ScheduleConfig
+	schedule cron.Schedule
+}
+
+// Next returns the first scheduled time after the given one
+func (s *Schedule) Next(after time.Time) time.Time {
+	return s.schedule.Next(after)
+}
+
+// Scheduler runs the schedules at their scheduled times. Every occurrence is recorded in the store, so an occurrence
+// runs once and the occurrences missed while the scheduler was stopped are caught up once when it starts
+type Scheduler struct {
+	client    *ethclient.Client
+	allowance *wallet.Allowance
+	store     *store.Store
+	schedules []*Schedule
+
+	// mu serializes the runs, a manual run never overlaps a scheduled one
+	mu sync.Mutex
+}
+
+// NewScheduler returns a new scheduler instance
+func NewScheduler(client *ethclient.Client, privateKey string, contractAddress string, st *store.Store, pol *policy.Policy, cfg []config.ScheduleConfig) (*Scheduler, error) {
+	if st == nil {
+		return nil, errs.ErrScheduleStoreRequired
+	}
+
+	schedules, err := Parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	allowance := wallet.NewAllowanceRunner(privateKey, contractAddress)
+	allowance.SetStore(st)
+	allowance.SetPolicy(pol)
+
+	return &Scheduler{
+		client:    client,
+		allowance: allowance,
+		store:     st,
+		schedules: schedules,
+	}, nil
+}
+
+// Parse validates the configured schedules and parses their cron expressions
+func Parse(cfg []config.ScheduleConfig) ([]*Schedule, error) {
+	names := make(map[string]struct{}, len(cfg))
+	schedules := make([]*Schedule, 0, len(cfg))
+	for _, schedule := range cfg {
+		if schedule.Name == "" {
+			return nil, fmt.Errorf("%w: name is required", errs.ErrInvalidSchedule)
+		}
+		if _, ok := names[schedule.Name]; ok {
+			return nil, fmt.Errorf("%w: %s is duplicated", errs.ErrInvalidSchedule, schedule.Name)
+		}
+		names[schedule.Name] = struct{}{}
+
+		parsed, err := cron.ParseStandard(schedule.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s cron: %w", errs.ErrInvalidSchedule, schedule.Name, err)
+		}
+		if _, ok := actions[schedule.Action]; !ok {
+			return nil, fmt.Errorf("%w: %s action %q, use set or increase", errs.ErrInvalidSchedule, schedule.Name, schedule.Action)
+		}
+		if schedule.Amount <= 0 {
+			return nil, fmt.Errorf("%w: %s: %w", errs.ErrInvalidSchedule, schedule.Name, errs.ErrInvalidAmountAction)
+		}
+		if len(schedule.Beneficiaries) == 0 {
+			return nil, fmt.Errorf("%w: %s has no beneficiaries", errs.ErrInvalidSchedule, schedule.Name)
+		}
+		for _, beneficiary := range schedule.Beneficiaries {
+			if err := common.ValidateAddress(beneficiary); err != nil {
+				return nil, fmt.Errorf("%w: %s beneficiary %s: %w", errs.ErrInvalidSchedule, schedule.Name, beneficiary, err)
+			}
+		}
+
+		schedules = append(schedules, &Schedule{ScheduleConfig: schedule, schedule: parsed})
+	}
+	return schedules, nil
+}
+
+// Schedules returns the configured schedules
+func (s *Scheduler) Schedules() []*Schedule {
+	return s.schedules
+}
+
+// Start resumes the runs interrupted by a previous stop, catches up the missed occurrences and then runs the
+// schedules at their scheduled times until the context is done
+func (s *Scheduler) Start(ctx context.Context) error {
+	if len(s.schedules) == 0 {
+		return nil
+	}
+
+	if err := s.resume(ctx); err != nil {
+		return err
+	}
+
+	last := make(map[string]time.Time, len(s.schedules))
+	now := time.Now()
+	for _, schedule := range s.schedules {
+		registeredAt, err := s.store.RegisterSchedule(ctx, schedule.Name, now)
+		if err != nil {
+			return err
+		}
+		lastRun, err := s.store.LastScheduledRun(ctx, schedule.Name)
+		if err != nil {
+			return err
+		}
+
+		last[schedule.Name] = registeredAt
+		if lastRun.After(registeredAt) {
+			last[schedule.Name] = lastRun
+		}
+	}
+
+	for {
+		now := time.Now()
+		next := time.Time{}
+		for _, schedule := range s.schedules {
+			scheduledAt, missed := due(schedule, last[schedule.Name], now)
+			if !scheduledAt.IsZero() {
+				trigger := store.TriggerScheduled
+				if missed > 0 || now.Sub(scheduledAt) > lateness {
+					trigger = store.TriggerCatchUp
+				}
+				// the failures of the beneficiaries are logged and recorded by the run
+				if _, _, err := s.run(ctx, schedule, scheduledAt, trigger, missed); err != nil {
+					slog.ErrorContext(ctx, "failed to run schedule",
+						slog.String("schedule", schedule.Name),
+						slog.String("error", err.Error()),
+					)
+				}
+				last[schedule.Name] = scheduledAt
+			}
+
+			if scheduleNext := schedule.Next(last[schedule.Name]); next.IsZero() || scheduleNext.Before(next) {
+				next = scheduleNext
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// RunNow runs a schedule immediately, the run is recorded as manual and does not change the scheduled occurrences.
+// The manual runs are keyed apart from the occurrences, a run requested at the time of an occurrence does not take its
+// place nor its idempotency keys. A failed run is returned along the errors of its beneficiaries, joined, keeping their
+// classification
+func (s *Scheduler) RunNow(ctx context.Context, name string) (*store.ScheduleRun, error) {
+	schedule := s.schedule(name)
+	if schedule == nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrUnknownSchedule, name)
+	}
+
+	run, failures, err := s.run(ctx, schedule, time.Now().Truncate(time.Second), store.TriggerManual, 0)
+	if err != nil {
+		return nil, err
+	}
+	return run, failures
+}
+
+// due returns the latest occurrence after the last run up to now and the number of earlier occurrences it skips,
+// the scheduled time is zero when there is no occurrence due
+func due(schedule *Schedule, last time.Time, now time.Time) (time.Time, int) {
+	var (
+		scheduledAt time.Time
+		count       int
+	)
+	for next := schedule.Next(last); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		scheduledAt = next
+		count++
+	}
+	if count == 0 {
+		return time.Time{}, 0
+	}
+	return scheduledAt, count - 1
+}
+
+// resume runs again the runs left running by a previous stop. The idempotency keys of the beneficiaries prevent
+// applying twice the transactions already sent
+func (s *Scheduler) resume(ctx context.Context) error {
+	runs, err := s.store.ListScheduleRuns(ctx, store.ScheduleRunFilter{Statuses: []string{store.RunRunning}})
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		schedule := s.schedule(run.Schedule)
+		if schedule == nil {
+			slog.WarnContext(ctx, "interrupted run of an unknown schedule",
+				slog.String("schedule", run.Schedule),
+				slog.Time("scheduled_at", run.ScheduledAt),
+			)
+			continue
+		}
+
+		slog.InfoContext(ctx, "resuming interrupted schedule run",
+			slog.String("schedule", run.Schedule),
+			slog.Time("scheduled_at", run.ScheduledAt),
+		)
+		s.mu.Lock()
+		_, _, err := s.execute(ctx, schedule, run)
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run records and executes an occurrence of the schedule, an occurrence already recorded is not run again
+func (s *Scheduler) run(ctx context.Context, schedule *Schedule, scheduledAt time.Time, trigger string, missed int) (result *store.ScheduleRun, failures error, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := store.ScheduleRun{
+		Schedule:    schedule.Name,
+		ScheduledAt: scheduledAt,
+		Trigger:     trigger,
+		Missed:      missed,
+		StartedAt:   time.Now(),
+	}
+	started, err := s.store.StartScheduleRun(ctx, run)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !started {
+		slog.InfoContext(ctx, "schedule occurrence already run",
+			slog.String("schedule", schedule.Name),
+			slog.Time("scheduled_at", scheduledAt),
+		)
+		recorded, err := s.store.GetScheduleRun(ctx, schedule.Name, scheduledAt, run.Manual())
+		return recorded, nil, err
+	}
+
+	return s.execute(ctx, schedule, run)
+}
+
+// execute applies the schedule action to every beneficiary and records the outcome of the run. The failures of the
+// beneficiaries are returned apart from the errors interrupting the run
+func (s *Scheduler) execute(ctx context.Context, schedule *Schedule, run store.ScheduleRun) (result *store.ScheduleRun, failures error, err error) {
+	slog.InfoContext(ctx, "running schedule",
+		slog.String("schedule", schedule.Name),
+		slog.Time("scheduled_at", run.ScheduledAt),
+		slog.String("trigger", run.Trigger),
+		slog.Int("missed", run.Missed),
+	)
+
+	ctx = wallet.WithRequester(ctx, "schedule:"+schedule.Name)
+
+	var beneficiaryErrors []error
+	run.Applied, run.Failed = 0, 0
+	for _, beneficiary := range schedule.Beneficiaries {
+		key := fmt.Sprintf("schedule:%s:%d:%s", schedule.Name, run.ScheduledAt.Unix(), strings.ToLower(beneficiary))
+		if run.Manual() {
+			key = fmt.Sprintf("schedule:%s:manual:%d:%s", schedule.Name, run.ScheduledAt.Unix(), strings.ToLower(beneficiary))
+		}
+		result, err := s.allowance.ChangeAllowance(wallet.WithIdempotencyKey(ctx, key), s.client, schedule.Action, beneficiary, schedule.Amount)
+		if err != nil {
+			if ctx.Err() != nil {
+				// the run stays running and is resumed on the next start
+				return nil, nil, err
+			}
+
+			run.Failed++
+			beneficiaryErrors = append(beneficiaryErrors, fmt.Errorf("%s: %w", beneficiary, err))
+			slog.ErrorContext(ctx, "failed to apply schedule",
+				slog.String("schedule", schedule.Name),
+				slog.String("beneficiary", beneficiary),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		run.Applied++
+		slog.InfoContext(ctx, "schedule applied",
+			slog.String("schedule", schedule.Name),
+			slog.String("beneficiary", beneficiary),
+			slog.String("tx_hash", result.TxHash),
+		)
+	}
+
+	run.Status = store.RunSucceeded
+	if run.Failed > 0 {
+		run.Status = store.RunFailed
+		messages := make([]string, len(beneficiaryErrors))
+		for i, failure := range beneficiaryErrors {
+			messages[i] = failure.Error()
+		}
+		run.Error = strings.Join(messages, "; ")
+	}
+	if err := s.store.FinishScheduleRun(ctx, run); err != nil {
+		return nil, nil, err
+	}
+
+	run.FinishedAt = time.Now()
+	return &run, errors.Join(beneficiaryErrors...), nil
+}
+
+// schedule returns the schedule with the given name, nil when it is not configured
+func (s *Scheduler) schedule(name string) *Schedule {
+	for _, schedule := range s.schedules {
+		if schedule.Name == name {
+			return schedule
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Secret reference schemes
+const (
+	FileScheme  = "file"
+	EnvScheme   = "env"
+	ExecScheme  = "exec"
+	HTTPScheme  = "http"
+	HTTPSScheme = "https"
+)
+
+// maxSecretSize maximum size of a secret read from a file, a command or an endpoint
+const maxSecretSize = 64 << 10
+
+// Options settings of the providers
+type Options struct {
+	// HTTPToken bearer token sent to the HTTP secrets endpoints
+	HTTPToken string
+	// Timeout maximum time of a command or of an HTTP request
+	Timeout time.Duration
+}
+
+// Scheme returns the scheme of a secret reference, empty when the value is the secret itself
+func Scheme(value string) string {
+	scheme, _, ok := strings.Cut(value, ":")
+	if !ok {
+		return ""
+	}
+	switch scheme {
+	case FileScheme, EnvScheme, ExecScheme:
+		return scheme
+	case HTTPScheme, HTTPSScheme:
+		if strings.HasPrefix(value, scheme+"://") {
+			return scheme
+		}
+	}
+	return ""
+}
+
+// Resolve returns the secret of a reference: file:<path> reads a file, env:<name> an environment variable,
+// exec:<command> the output of a command and an http(s) URL the response of a secrets endpoint. The values without
+// a known scheme are the secret itself. The errors never hold the secret
+func Resolve(ctx context.Context, value string, opts Options) (string, error) {
+	var (
+		secret string
+		err    error
+	)
+	switch Scheme(value) {
+	case "":
+		return value, nil
+	case FileScheme:
+		secret, err = readFile(strings.TrimPrefix(value, FileScheme+":"))
+	case EnvScheme:
+		secret, err = readEnv(strings.TrimPrefix(value, EnvScheme+":"))
+	case ExecScheme:
+		secret, err = run(ctx, strings.TrimPrefix(value, ExecScheme+":"), opts.Timeout)
+	default:
+		secret, err = fetch(ctx, value, opts)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errs.ErrSecretUnavailable, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("%w: %s secret is empty", errs.ErrSecretUnavailable, Scheme(value))
+	}
+	return secret, nil
+}
+
+// readFile reads a secret file, the surrounding spaces and line breaks are removed
+func readFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxSecretSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readEnv reads a secret environment variable
+func readEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// run runs a command without shell, i.e.: a password manager CLI, and returns its standard output. Its standard error
+// is not reported, it could hold the secret
+func run(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("exec secret has no command")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("secret command %s failed: %w", args[0], err)
+	}
+	if stdout.Len() > maxSecretSize {
+		return "", fmt.Errorf("secret command %s output exceeds %d bytes", args[0], maxSecretSize)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// splitCommand splits a command in arguments as a shell does, without expanding anything: the single quotes keep
+// their content as is, the double quotes and the backslash escape the spaces, i.e.: op read "op://vault/My Item/pk"
+func splitCommand(command string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		// inArg the current argument was started, it may be an empty quoted string
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range command {
+		switch {
+		case escaped:
+			// in double quotes the backslash only escapes the quote and itself
+			if quote == '"' && r != '"' && r != '\\' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("exec secret has an unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// fetch requests a secret from an HTTP endpoint. The response is either the secret itself or a JSON object with the
+// secret in its value field. The bearer token is only sent over https, plain http is accepted for the loopback hosts
+func fetch(ctx context.Context, endpoint string, opts Options) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid secrets endpoint: %w", err)
+	}
+	if request.URL.Scheme != HTTPSScheme && !isLoopback(request.URL.Hostname()) {
+		return "", fmt.Errorf("secrets endpoint %s must use https, plain http is only accepted for loopback hosts", request.URL.Host)
+	}
+	if opts.HTTPToken != "" {
+		request.Header.Set("Authorization", "Bearer "+opts.HTTPToken)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to request secret: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secrets endpoint %s answered %s", request.URL.Host, response.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxSecretSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+
+	var body struct {
+		Value *string `json:"value"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Value != nil {
+		return strings.TrimSpace(*body.Value), nil
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// isLoopback reports whether a host is the local host
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,192 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var opts = Options{Timeout: 5 * time.Second}
+
+func TestScheme(t *testing.T) {
+	tests := map[string]string{
+		"0123abcd":                    "",
+		"file:/run/secrets/pk":        FileScheme,
+		"env:WALLET_PK":               EnvScheme,
+		"exec:pass show wallet":       ExecScheme,
+		"https://vault.example.com/x": HTTPSScheme,
+		"http://127.0.0.1:8200/pk":    HTTPScheme,
+		"http:not-an-url":             "",
+		"vault:pk":                    "",
+	}
+	for value, want := range tests {
+		if got := Scheme(value); got != want {
+			t.Errorf("Scheme(%q) = %q, expected %q", value, got, want)
+		}
+	}
+}
+
+func TestResolveLiteral(t *testing.T) {
+	secret, err := Resolve(context.Background(), "0123abcd", opts)
+	if err != nil || secret != "0123abcd" {
+		t.Fatalf("got %q, %v, expected the value itself", secret, err)
+	}
+}
+
+func TestResolveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pk")
+	if err := os.WriteFile(path, []byte("  file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := Resolve(context.Background(), "file:"+path, opts)
+	if err != nil || secret != "file-secret" {
+		t.Fatalf("got %q, %v, expected the trimmed file content", secret, err)
+	}
+
+	_, err = Resolve(context.Background(), "file:"+filepath.Join(t.TempDir(), "missing"), opts)
+	if !errors.Is(err, errs.ErrSecretUnavailable) {
+		t.Fatalf("missing file returned %v, expected a secret unavailable error", err)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Resolve(context.Background(), "file:"+empty, opts); !errors.Is(err, errs.ErrSecretUnavailable) {
+		t.Fatalf("empty file returned %v, expected a secret unavailable error", err)
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	t.Setenv("WALLET_TEST_SECRET", "env-secret")
+
+	secret, err := Resolve(context.Background(), "env:WALLET_TEST_SECRET", opts)
+	if err != nil || secret != "env-secret" {
+		t.Fatalf("got %q, %v, expected the variable value", secret, err)
+	}
+
+	_, err = Resolve(context.Background(), "env:WALLET_TEST_UNSET", opts)
+	if !errors.Is(err, errs.ErrSecretUnavailable) || !strings.Contains(err.Error(), "WALLET_TEST_UNSET") {
+		t.Fatalf("unset variable returned %v, expected a secret unavailable error naming it", err)
+	}
+}
+
+func TestResolveExec(t *testing.T) {
+	secret, err := Resolve(context.Background(), `exec:echo "exec  secret"`, opts)
+	if err != nil || secret != "exec  secret" {
+		t.Fatalf("got %q, %v, expected the quoted argument as printed", secret, err)
+	}
+
+	_, err = Resolve(context.Background(), "exec:false", opts)
+	if !errors.Is(err, errs.ErrSecretUnavailable) {
+		t.Fatalf("failing command returned %v, expected a secret unavailable error", err)
+	}
+
+	_, err = Resolve(context.Background(), "exec:sleep 5", Options{Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, errs.ErrSecretUnavailable) {
+		t.Fatalf("slow command returned %v, expected a secret unavailable error", err)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"pass show wallet/pk", []string{"pass", "show", "wallet/pk"}},
+		{`op read "op://vault/My Item/pk"`, []string{"op", "read", "op://vault/My Item/pk"}},
+		{`vault kv get -field='private key' secret/wallet`, []string{"vault", "kv", "get", "-field=private key", "secret/wallet"}},
+		{`cat My\ Secret.txt`, []string{"cat", "My Secret.txt"}},
+		{`echo "a \"quoted\" \n" ''`, []string{"echo", `a "quoted" \n`, ""}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		if err != nil {
+			t.Errorf("splitCommand(%q) failed: %v", tt.command, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, expected %q", tt.command, got, tt.want)
+		}
+	}
+
+	for _, command := range []string{`op read "op://vault`, `echo 'open`, `echo trailing\`} {
+		if _, err := splitCommand(command); err == nil {
+			t.Errorf("splitCommand(%q) succeeded, expected an unterminated quote error", command)
+		}
+	}
+}
+
+func TestStandIn(t *testing.T) {
+	server := httptest.NewServer(StandIn(map[string]string{"pk": "http-secret"}, "token"))
+	defer server.Close()
+
+	secret, err := Resolve(context.Background(), server.URL+"/pk", Options{HTTPToken: "token", Timeout: opts.Timeout})
+	if err != nil || secret != "http-secret" {
+		t.Fatalf("got %q, %v, expected the served secret", secret, err)
+	}
+
+	tests := []struct {
+		name  string
+		url   string
+		token string
+		want  string
+	}{
+		{"wrong token", server.URL + "/pk", "other", "401"},
+		{"missing token", server.URL + "/pk", "", "401"},
+		{"unknown secret", server.URL + "/missing", "token", "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Resolve(context.Background(), tt.url, Options{HTTPToken: tt.token, Timeout: opts.Timeout})
+			if !errors.Is(err, errs.ErrSecretUnavailable) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, expected a secret unavailable error with status %s", err, tt.want)
+			}
+		})
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/pk", nil)
+	request.Header.Set("Authorization", "Bearer token")
+	recorder := httptest.NewRecorder()
+	StandIn(map[string]string{"pk": "http-secret"}, "token").ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST answered %d, expected %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestFetchRequiresHTTPS(t *testing.T) {
+	var requested bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	// the test server listens on the loopback interface, it is reached through a non loopback name
+	url := strings.Replace(server.URL, "127.0.0.1", "wallet.invalid", 1)
+	_, err := Resolve(context.Background(), url+"/pk", Options{HTTPToken: "token", Timeout: opts.Timeout})
+	if !errors.Is(err, errs.ErrSecretUnavailable) || !strings.Contains(err.Error(), "https") {
+		t.Fatalf("got %v, expected the plain http endpoint to be refused", err)
+	}
+	if requested {
+		t.Fatal("the token was sent over plain http")
+	}
+
+	for _, host := range []string{"localhost", "127.0.0.1", "::1", "127.0.0.2"} {
+		if !isLoopback(host) {
+			t.Errorf("%s is not a loopback host", host)
+		}
+	}
+	if isLoopback("10.0.0.1") {
+		t.Error("10.0.0.1 is a loopback host")
+	}
+}
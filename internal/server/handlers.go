@@ -0,0 +1,351 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"log/slog"
+	"net/http"
+)
+
+// maxBodySize maximum size of a request body
+const maxBodySize = 1 << 20
+
+var allowanceActions = map[string]struct{}{
+	wallet.SetAction:      {},
+	wallet.IncreaseAction: {},
+	wallet.ReduceAction:   {},
+}
+
+// amountRequest body of the requests changing an allowance or receiving funds
+type amountRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+// transferRequest body of the requests sending funds or transferring the ownership
+type transferRequest struct {
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+}
+
+// allowanceResponse struct
+type allowanceResponse struct {
+	Address   string `json:"address"`
+	Allowance int64  `json:"allowance"`
+}
+
+// balanceResponse struct
+type balanceResponse struct {
+	Address string `json:"address"`
+	Balance int64  `json:"balance"`
+}
+
+// ownerResponse struct
+type ownerResponse struct {
+	Owner string `json:"owner"`
+}
+
+// spendRequestSubmission body of the requests submitting a spend request signed by the beneficiary
+type spendRequestSubmission struct {
+	Beneficiary string `json:"beneficiary"`
+	Amount      int64  `json:"amount"`
+	Reason      string `json:"reason"`
+	Reference   string `json:"reference"`
+	Signature   string `json:"signature"`
+}
+
+// errorResponse struct
+type errorResponse struct {
+	Error  string                    `json:"error"`
+	Result *wallet.TransactionResult `json:"result,omitempty"`
+}
+
+func (s *Server) getAllowance(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if err := common.ValidateAddress(address); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	allowance, err := s.allowance.GetAllowance(r.Context(), s.client, address)
+	if err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, allowanceResponse{Address: address, Allowance: allowance})
+}
+
+func (s *Server) changeAllowance(w http.ResponseWriter, r *http.Request) {
+	address, action := r.PathValue("address"), r.PathValue("action")
+	if _, ok := allowanceActions[action]; !ok {
+		writeError(w, r, errs.ErrInvalidAllowanceAction, nil)
+		return
+	}
+	if err := common.ValidateAddress(address); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	var request amountRequest
+	if err := readJSON(r, &request); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+	if request.Amount <= 0 {
+		writeError(w, r, errs.ErrInvalidAmountAction, nil)
+		return
+	}
+
+	s.transact(w, r, func(ctx context.Context) (*wallet.TransactionResult, error) {
+		return s.allowance.ChangeAllowance(ctx, s.client, action, address, request.Amount)
+	})
+}
+
+func (s *Server) getContractBalance(w http.ResponseWriter, r *http.Request) {
+	balance, err := s.balance.GetContractBalance(r.Context(), s.client)
+	if err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, balanceResponse{Address: "contract", Balance: balance})
+}
+
+func (s *Server) getAddressBalance(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if err := common.ValidateAddress(address); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	balance, err := s.balance.GetAddressBalance(r.Context(), s.client, address)
+	if err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, balanceResponse{Address: address, Balance: balance})
+}
+
+func (s *Server) getOwner(w http.ResponseWriter, r *http.Request) {
+	owner, err := s.owner.GetOwner(r.Context(), s.client)
+	if err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ownerResponse{Owner: owner})
+}
+
+func (s *Server) transferOwnership(w http.ResponseWriter, r *http.Request) {
+	var request transferRequest
+	if err := readJSON(r, &request); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+	if request.Address == "" {
+		writeError(w, r, errs.ErrMissingTargetAddress, nil)
+		return
+	}
+	if err := common.ValidateAddress(request.Address); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	s.transact(w, r, func(ctx context.Context) (*wallet.TransactionResult, error) {
+		return s.owner.TransferOwner(ctx, s.client, request.Address)
+	})
+}
+
+func (s *Server) send(w http.ResponseWriter, r *http.Request) {
+	var request transferRequest
+	if err := readJSON(r, &request); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+	if request.Address == "" {
+		writeError(w, r, errs.ErrMissingTargetAddress, nil)
+		return
+	}
+	if err := common.ValidateAddress(request.Address); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+	if request.Amount <= 0 {
+		writeError(w, r, errs.ErrInvalidAmountAction, nil)
+		return
+	}
+
+	s.transact(w, r, func(ctx context.Context) (*wallet.TransactionResult, error) {
+		return s.transfers.Send(ctx, s.client, request.Address, request.Amount)
+	})
+}
+
+func (s *Server) receive(w http.ResponseWriter, r *http.Request) {
+	var request amountRequest
+	if err := readJSON(r, &request); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+	if request.Amount <= 0 {
+		writeError(w, r, errs.ErrInvalidAmountAction, nil)
+		return
+	}
+
+	s.transact(w, r, func(ctx context.Context) (*wallet.TransactionResult, error) {
+		return s.transfers.Receive(ctx, s.client, request.Amount)
+	})
+}
+
+func (s *Server) submitSpendRequest(w http.ResponseWriter, r *http.Request) {
+	if s.spend == nil {
+		writeError(w, r, errs.ErrSpendStoreRequired, nil)
+		return
+	}
+
+	var request spendRequestSubmission
+	if err := readJSON(r, &request); err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	spendRequest, err := s.spend.Submit(r.Context(), request.Beneficiary, request.Amount, request.Reason, request.Reference, request.Signature)
+	if err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, spendRequest)
+}
+
+func (s *Server) listSpendRequests(w http.ResponseWriter, r *http.Request) {
+	if s.spend == nil {
+		writeError(w, r, errs.ErrSpendStoreRequired, nil)
+		return
+	}
+
+	statuses := r.URL.Query()["status"]
+	if len(statuses) == 0 {
+		statuses = []string{store.SpendRequestPending}
+	}
+
+	review, err := s.spend.Review(r.Context(), s.client, statuses)
+	if err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, review)
+}
+
+func (s *Server) approveSpendRequest(w http.ResponseWriter, r *http.Request) {
+	if s.spend == nil {
+		writeError(w, r, errs.ErrSpendStoreRequired, nil)
+		return
+	}
+
+	spendRequest, result, err := s.spend.Approve(r.Context(), s.client, r.PathValue("id"), wallet.Requester(r.Context()))
+
+	if err != nil {
+		writeError(w, r, err, result)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, spendRequest)
+}
+
+func (s *Server) rejectSpendRequest(w http.ResponseWriter, r *http.Request) {
+	if s.spend == nil {
+		writeError(w, r, errs.ErrSpendStoreRequired, nil)
+		return
+	}
+
+	spendRequest, err := s.spend.Reject(r.Context(), r.PathValue("id"), wallet.Requester(r.Context()))
+	if err != nil {
+		writeError(w, r, err, nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, spendRequest)
+}
+
+// transact runs an operation signing a transaction and writes its result
+func (s *Server) transact(w http.ResponseWriter, r *http.Request, operation func(ctx context.Context) (*wallet.TransactionResult, error)) {
+	ctx := wallet.WithCallerIdempotencyKey(r.Context(), r.Header.Get("Idempotency-Key"))
+
+	result, err := operation(ctx)
+
+	if err != nil {
+		writeError(w, r, err, result)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readJSON decode the request body
+func readJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrInvalidRequestBody, err)
+	}
+	return nil
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response, including the transaction result when there is one
+func writeError(w http.ResponseWriter, r *http.Request, err error, result *wallet.TransactionResult) {
+	status := errorStatus(err)
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "request failed",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	writeJSON(w, status, errorResponse{Error: err.Error(), Result: result})
+}
+
+// errorStatus maps an error to its HTTP status code from the kind given by errs.Classify
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errs.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, errs.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errs.IsNotFound(err):
+		return http.StatusNotFound
+	case errs.IsConflict(err):
+		return http.StatusConflict
+	case errs.IsInternal(err):
+		return http.StatusInternalServerError
+	case errors.Is(err, errs.ErrTransactionDropped):
+		return http.StatusGone
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+
+	switch _, exit := errs.Classify(err); exit {
+	case errs.ExitValidation:
+		return http.StatusBadRequest
+	case errs.ExitPolicy, errs.ExitReverted:
+		return http.StatusUnprocessableEntity
+	case errs.ExitRPC:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
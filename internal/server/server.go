@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/internal/auth"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/spend"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	shutdownTimeout   = 5 * time.Second
+	readHeaderTimeout = 5 * time.Second
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// Server JSON HTTP API exposing the wallet runners
+type Server struct {
+	client    *ethclient.Client
+	allowance *wallet.Allowance
+	balance   wallet.Balance
+	owner     wallet.Owner
+	transfers wallet.Transfers
+	spend     *spend.Manager
+	auth      *auth.Authenticator
+	// metrics whether the API serves the /metrics endpoint, it is not when the metrics have their own listener
+	metrics bool
+}
+
+// New returns a new server instance
+func New(client *ethclient.Client, privateKey string, contractAddress string, authenticator *auth.Authenticator, st *store.Store, pol *policy.Policy) (*Server, error) {
+	s := &Server{
+		client:    client,
+		auth:      authenticator,
+		metrics:   true,
+		allowance: wallet.NewAllowanceRunner(privateKey, contractAddress),
+		balance:   wallet.NewBalanceRunner(privateKey, contractAddress),
+		owner:     wallet.NewOwnerRunner(privateKey, contractAddress),
+		transfers: wallet.NewTransfersRunner(privateKey, contractAddress),
+	}
+
+	s.allowance.SetPolicy(pol)
+	s.owner.SetPolicy(pol)
+	s.transfers.SetPolicy(pol)
+
+	// the store records the idempotency keys, the requests using them are rejected without it
+	if st != nil {
+		s.allowance.SetStore(st)
+		s.owner.SetStore(st)
+		s.transfers.SetStore(st)
+		var err error
+		if s.spend, err = spend.New(st, privateKey, contractAddress, pol); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// SetMetrics sets whether the API serves the /metrics endpoint, it does by default
+func (s *Server) SetMetrics(enabled bool) {
+	s.metrics = enabled
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/allowances/{address}", s.authorize(auth.GetAllowanceOperation, s.getAllowance))
+	mux.HandleFunc("POST /v1/allowances/{address}/{action}", s.authorize(auth.ChangeAllowanceOperation, s.changeAllowance))
+	mux.HandleFunc("GET /v1/balances/contract", s.authorize(auth.GetBalanceOperation, s.getContractBalance))
+	mux.HandleFunc("GET /v1/balances/{address}", s.authorize(auth.GetBalanceOperation, s.getAddressBalance))
+	mux.HandleFunc("GET /v1/owner", s.authorize(auth.GetOwnerOperation, s.getOwner))
+	mux.HandleFunc("POST /v1/owner/transfer", s.authorize(auth.TransferOwnershipOperation, s.transferOwnership))
+	mux.HandleFunc("POST /v1/transfers/send", s.authorize(auth.SendOperation, s.send))
+	mux.HandleFunc("POST /v1/transfers/receive", s.authorize(auth.ReceiveOperation, s.receive))
+	mux.HandleFunc("POST /v1/spend-requests", s.authorize(auth.SubmitSpendRequestOperation, s.submitSpendRequest))
+	mux.HandleFunc("GET /v1/spend-requests", s.authorize(auth.ListSpendRequestsOperation, s.listSpendRequests))
+	mux.HandleFunc("POST /v1/spend-requests/{id}/approve", s.authorize(auth.DecideSpendRequestOperation, s.approveSpendRequest))
+	mux.HandleFunc("POST /v1/spend-requests/{id}/reject", s.authorize(auth.DecideSpendRequestOperation, s.rejectSpendRequest))
+
+	mux.HandleFunc("GET /openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(openAPI)
+	})
+	if s.metrics {
+		mux.Handle("GET /metrics", s.authorize(auth.MetricsOperation, metrics.Handler().ServeHTTP))
+	}
+
+	return mux
+}
+
+// authorize rejects the requests whose credentials are not granted the operation, before the handler gets the signer
+func (s *Server) authorize(operation string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		credential := auth.Credential(r.Header.Get("Authorization"), r.Header.Get("X-API-Key"))
+		principal, err := s.auth.Authorize(r.Context(), credential, operation)
+		if err != nil {
+			if errors.Is(err, errs.ErrUnauthenticated) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			writeError(w, r, err, nil)
+			return
+		}
+
+		next(w, r.WithContext(wallet.WithRequester(r.Context(), "api:"+principal.Name)))
+	}
+}
+
+// Serve serves the API on the given address until the context is done
+func (s *Server) Serve(ctx context.Context, address string) error {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctxShutdown)
+	}()
+
+	slog.DebugContext(ctx, "serving API", slog.String("address", address))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package spend
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/wallet"
+	"log/slog"
+	"strings"
+)
+
+// maxFieldLength maximum length of the reason and the reference of a request
+const maxFieldLength = 256
+
+// Manager records the spend requests signed by the beneficiaries and pays the ones the owner approves
+type Manager struct {
+	store           *store.Store
+	contractAddress string
+	allowance       *wallet.Allowance
+	balance         wallet.Balance
+	transfers       wallet.Transfers
+}
+
+// Review spend requests with the figures the owner needs to decide on them
+type Review struct {
+	ContractBalance int64             `json:"contract_balance"`
+	Requests        []ReviewedRequest `json:"requests"`
+}
+
+// ReviewedRequest spend request with the current allowance of its beneficiary. Amounts are expressed in Ether
+type ReviewedRequest struct {
+	store.SpendRequest
+	Allowance int64 `json:"allowance"`
+}
+
+// New returns a new manager instance, the private key signs the payouts of the approved requests
+func New(st *store.Store, privateKey string, contractAddress string, pol *policy.Policy) (*Manager, error) {
+	if st == nil {
+		return nil, errs.ErrSpendStoreRequired
+	}
+
+	transfers := wallet.NewTransfersRunner(privateKey, contractAddress)
+	transfers.SetStore(st)
+	transfers.SetPolicy(pol)
+
+	return &Manager{
+		store:           st,
+		contractAddress: ethcommon.HexToAddress(contractAddress).Hex(),
+		allowance:       wallet.NewAllowanceRunner(privateKey, contractAddress),
+		balance:         wallet.NewBalanceRunner(privateKey, contractAddress),
+		transfers:       transfers,
+	}, nil
+}
+
+// Message returns the text a beneficiary signs, with the Ethereum personal message prefix, to submit a request
+func (m *Manager) Message(beneficiary string, amount int64, reason string, reference string) string {
+	return fmt.Sprintf("wallet spend request\nbeneficiary: %s\namount: %d ether\nreason: %s\nreference: %s\ncontract: %s",
+		ethcommon.HexToAddress(beneficiary).Hex(), amount, reason, reference, m.contractAddress)
+}
+
+// Sign signs the request message with the beneficiary private key, for beneficiaries submitting with the command line
+func (m *Manager) Sign(amount int64, reason string, reference string, key *ecdsa.PrivateKey) (string, error) {
+	beneficiary := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	return common.SignMessage(m.Message(beneficiary, amount, reason, reference), key)
+}
+
+// Submit records a pending request after verifying it is signed by the beneficiary. The reference is unique per
+// beneficiary, so a signed request cannot be submitted twice
+func (m *Manager) Submit(ctx context.Context, beneficiary string, amount int64, reason string, reference string, signature string) (*store.SpendRequest, error) {
+	if err := common.ValidateBeneficiary(beneficiary, m.contractAddress); err != nil {
+		return nil, err
+	}
+	if amount <= 0 {
+		return nil, errs.ErrInvalidAmountAction
+	}
+	if reference == "" {
+		return nil, fmt.Errorf("%w: reference is required", errs.ErrInvalidSpendRequest)
+	}
+	for name, value := range map[string]string{"reason": reason, "reference": reference} {
+		if len(value) > maxFieldLength || strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("%w: %s must be a single line of at most %d characters", errs.ErrInvalidSpendRequest, name, maxFieldLength)
+		}
+	}
+
+	address := ethcommon.HexToAddress(beneficiary)
+	if err := common.VerifyMessage(m.Message(beneficiary, amount, reason, reference), signature, address); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate spend request id: %w", err)
+	}
+
+	request := store.SpendRequest{
+		ID:          hex.EncodeToString(id),
+		Beneficiary: address.Hex(),
+		Amount:      amount,
+		Reason:      reason,
+		Reference:   reference,
+		Signature:   strings.TrimSpace(signature),
+	}
+	created, err := m.store.CreateSpendRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: %s", errs.ErrDuplicateSpendRequest, reference)
+	}
+
+	slog.InfoContext(ctx, "spend request submitted",
+		slog.String("id", request.ID),
+		slog.String("beneficiary", request.Beneficiary),
+		slog.Int64("amount", amount),
+		slog.String("reference", reference),
+	)
+	return m.Get(ctx, request.ID)
+}
+
+// Get returns a spend request
+func (m *Manager) Get(ctx context.Context, id string) (*store.SpendRequest, error) {
+	request, err := m.store.GetSpendRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrUnknownSpendRequest, id)
+	}
+	return request, nil
+}
+
+// Review returns the requests with the given statuses, with the current allowance of their beneficiaries and the
+// contract balance
+func (m *Manager) Review(ctx context.Context, client *ethclient.Client, statuses []string) (*Review, error) {
+	requests, err := m.store.ListSpendRequests(ctx, statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := m.balance.GetContractBalance(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	beneficiaries := make([]string, 0, len(requests))
+	for _, request := range requests {
+		beneficiaries = append(beneficiaries, request.Beneficiary)
+	}
+	allowances, err := m.allowance.GetAllowances(ctx, client, beneficiaries)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &Review{ContractBalance: balance, Requests: make([]ReviewedRequest, 0, len(requests))}
+	for _, request := range requests {
+		review.Requests = append(review.Requests, ReviewedRequest{SpendRequest: request, Allowance: allowances[request.Beneficiary]})
+	}
+	return review, nil
+}
+
+// Approve pays a pending request and links its transaction to it. The request id is used as idempotency key, so
+// approving it again never sends a second payout while the first one may still be mined. A failed payout leaves the
+// request pending with its error, a reverted or dropped one releases the key so approving it again retries the payout
+func (m *Manager) Approve(ctx context.Context, client *ethclient.Client, id string, approver string) (*store.SpendRequest, *wallet.TransactionResult, error) {
+	request, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if request.Status != store.SpendRequestPending {
+		return nil, nil, fmt.Errorf("%w: %s is %s", errs.ErrSpendRequestClosed, id, request.Status)
+	}
+
+	ctx = wallet.WithIdempotencyKey(ctx, "spend-request:"+id)
+	ctx = wallet.WithRequester(ctx, approver)
+
+	result, err := m.transfers.Send(ctx, client, request.Beneficiary, request.Amount)
+	if err != nil {
+		if _, updateErr := m.store.UpdateSpendRequest(ctx, id, store.SpendRequestPending, store.SpendRequestPending, "", err.Error(), ""); updateErr != nil {
+			slog.ErrorContext(ctx, "failed to update spend request", slog.String("id", id), slog.String("error", updateErr.Error()))
+		}
+		return nil, result, err
+	}
+
+	paid, err := m.store.UpdateSpendRequest(ctx, id, store.SpendRequestPending, store.SpendRequestPaid, result.TxHash, "", approver)
+	if err != nil {
+		return nil, result, err
+	}
+	if !paid {
+		slog.WarnContext(ctx, "spend request closed while it was being paid", slog.String("id", id), slog.String("tx_hash", result.TxHash))
+	}
+
+	slog.InfoContext(ctx, "spend request paid",
+		slog.String("id", id),
+		slog.String("approver", approver),
+		slog.String("tx_hash", result.TxHash),
+	)
+	request, err = m.Get(ctx, id)
+	return request, result, err
+}
+
+// Reject closes a pending request without paying it
+func (m *Manager) Reject(ctx context.Context, id string, approver string) (*store.SpendRequest, error) {
+	request, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rejected, err := m.store.UpdateSpendRequest(ctx, id, store.SpendRequestPending, store.SpendRequestRejected, "", request.Error, approver)
+	if err != nil {
+		return nil, err
+	}
+	if !rejected {
+		return nil, fmt.Errorf("%w: %s is %s", errs.ErrSpendRequestClosed, id, request.Status)
+	}
+
+	slog.InfoContext(ctx, "spend request rejected", slog.String("id", id), slog.String("approver", approver))
+	return m.Get(ctx, id)
+}
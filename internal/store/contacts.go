@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Contact named address of the address book, the tags group the contacts
+type Contact struct {
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddContact records a new contact, it reports false when a contact with the same name or address exists. The names
+// are compared without case
+func (s *Store) AddContact(ctx context.Context, contact Contact) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO contacts (name, address, tags, created_at) VALUES (?, ?, ?, ?)`,
+		contact.Name,
+		contact.Address,
+		strings.Join(contact.Tags, ","),
+		time.Now().Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to add contact: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// RemoveContact removes a contact by name, it reports false when the contact is unknown
+func (s *Store) RemoveContact(ctx context.Context, name string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM contacts WHERE name = ?`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove contact: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// ListContacts returns the contacts ordered by name
+func (s *Store) ListContacts(ctx context.Context) ([]Contact, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, address, tags, created_at FROM contacts ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Contact
+	for rows.Next() {
+		var (
+			contact   Contact
+			tags      string
+			createdAt int64
+		)
+		if err := rows.Scan(&contact.Name, &contact.Address, &tags, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+
+		contact.Tags = []string{}
+		if tags != "" {
+			contact.Tags = strings.Split(tags, ",")
+		}
+		contact.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, contact)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ENSRecord cached result of an ENS lookup, the value is an address for a name, or a name for an address
+type ENSRecord struct {
+	Key        string
+	Value      string
+	ResolvedAt time.Time
+}
+
+// GetENSRecord returns the cached result of a lookup, nil when the key is unknown
+func (s *Store) GetENSRecord(ctx context.Context, key string) (*ENSRecord, error) {
+	var (
+		record     ENSRecord
+		resolvedAt int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT key, value, resolved_at FROM ens_records WHERE key = ?`, key,
+	).Scan(&record.Key, &record.Value, &resolvedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ens record: %w", err)
+	}
+
+	record.ResolvedAt = time.Unix(resolvedAt, 0)
+	return &record, nil
+}
+
+// SaveENSRecord records the result of a lookup, replacing the previous one
+func (s *Store) SaveENSRecord(ctx context.Context, key string, value string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO ens_records (key, value, resolved_at) VALUES (?, ?, ?)`,
+		key, value, time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save ens record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AllowanceChangedEvent     = "AllowanceChanged"
+	MoneySentEvent            = "MoneySent"
+	MoneyReceivedEvent        = "MoneyReceived"
+	OwnershipTransferredEvent = "OwnershipTransferred"
+)
+
+// Event decoded contract event. Address is the beneficiary, the sender of the funds or the new owner depending on
+// the event type, Counterparty is the allowance sender or the previous owner. Amounts are expressed in Wei
+type Event struct {
+	ChainID      uint64    `json:"chain_id"`
+	Contract     string    `json:"contract"`
+	TxHash       string    `json:"tx_hash"`
+	LogIndex     uint      `json:"log_index"`
+	BlockNumber  uint64    `json:"block_number"`
+	Timestamp    time.Time `json:"timestamp"`
+	Type         string    `json:"event_type"`
+	Address      string    `json:"address"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Amount       *big.Int  `json:"amount"`
+	PrevAmount   *big.Int  `json:"prev_amount,omitempty"`
+}
+
+// EventFilter criteria to query events, zero values are not applied
+type EventFilter struct {
+	ChainID   uint64
+	Contract  string
+	Types     []string
+	Address   string
+	FromBlock uint64
+	ToBlock   uint64
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// BeneficiaryTotal aggregated activity of a beneficiary
+type BeneficiaryTotal struct {
+	Beneficiary string   `json:"beneficiary"`
+	Payouts     int      `json:"payouts"`
+	Sent        *big.Int `json:"sent"`
+	Allowance   *big.Int `json:"allowance"`
+}
+
+// SaveEvent stores an event, events already stored are ignored
+func (s *Store) SaveEvent(ctx context.Context, event Event) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO events (
+			chain_id, contract, tx_hash, log_index, block_number, block_time, event, address, counterparty, amount, prev_amount
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.ChainID,
+		event.Contract,
+		event.TxHash,
+		event.LogIndex,
+		event.BlockNumber,
+		event.Timestamp.Unix(),
+		event.Type,
+		event.Address,
+		event.Counterparty,
+		amountString(event.Amount),
+		amountString(event.PrevAmount),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+	return nil
+}
+
+// DeleteEvent removes an event, used when a log is removed by a chain reorganisation
+func (s *Store) DeleteEvent(ctx context.Context, chainID uint64, contract string, txHash string, logIndex uint) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM events WHERE chain_id = ? AND contract = ? AND tx_hash = ? AND log_index = ?`,
+		chainID, contract, txHash, logIndex,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
+// LastBlock returns the highest block number stored for a contract, zero if there are no events
+func (s *Store) LastBlock(ctx context.Context, chainID uint64, contract string) (uint64, error) {
+	var block sql.NullInt64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT MAX(block_number) FROM events WHERE chain_id = ? AND contract = ?`,
+		chainID, contract,
+	).Scan(&block)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get last block: %w", err)
+	}
+	return uint64(block.Int64), nil
+}
+
+// QueryEvents returns the events matching the filter ordered by block and log index
+func (s *Store) QueryEvents(ctx context.Context, filter EventFilter) ([]Event, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	if filter.ChainID != 0 {
+		conditions = append(conditions, "chain_id = ?")
+		args = append(args, filter.ChainID)
+	}
+	if filter.Contract != "" {
+		conditions = append(conditions, "contract = ?")
+		args = append(args, filter.Contract)
+	}
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, "event IN (?"+strings.Repeat(", ?", len(filter.Types)-1)+")")
+		for _, eventType := range filter.Types {
+			args = append(args, eventType)
+		}
+	}
+	if filter.Address != "" {
+		conditions = append(conditions, "(address = ? OR counterparty = ?)")
+		args = append(args, filter.Address, filter.Address)
+	}
+	if filter.FromBlock != 0 {
+		conditions = append(conditions, "block_number >= ?")
+		args = append(args, filter.FromBlock)
+	}
+	if filter.ToBlock != 0 {
+		conditions = append(conditions, "block_number <= ?")
+		args = append(args, filter.ToBlock)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "block_time >= ?")
+		args = append(args, filter.Since.Unix())
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "block_time < ?")
+		args = append(args, filter.Until.Unix())
+	}
+
+	query := `SELECT chain_id, contract, tx_hash, log_index, block_number, block_time, event, address, counterparty,
+		amount, prev_amount FROM events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY block_number, log_index"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var (
+			event              Event
+			blockTime          int64
+			amount, prevAmount string
+		)
+		err := rows.Scan(
+			&event.ChainID,
+			&event.Contract,
+			&event.TxHash,
+			&event.LogIndex,
+			&event.BlockNumber,
+			&blockTime,
+			&event.Type,
+			&event.Address,
+			&event.Counterparty,
+			&amount,
+			&prevAmount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+
+		event.Timestamp = time.Unix(blockTime, 0).UTC()
+		event.Amount = parseAmount(amount)
+		event.PrevAmount = parseAmount(prevAmount)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate events: %w", err)
+	}
+	return events, nil
+}
+
+// AggregateByBeneficiary returns the payouts and the last allowance of every beneficiary of the matching events
+func (s *Store) AggregateByBeneficiary(ctx context.Context, filter EventFilter) ([]BeneficiaryTotal, error) {
+	filter.Types = []string{AllowanceChangedEvent, MoneySentEvent}
+	filter.Limit = 0
+
+	events, err := s.QueryEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]*BeneficiaryTotal)
+	for _, event := range events {
+		total, ok := totals[event.Address]
+		if !ok {
+			total = &BeneficiaryTotal{
+				Beneficiary: event.Address,
+				Sent:        new(big.Int),
+				Allowance:   new(big.Int),
+			}
+			totals[event.Address] = total
+		}
+
+		switch event.Type {
+		case AllowanceChangedEvent:
+			total.Allowance = event.Amount
+		case MoneySentEvent:
+			total.Payouts++
+			total.Sent.Add(total.Sent, event.Amount)
+		}
+	}
+
+	result := make([]BeneficiaryTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Beneficiary < result[j].Beneficiary
+	})
+
+	return result, nil
+}
+
+// amountString format an amount to be stored
+func amountString(amount *big.Int) string {
+	if amount == nil {
+		return "0"
+	}
+	return amount.String()
+}
+
+// parseAmount parse a stored amount
+func parseAmount(value string) *big.Int {
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return amount
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyKey request identified by a client provided key and the transaction it broadcast. TxHash is empty
+// until the transaction is signed
+type IdempotencyKey struct {
+	Key       string
+	Operation string
+	Request   string
+	TxHash    string
+	CreatedAt time.Time
+}
+
+// ReserveIdempotencyKey reserves a key for a request. When the key is already reserved the existing reservation is
+// returned and reserved is false
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key string, operation string, request string) (*IdempotencyKey, bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO idempotency_keys (key, operation, request, created_at) VALUES (?, ?, ?, ?)`,
+		key, operation, request, time.Now().Unix(),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 1 {
+		return nil, true, nil
+	}
+
+	existing, err := s.GetIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// GetIdempotencyKey returns the reservation of a key, nil when the key is unknown
+func (s *Store) GetIdempotencyKey(ctx context.Context, key string) (*IdempotencyKey, error) {
+	var (
+		reservation IdempotencyKey
+		createdAt   int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT key, operation, request, tx_hash, created_at FROM idempotency_keys WHERE key = ?`, key,
+	).Scan(&reservation.Key, &reservation.Operation, &reservation.Request, &reservation.TxHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	reservation.CreatedAt = time.Unix(createdAt, 0)
+	return &reservation, nil
+}
+
+// TakeOverIdempotencyKey renews a reservation without transaction created before the given time, it reports
+// whether the reservation was taken over
+func (s *Store) TakeOverIdempotencyKey(ctx context.Context, key string, before time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET created_at = ? WHERE key = ? AND tx_hash = '' AND created_at < ?`,
+		time.Now().Unix(), key, before.Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to take over idempotency key: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// SetIdempotencyTx records the transaction signed for a reserved key
+func (s *Store) SetIdempotencyTx(ctx context.Context, key string, txHash string) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE idempotency_keys SET tx_hash = ? WHERE key = ?`, txHash, key); err != nil {
+		return fmt.Errorf("failed to set idempotency transaction: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes a reservation without transaction, so the key can be used again
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ? AND tx_hash = ''`, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyTx deletes a reservation bound to the given transaction, so the key can be used again once the
+// transaction reverted or was dropped. It reports whether the reservation was deleted
+func (s *Store) ReleaseIdempotencyTx(ctx context.Context, key string, txHash string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ? AND tx_hash = ?`, key, txHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Status of the transactions recorded in the outbox
+const (
+	// TxSigned the transaction was signed and recorded, it might not have been broadcast
+	TxSigned = "signed"
+	// TxPending the transaction was broadcast and waits to be mined
+	TxPending = "pending"
+	// TxMined the transaction was mined successfully
+	TxMined = "mined"
+	// TxFailed the transaction was mined and reverted
+	TxFailed = "failed"
+	// TxReplaced the transaction nonce was used by another transaction, it will never be mined
+	TxReplaced = "replaced"
+	// TxDropped the transaction was rejected by the node on every broadcast attempt and is no longer tracked
+	TxDropped = "dropped"
+)
+
+// Transaction signed transaction recorded before being broadcast. Amount is expressed in Wei
+type Transaction struct {
+	Hash        string    `json:"hash"`
+	ChainID     uint64    `json:"chain_id"`
+	Sender      string    `json:"sender"`
+	Nonce       uint64    `json:"nonce"`
+	Operation   string    `json:"operation"`
+	Target      string    `json:"target,omitempty"`
+	Amount      *big.Int  `json:"amount"`
+	Requester   string    `json:"requester"`
+	Raw         []byte    `json:"-"`
+	Status      string    `json:"status"`
+	BlockNumber uint64    `json:"block_number,omitempty"`
+	GasUsed     uint64    `json:"gas_used,omitempty"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Final reports whether the transaction reached a final status
+func (t *Transaction) Final() bool {
+	return t.Status == TxMined || t.Status == TxFailed || t.Status == TxReplaced || t.Status == TxDropped
+}
+
+// TransactionFilter criteria to list transactions, zero values are not applied
+type TransactionFilter struct {
+	Statuses   []string
+	Operations []string
+	Target     string
+	Since      time.Time
+	Limit      int
+}
+
+const transactionColumns = `hash, chain_id, sender, nonce, operation, target, amount, requester, raw, status,
+	block_number, gas_used, attempts, last_error, created_at, updated_at`
+
+// SaveTransaction records a signed transaction, it must be called before broadcasting it. It is created now unless
+// CreatedAt is set
+func (s *Store) SaveTransaction(ctx context.Context, tx Transaction) error {
+	now := time.Now().Unix()
+	createdAt := now
+	if !tx.CreatedAt.IsZero() {
+		createdAt = tx.CreatedAt.Unix()
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO transactions (`+transactionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, '', ?, ?)`,
+		tx.Hash,
+		tx.ChainID,
+		tx.Sender,
+		tx.Nonce,
+		tx.Operation,
+		tx.Target,
+		amountString(tx.Amount),
+		tx.Requester,
+		tx.Raw,
+		TxSigned,
+		createdAt,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save transaction: %w", err)
+	}
+	return nil
+}
+
+// RecordBroadcast records a broadcast attempt, the transaction is pending when it succeeded
+func (s *Store) RecordBroadcast(ctx context.Context, hash string, broadcastErr error) error {
+	var err error
+	if broadcastErr == nil {
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE transactions SET status = ?, attempts = attempts + 1, last_error = '', updated_at = ? WHERE hash = ?`,
+			TxPending, time.Now().Unix(), hash,
+		)
+	} else {
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE transactions SET attempts = attempts + 1, last_error = ?, updated_at = ? WHERE hash = ?`,
+			broadcastErr.Error(), time.Now().Unix(), hash,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record broadcast: %w", err)
+	}
+	return nil
+}
+
+// SetTransactionStatus sets the status of a transaction, with the block and gas used once it is mined
+func (s *Store) SetTransactionStatus(ctx context.Context, hash string, status string, blockNumber uint64, gasUsed uint64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE transactions SET status = ?, block_number = ?, gas_used = ?, updated_at = ? WHERE hash = ?`,
+		status, blockNumber, gasUsed, time.Now().Unix(), hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set transaction status: %w", err)
+	}
+	return nil
+}
+
+// GetTransaction returns a recorded transaction, nil when it is unknown
+func (s *Store) GetTransaction(ctx context.Context, hash string) (*Transaction, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE hash = ?`, hash)
+	tx, err := scanTransaction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// PendingTransactions returns the transactions without final status ordered by nonce
+func (s *Store) PendingTransactions(ctx context.Context) ([]Transaction, error) {
+	return s.ListTransactions(ctx, TransactionFilter{Statuses: []string{TxSigned, TxPending}})
+}
+
+// ListTransactions returns the recorded transactions matching the filter, ordered by sender and nonce
+func (s *Store) ListTransactions(ctx context.Context, filter TransactionFilter) ([]Transaction, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status IN (?"+strings.Repeat(", ?", len(filter.Statuses)-1)+")")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if len(filter.Operations) > 0 {
+		conditions = append(conditions, "operation IN (?"+strings.Repeat(", ?", len(filter.Operations)-1)+")")
+		for _, operation := range filter.Operations {
+			args = append(args, operation)
+		}
+	}
+	if filter.Target != "" {
+		conditions = append(conditions, "target = ?")
+		args = append(args, filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.Unix())
+	}
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY sender, nonce, created_at"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		result = append(result, *tx)
+	}
+	return result, rows.Err()
+}
+
+func scanTransaction(row interface{ Scan(dest ...any) error }) (*Transaction, error) {
+	var (
+		tx                   Transaction
+		amount               string
+		createdAt, updatedAt int64
+	)
+	err := row.Scan(
+		&tx.Hash,
+		&tx.ChainID,
+		&tx.Sender,
+		&tx.Nonce,
+		&tx.Operation,
+		&tx.Target,
+		&amount,
+		&tx.Requester,
+		&tx.Raw,
+		&tx.Status,
+		&tx.BlockNumber,
+		&tx.GasUsed,
+		&tx.Attempts,
+		&tx.LastError,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.Amount = parseAmount(amount)
+	tx.CreatedAt = time.Unix(createdAt, 0)
+	tx.UpdatedAt = time.Unix(updatedAt, 0)
+	return &tx, nil
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status of the proposals
+const (
+	// ProposalPending the proposal waits for the approvers decisions
+	ProposalPending = "pending"
+	// ProposalApproved the proposal got the required approvals and can be executed
+	ProposalApproved = "approved"
+	// ProposalRejected enough approvers rejected the proposal for it to never get the required approvals
+	ProposalRejected = "rejected"
+	// ProposalExecuting the proposal operation is being signed and sent
+	ProposalExecuting = "executing"
+	// ProposalExecuted the proposal operation was mined
+	ProposalExecuted = "executed"
+	// ProposalExpired the proposal was not executed before its expiry
+	ProposalExpired = "expired"
+)
+
+// Decisions of the approvers
+const (
+	Approve = "approve"
+	Reject  = "reject"
+)
+
+// Proposal operation waiting for the approvals required to be executed. Amount is expressed in Ether
+type Proposal struct {
+	ID        string     `json:"id"`
+	Operation string     `json:"operation"`
+	Target    string     `json:"target,omitempty"`
+	Amount    int64      `json:"amount"`
+	Proposer  string     `json:"proposer"`
+	Status    string     `json:"status"`
+	TxHash    string     `json:"tx_hash,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Decisions []Decision `json:"decisions"`
+}
+
+// Decision signed decision of an approver on a proposal
+type Decision struct {
+	Approver  string    `json:"approver"`
+	Address   string    `json:"address"`
+	Decision  string    `json:"decision"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const proposalColumns = `id, operation, target, amount, proposer, status, tx_hash, error, created_at, expires_at, updated_at`
+
+// CreateProposal records a new pending proposal
+func (s *Store) CreateProposal(ctx context.Context, proposal Proposal) error {
+	now := time.Now().Unix()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO proposals (`+proposalColumns+`) VALUES (?, ?, ?, ?, ?, ?, '', '', ?, ?, ?)`,
+		proposal.ID,
+		proposal.Operation,
+		proposal.Target,
+		proposal.Amount,
+		proposal.Proposer,
+		ProposalPending,
+		now,
+		proposal.ExpiresAt.Unix(),
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create proposal: %w", err)
+	}
+	return nil
+}
+
+// GetProposal returns a proposal with its decisions, nil when it is unknown
+func (s *Store) GetProposal(ctx context.Context, id string) (*Proposal, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+proposalColumns+` FROM proposals WHERE id = ?`, id)
+	proposal, err := scanProposal(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proposal: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT approver, address, decision, signature, created_at FROM proposal_decisions
+		WHERE proposal_id = ? ORDER BY created_at, approver`, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proposal decisions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			decision  Decision
+			createdAt int64
+		)
+		if err := rows.Scan(&decision.Approver, &decision.Address, &decision.Decision, &decision.Signature, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan proposal decision: %w", err)
+		}
+		decision.CreatedAt = time.Unix(createdAt, 0)
+		proposal.Decisions = append(proposal.Decisions, decision)
+	}
+	return proposal, rows.Err()
+}
+
+// ListProposals returns the proposals with the given statuses, all of them when empty, the latest first
+func (s *Store) ListProposals(ctx context.Context, statuses []string) ([]Proposal, error) {
+	query := `SELECT ` + proposalColumns + ` FROM proposals`
+	var args []any
+	if len(statuses) > 0 {
+		query += " WHERE status IN (?" + strings.Repeat(", ?", len(statuses)-1) + ")"
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+	}
+	query += " ORDER BY created_at DESC, id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proposals: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Proposal
+	for rows.Next() {
+		proposal, err := scanProposal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan proposal: %w", err)
+		}
+		result = append(result, *proposal)
+	}
+	return result, rows.Err()
+}
+
+// AddDecision records the decision of an approver, it reports false when the approver already decided
+func (s *Store) AddDecision(ctx context.Context, id string, decision Decision) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO proposal_decisions (proposal_id, approver, address, decision, signature, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		id, decision.Approver, decision.Address, decision.Decision, decision.Signature, time.Now().Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to add proposal decision: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// UpdateProposal changes the status of a proposal when it has the expected one, it reports whether it was changed
+func (s *Store) UpdateProposal(ctx context.Context, id string, from string, to string, txHash string, errMsg string) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE proposals SET status = ?, tx_hash = ?, error = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		to, txHash, errMsg, time.Now().Unix(), id, from,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update proposal: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+func scanProposal(row interface{ Scan(dest ...any) error }) (*Proposal, error) {
+	var (
+		proposal                        Proposal
+		createdAt, expiresAt, updatedAt int64
+	)
+	err := row.Scan(
+		&proposal.ID,
+		&proposal.Operation,
+		&proposal.Target,
+		&proposal.Amount,
+		&proposal.Proposer,
+		&proposal.Status,
+		&proposal.TxHash,
+		&proposal.Error,
+		&createdAt,
+		&expiresAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal.CreatedAt = time.Unix(createdAt, 0)
+	proposal.ExpiresAt = time.Unix(expiresAt, 0)
+	proposal.UpdatedAt = time.Unix(updatedAt, 0)
+	return &proposal, nil
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status of the schedule runs
+const (
+	// RunRunning the run started, it is resumed after a restart when it did not finish
+	RunRunning = "running"
+	// RunSucceeded the action was applied to every beneficiary
+	RunSucceeded = "succeeded"
+	// RunFailed the action failed for at least one beneficiary
+	RunFailed = "failed"
+)
+
+// Triggers of the schedule runs
+const (
+	// TriggerScheduled the run started at its scheduled time
+	TriggerScheduled = "scheduled"
+	// TriggerCatchUp the run started late, after its scheduled time was missed
+	TriggerCatchUp = "catch_up"
+	// TriggerManual the run was requested with run-now
+	TriggerManual = "manual"
+)
+
+// ScheduleRun execution of a schedule. Missed is the number of earlier occurrences skipped by a catch up run
+type ScheduleRun struct {
+	Schedule    string    `json:"schedule"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Trigger     string    `json:"trigger"`
+	Missed      int       `json:"missed"`
+	Status      string    `json:"status"`
+	Applied     int       `json:"applied"`
+	Failed      int       `json:"failed"`
+	Error       string    `json:"error,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+}
+
+// Manual reports whether the run was requested with run-now rather than for an occurrence of the schedule
+func (r ScheduleRun) Manual() bool {
+	return r.Trigger == TriggerManual
+}
+
+// ScheduleRunFilter criteria to list schedule runs, zero values are not applied
+type ScheduleRunFilter struct {
+	Schedule string
+	Statuses []string
+	Limit    int
+}
+
+const scheduleRunColumns = `schedule, scheduled_at, trigger, missed, status, applied, failed, error, started_at, finished_at`
+
+// RegisterSchedule records the first time a schedule is seen and returns it, occurrences before it are never caught up
+func (s *Store) RegisterSchedule(ctx context.Context, name string, at time.Time) (time.Time, error) {
+	_, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO schedules (name, registered_at) VALUES (?, ?)`, name, at.Unix())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to register schedule: %w", err)
+	}
+
+	var registeredAt int64
+	err = s.db.QueryRowContext(ctx, `SELECT registered_at FROM schedules WHERE name = ?`, name).Scan(&registeredAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return time.Unix(registeredAt, 0), nil
+}
+
+// StartScheduleRun records a run as running. It reports false when the occurrence was already run, so every
+// occurrence runs once. The manual runs have their own key space, they never take the place of an occurrence
+func (s *Store) StartScheduleRun(ctx context.Context, run ScheduleRun) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO schedule_runs (`+scheduleRunColumns+`, manual)
+		VALUES (?, ?, ?, ?, ?, 0, 0, '', ?, 0, ?)`,
+		run.Schedule,
+		run.ScheduledAt.Unix(),
+		run.Trigger,
+		run.Missed,
+		RunRunning,
+		run.StartedAt.Unix(),
+		run.Manual(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to start schedule run: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// FinishScheduleRun records the outcome of a run
+func (s *Store) FinishScheduleRun(ctx context.Context, run ScheduleRun) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE schedule_runs SET status = ?, applied = ?, failed = ?, error = ?, finished_at = ?
+		WHERE schedule = ? AND scheduled_at = ? AND manual = ?`,
+		run.Status, run.Applied, run.Failed, run.Error, time.Now().Unix(), run.Schedule, run.ScheduledAt.Unix(), run.Manual(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish schedule run: %w", err)
+	}
+	return nil
+}
+
+// LastScheduledRun returns the time of the latest occurrence run by the scheduler, zero when it never ran. Manual
+// runs are not taken into account
+func (s *Store) LastScheduledRun(ctx context.Context, schedule string) (time.Time, error) {
+	var scheduledAt sql.NullInt64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT MAX(scheduled_at) FROM schedule_runs WHERE schedule = ? AND manual = 0`, schedule,
+	).Scan(&scheduledAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last schedule run: %w", err)
+	}
+	if !scheduledAt.Valid {
+		return time.Time{}, nil
+	}
+	return time.Unix(scheduledAt.Int64, 0), nil
+}
+
+// GetScheduleRun returns a scheduled or a manual run, nil when it is unknown
+func (s *Store) GetScheduleRun(ctx context.Context, schedule string, scheduledAt time.Time, manual bool) (*ScheduleRun, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+scheduleRunColumns+` FROM schedule_runs WHERE schedule = ? AND scheduled_at = ? AND manual = ?`,
+		schedule, scheduledAt.Unix(), manual,
+	)
+	run, err := scanScheduleRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule run: %w", err)
+	}
+	return run, nil
+}
+
+// ListScheduleRuns returns the runs matching the filter, the latest first
+func (s *Store) ListScheduleRuns(ctx context.Context, filter ScheduleRunFilter) ([]ScheduleRun, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if filter.Schedule != "" {
+		conditions = append(conditions, "schedule = ?")
+		args = append(args, filter.Schedule)
+	}
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status IN (?"+strings.Repeat(", ?", len(filter.Statuses)-1)+")")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+
+	query := `SELECT ` + scheduleRunColumns + ` FROM schedule_runs`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY scheduled_at DESC, schedule"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedule runs: %w", err)
+	}
+	defer rows.Close()
+
+	var result []ScheduleRun
+	for rows.Next() {
+		run, err := scanScheduleRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule run: %w", err)
+		}
+		result = append(result, *run)
+	}
+	return result, rows.Err()
+}
+
+func scanScheduleRun(row interface{ Scan(dest ...any) error }) (*ScheduleRun, error) {
+	var (
+		run                                ScheduleRun
+		scheduledAt, startedAt, finishedAt int64
+	)
+	err := row.Scan(
+		&run.Schedule,
+		&scheduledAt,
+		&run.Trigger,
+		&run.Missed,
+		&run.Status,
+		&run.Applied,
+		&run.Failed,
+		&run.Error,
+		&startedAt,
+		&finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	run.ScheduledAt = time.Unix(scheduledAt, 0)
+	run.StartedAt = time.Unix(startedAt, 0)
+	if finishedAt > 0 {
+		run.FinishedAt = time.Unix(finishedAt, 0)
+	}
+	return &run, nil
+}
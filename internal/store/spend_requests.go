@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status of the spend requests
+const (
+	// SpendRequestPending the request waits for the owner decision
+	SpendRequestPending = "pending"
+	// SpendRequestPaid the request was approved and its payout mined
+	SpendRequestPaid = "paid"
+	// SpendRequestRejected the owner rejected the request
+	SpendRequestRejected = "rejected"
+)
+
+// SpendRequest payout requested by a beneficiary with its own signature. Amount is expressed in Ether
+type SpendRequest struct {
+	ID          string    `json:"id"`
+	Beneficiary string    `json:"beneficiary"`
+	Amount      int64     `json:"amount"`
+	Reason      string    `json:"reason,omitempty"`
+	Reference   string    `json:"reference"`
+	Signature   string    `json:"signature"`
+	Status      string    `json:"status"`
+	TxHash      string    `json:"tx_hash,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DecidedBy   string    `json:"decided_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const spendRequestColumns = `id, beneficiary, amount, reason, reference, signature, status, tx_hash, error, decided_by, created_at, updated_at`
+
+// CreateSpendRequest records a new pending spend request, it reports false when the beneficiary already submitted
+// a request with the same reference
+func (s *Store) CreateSpendRequest(ctx context.Context, request SpendRequest) (bool, error) {
+	now := time.Now().Unix()
+	result, err := s.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO spend_requests (`+spendRequestColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, '', '', '', ?, ?)`,
+		request.ID,
+		request.Beneficiary,
+		request.Amount,
+		request.Reason,
+		request.Reference,
+		request.Signature,
+		SpendRequestPending,
+		now,
+		now,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create spend request: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// GetSpendRequest returns a spend request, nil when it is unknown
+func (s *Store) GetSpendRequest(ctx context.Context, id string) (*SpendRequest, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+spendRequestColumns+` FROM spend_requests WHERE id = ?`, id)
+	request, err := scanSpendRequest(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spend request: %w", err)
+	}
+	return request, nil
+}
+
+// ListSpendRequests returns the spend requests with the given statuses, all of them when empty, the oldest first
+func (s *Store) ListSpendRequests(ctx context.Context, statuses []string) ([]SpendRequest, error) {
+	query := `SELECT ` + spendRequestColumns + ` FROM spend_requests`
+	var args []any
+	if len(statuses) > 0 {
+		query += " WHERE status IN (?" + strings.Repeat(", ?", len(statuses)-1) + ")"
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+	}
+	query += " ORDER BY created_at, id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spend requests: %w", err)
+	}
+	defer rows.Close()
+
+	var result []SpendRequest
+	for rows.Next() {
+		request, err := scanSpendRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan spend request: %w", err)
+		}
+		result = append(result, *request)
+	}
+	return result, rows.Err()
+}
+
+// UpdateSpendRequest changes the status of a spend request when it has the expected one, it reports whether it
+// was changed
+func (s *Store) UpdateSpendRequest(ctx context.Context, id string, from string, to string, txHash string, errMsg string, decidedBy string) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE spend_requests SET status = ?, tx_hash = ?, error = ?, decided_by = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		to, txHash, errMsg, decidedBy, time.Now().Unix(), id, from,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update spend request: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+func scanSpendRequest(row interface{ Scan(dest ...any) error }) (*SpendRequest, error) {
+	var (
+		request              SpendRequest
+		createdAt, updatedAt int64
+	)
+	err := row.Scan(
+		&request.ID,
+		&request.Beneficiary,
+		&request.Amount,
+		&request.Reason,
+		&request.Reference,
+		&request.Signature,
+		&request.Status,
+		&request.TxHash,
+		&request.Error,
+		&request.DecidedBy,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	request.CreatedAt = time.Unix(createdAt, 0)
+	request.UpdatedAt = time.Unix(updatedAt, 0)
+	return &request, nil
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "modernc.org/sqlite"
+)
+
+// busyTimeout time in milliseconds to wait for a lock held by another process using the same database
+const busyTimeout = 5000
+
+// migrations statements creating the store schema, they must be idempotent
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS events (
+		chain_id     INTEGER NOT NULL,
+		contract     TEXT    NOT NULL,
+		tx_hash      TEXT    NOT NULL,
+		log_index    INTEGER NOT NULL,
+		block_number INTEGER NOT NULL,
+		block_time   INTEGER NOT NULL,
+		event        TEXT    NOT NULL,
+		address      TEXT    NOT NULL,
+		counterparty TEXT    NOT NULL DEFAULT '',
+		amount       TEXT    NOT NULL DEFAULT '0',
+		prev_amount  TEXT    NOT NULL DEFAULT '0',
+		PRIMARY KEY (chain_id, contract, tx_hash, log_index)
+	)`,
+	`CREATE INDEX IF NOT EXISTS events_block ON events (chain_id, contract, block_number, log_index)`,
+	`CREATE INDEX IF NOT EXISTS events_address ON events (address)`,
+	`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		key        TEXT    NOT NULL PRIMARY KEY,
+		operation  TEXT    NOT NULL,
+		request    TEXT    NOT NULL,
+		tx_hash    TEXT    NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS transactions (
+		hash         TEXT    NOT NULL PRIMARY KEY,
+		chain_id     INTEGER NOT NULL,
+		sender       TEXT    NOT NULL,
+		nonce        INTEGER NOT NULL,
+		operation    TEXT    NOT NULL,
+		target       TEXT    NOT NULL DEFAULT '',
+		amount       TEXT    NOT NULL DEFAULT '0',
+		requester    TEXT    NOT NULL DEFAULT '',
+		raw          BLOB    NOT NULL,
+		status       TEXT    NOT NULL,
+		block_number INTEGER NOT NULL DEFAULT 0,
+		gas_used     INTEGER NOT NULL DEFAULT 0,
+		attempts     INTEGER NOT NULL DEFAULT 0,
+		last_error   TEXT    NOT NULL DEFAULT '',
+		created_at   INTEGER NOT NULL,
+		updated_at   INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS transactions_status ON transactions (status)`,
+	`CREATE TABLE IF NOT EXISTS schedules (
+		name          TEXT    NOT NULL PRIMARY KEY,
+		registered_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS schedule_runs (
+		schedule     TEXT    NOT NULL,
+		scheduled_at INTEGER NOT NULL,
+		trigger      TEXT    NOT NULL,
+		missed       INTEGER NOT NULL DEFAULT 0,
+		status       TEXT    NOT NULL,
+		applied      INTEGER NOT NULL DEFAULT 0,
+		failed       INTEGER NOT NULL DEFAULT 0,
+		error        TEXT    NOT NULL DEFAULT '',
+		started_at   INTEGER NOT NULL,
+		finished_at  INTEGER NOT NULL DEFAULT 0,
+		manual       INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (schedule, scheduled_at, manual)
+	)`,
+	`CREATE TABLE IF NOT EXISTS proposals (
+		id         TEXT    NOT NULL PRIMARY KEY,
+		operation  TEXT    NOT NULL,
+		target     TEXT    NOT NULL DEFAULT '',
+		amount     INTEGER NOT NULL DEFAULT 0,
+		proposer   TEXT    NOT NULL DEFAULT '',
+		status     TEXT    NOT NULL,
+		tx_hash    TEXT    NOT NULL DEFAULT '',
+		error      TEXT    NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS proposal_decisions (
+		proposal_id TEXT    NOT NULL,
+		approver    TEXT    NOT NULL,
+		address     TEXT    NOT NULL,
+		decision    TEXT    NOT NULL,
+		signature   TEXT    NOT NULL,
+		created_at  INTEGER NOT NULL,
+		PRIMARY KEY (proposal_id, approver)
+	)`,
+	`CREATE TABLE IF NOT EXISTS spend_requests (
+		id          TEXT    NOT NULL PRIMARY KEY,
+		beneficiary TEXT    NOT NULL,
+		amount      INTEGER NOT NULL,
+		reason      TEXT    NOT NULL DEFAULT '',
+		reference   TEXT    NOT NULL,
+		signature   TEXT    NOT NULL,
+		status      TEXT    NOT NULL,
+		tx_hash     TEXT    NOT NULL DEFAULT '',
+		error       TEXT    NOT NULL DEFAULT '',
+		decided_by  TEXT    NOT NULL DEFAULT '',
+		created_at  INTEGER NOT NULL,
+		updated_at  INTEGER NOT NULL,
+		UNIQUE (beneficiary, reference)
+	)`,
+	`CREATE INDEX IF NOT EXISTS spend_requests_status ON spend_requests (status)`,
+	`CREATE TABLE IF NOT EXISTS contacts (
+		name       TEXT    NOT NULL PRIMARY KEY COLLATE NOCASE,
+		address    TEXT    NOT NULL UNIQUE,
+		tags       TEXT    NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS ens_records (
+		key         TEXT    NOT NULL PRIMARY KEY,
+		value       TEXT    NOT NULL,
+		resolved_at INTEGER NOT NULL
+	)`,
+}
+
+// Store embedded persistent store backed by SQLite
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the store at the given path, creating and migrating it when needed
+func Open(ctx context.Context, path string) (*Store, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)", path, busyTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if _, err := db.ExecContext(ctx, migration); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to migrate store: %w", err)
+		}
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
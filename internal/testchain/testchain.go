@@ -0,0 +1,104 @@
+package testchain
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/maxipaz/wallet/config"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// blockPeriod interval at which the blocks are mined
+const blockPeriod = 50 * time.Millisecond
+
+// Chain simulated chain for the tests, mining a block every blockPeriod. The account of Key is funded and is the
+// signer of the runners
+type Chain struct {
+	Backend *simulated.Backend
+	Client  *ethclient.Client
+	Key     *ecdsa.PrivateKey
+	// URL IPC endpoint of the node, for the code dialing the node itself
+	URL string
+}
+
+// New starts a simulated chain closed at the end of the test. The node is reached over IPC, the simulated backend
+// client is not an *ethclient.Client
+func New(t testing.TB) *Chain {
+	t.Helper()
+	return NewWithAlloc(t, nil)
+}
+
+// NewWithAlloc starts a simulated chain whose genesis also holds the given accounts, i.e.: contracts with their code
+// and storage
+func NewWithAlloc(t testing.TB, accounts types.GenesisAlloc) *Chain {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	funds := new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	alloc := types.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: funds}}
+	for address, account := range accounts {
+		alloc[address] = account
+	}
+
+	ipcPath := filepath.Join(t.TempDir(), "sim.ipc")
+	backend := simulated.NewBackend(alloc, func(nodeConf *node.Config, _ *ethconfig.Config) {
+		nodeConf.IPCPath = ipcPath
+	})
+
+	client, err := ethclient.Dial(ipcPath)
+	if err != nil {
+		_ = backend.Close()
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(blockPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				backend.Commit()
+			}
+		}
+	}()
+
+	previous := config.App.Blockchain
+	config.App.Blockchain.PrivateKey = hexKey(key)
+	config.App.Blockchain.ChainID = 0
+
+	t.Cleanup(func() {
+		close(done)
+		<-stopped
+		client.Close()
+		_ = backend.Close()
+		config.App.Blockchain = previous
+	})
+	return &Chain{Backend: backend, Client: client, Key: key, URL: ipcPath}
+}
+
+// hexKey returns the private key in the format of the blockchain.pk setting
+func hexKey(key *ecdsa.PrivateKey) string {
+	return common.Bytes2Hex(crypto.FromECDSA(key))
+}
+
+// Address returns the address of the funded account
+func (c *Chain) Address() common.Address {
+	return crypto.PubkeyToAddress(c.Key.PublicKey)
+}
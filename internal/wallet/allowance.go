@@ -2,14 +2,19 @@ package wallet
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	contracts "github.com/maxipaz/wallet/contracts/interfaces"
 	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/metrics"
 	"math/big"
+	"time"
 )
 
 const (
@@ -20,6 +25,7 @@ const (
 )
 
 type Allowance struct {
+	transactor
 	privateKey      string
 	contractAddress string
 }
@@ -41,7 +47,9 @@ func (r *Allowance) GetAllowance(ctx context.Context, client *ethclient.Client,
 
 	address := ethcommon.HexToAddress(beneficiaryAddress)
 
+	start := time.Now()
 	amount, err := contract.Allowance(&bind.CallOpts{Pending: false, Context: ctx}, address)
+	metrics.ObserveRPC("allowance", start)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get allowance: %w", err)
 	}
@@ -49,54 +57,119 @@ func (r *Allowance) GetAllowance(ctx context.Context, client *ethclient.Client,
 	return common.WeiToEther(amount).Int64(), nil
 }
 
-// ChangeAllowance change the Allowance value for a given address
-func (r *Allowance) ChangeAllowance(ctx context.Context, client *ethclient.Client, action string, target string, amount int64) error {
-	contract, err := common.GetContract(ctx, client, r.contractAddress)
-	if err != nil {
-		return fmt.Errorf("failed to get contract: %w", err)
+// GetAllowances returns the allowance of each given address, keyed by the address as given. The contract is checked
+// once and the allowances are read with JSON-RPC batch requests
+func (r *Allowance) GetAllowances(ctx context.Context, client *ethclient.Client, addresses []string) (map[string]int64, error) {
+	allowances := make(map[string]int64, len(addresses))
+	if len(addresses) == 0 {
+		return allowances, nil
 	}
 
-	signer, err := common.GetSigner(ctx, client)
+	if err := common.ValidateContractAddress(ctx, client, r.contractAddress); err != nil {
+		return nil, fmt.Errorf("failed to get contract: %w", err)
+	}
+	parsed, err := contracts.ContractMetaData.GetAbi()
 	if err != nil {
-		return fmt.Errorf("failed to get signer: %w", err)
+		return nil, fmt.Errorf("failed to get contract: %w", err)
 	}
 
-	var tx *types.Transaction
-	var txErr error
-	targetAddress := ethcommon.HexToAddress(target)
-
-	var operation string
-	switch action {
-	case SetAction:
-		{
-			tx, txErr = contract.SetAllowance(signer, targetAddress, common.EtherToWei(big.NewInt(amount)))
-			operation = "set_allowance"
+	contractAddress := ethcommon.HexToAddress(r.contractAddress)
+	results := make([]hexutil.Bytes, len(addresses))
+	calls := make([]rpc.BatchElem, len(addresses))
+	for i, address := range addresses {
+		data, err := parsed.Pack("allowance", ethcommon.HexToAddress(address))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get allowance of %s: %w", address, err)
 		}
-	case IncreaseAction:
-		{
-			tx, txErr = contract.IncreaseAllowance(signer, targetAddress, common.EtherToWei(big.NewInt(amount)))
-			operation = "increase_allowance"
+		calls[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []any{map[string]any{"to": contractAddress, "input": hexutil.Bytes(data)}, "latest"},
+			Result: &results[i],
 		}
-	case ReduceAction:
-		{
-			tx, txErr = contract.ReduceAllowance(signer, targetAddress, common.EtherToWei(big.NewInt(amount)))
-			operation = "reduce_allowance"
+	}
+
+	start := time.Now()
+	err = batchCall(ctx, client, calls)
+	metrics.ObserveRPC("allowances", start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowances: %w", err)
+	}
+
+	for i, address := range addresses {
+		values, err := parsed.Unpack("allowance", results[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get allowance of %s: %w", address, err)
 		}
+		allowances[address] = common.WeiToEther(values[0].(*big.Int)).Int64()
 	}
-	if txErr != nil {
-		return txErr
+	return allowances, nil
+}
+
+// ChangeAllowance change the Allowance value for a given address
+func (r *Allowance) ChangeAllowance(ctx context.Context, client *ethclient.Client, action string, target string, amount int64) (*TransactionResult, error) {
+	request, build, err := r.allowanceTx(ctx, client, action, target, amount)
+	if err != nil {
+		return nil, err
 	}
+	return r.transact(ctx, client, request, build)
+}
 
-	receipt, err := bind.WaitMined(ctx, client, tx)
+// SubmitChangeAllowance broadcasts the allowance change without waiting for it to be mined, see Pipeline and Wait
+func (r *Allowance) SubmitChangeAllowance(ctx context.Context, client *ethclient.Client, action string, target string, amount int64) (*PendingTransaction, error) {
+	request, build, err := r.allowanceTx(ctx, client, action, target, amount)
 	if err != nil {
-		return fmt.Errorf("failed to wait mined: %w", err)
+		return nil, err
 	}
+	return r.submit(ctx, client, request, build)
+}
 
-	if receipt.Status != types.ReceiptStatusSuccessful {
-		return errors.New("receipt status unsuccessful")
+// allowanceTx returns the request and the transaction builder of an allowance change
+func (r *Allowance) allowanceTx(ctx context.Context, client *ethclient.Client, action string, target string, amount int64) (txRequest, func(signer *bind.TransactOpts) (*types.Transaction, error), error) {
+	contract, err := common.GetContract(ctx, client, r.contractAddress)
+	if err != nil {
+		return txRequest{}, nil, fmt.Errorf("failed to get contract: %w", err)
+	}
+	if err := checkBeneficiary(ctx, client, target, r.contractAddress); err != nil {
+		return txRequest{}, nil, err
 	}
 
-	processTransaction(ctx, tx, operation)
+	targetAddress := ethcommon.HexToAddress(target)
+	value := common.EtherToWei(big.NewInt(amount))
+
+	var (
+		operation string
+		build     func(signer *bind.TransactOpts) (*types.Transaction, error)
+		// allowance resulting from the change, checked against the spending policy
+		allowance = amount
+	)
+	switch action {
+	case SetAction:
+		operation = "set_allowance"
+		build = func(signer *bind.TransactOpts) (*types.Transaction, error) {
+			return contract.SetAllowance(signer, targetAddress, value)
+		}
+	case IncreaseAction:
+		operation = "increase_allowance"
+		if r.policy != nil {
+			start := time.Now()
+			current, err := contract.Allowance(&bind.CallOpts{Context: ctx}, targetAddress)
+			metrics.ObserveRPC("allowance", start)
+			if err != nil {
+				return txRequest{}, nil, fmt.Errorf("failed to get allowance: %w", err)
+			}
+			allowance += common.WeiToEther(current).Int64()
+		}
+		build = func(signer *bind.TransactOpts) (*types.Transaction, error) {
+			return contract.IncreaseAllowance(signer, targetAddress, value)
+		}
+	case ReduceAction:
+		operation = "reduce_allowance"
+		build = func(signer *bind.TransactOpts) (*types.Transaction, error) {
+			return contract.ReduceAllowance(signer, targetAddress, value)
+		}
+	default:
+		return txRequest{}, nil, errs.ErrInvalidAllowanceAction
+	}
 
-	return nil
+	return txRequest{operation: operation, target: targetAddress.Hex(), amount: amount, allowance: allowance}, build, nil
 }
@@ -2,9 +2,14 @@ package wallet
 
 import (
 	"context"
+	"fmt"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	common2 "github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"time"
 )
 
 const (
@@ -16,6 +21,7 @@ const (
 type Balance interface {
 	GetContractBalance(ctx context.Context, client *ethclient.Client) (int64, error)
 	GetAddressBalance(ctx context.Context, client *ethclient.Client, address string) (int64, error)
+	GetAddressBalances(ctx context.Context, client *ethclient.Client, addresses []string) (map[string]int64, error)
 }
 
 type balance struct {
@@ -33,18 +39,53 @@ func NewBalanceRunner(privateKey string, contractAddress string) Balance {
 
 // GetContractBalance returns the contract balance
 func (b *balance) GetContractBalance(ctx context.Context, client *ethclient.Client) (int64, error) {
+	defer metrics.ObserveRPC("balance_at", time.Now())
+
 	value, err := client.BalanceAt(ctx, common.HexToAddress(b.contractAddress), nil)
 	if err != nil {
 		return 0, err
 	}
-	return common2.weiToEther(value).Int64(), nil
+	return common2.WeiToEther(value).Int64(), nil
 }
 
 // GetAddressBalance returns the balance of a given address
 func (b *balance) GetAddressBalance(ctx context.Context, client *ethclient.Client, address string) (int64, error) {
+	defer metrics.ObserveRPC("balance_at", time.Now())
+
 	value, err := client.BalanceAt(ctx, common.HexToAddress(address), nil)
 	if err != nil {
 		return 0, err
 	}
-	return common2.weiToEther(value).Int64(), nil
+	return common2.WeiToEther(value).Int64(), nil
+}
+
+// GetAddressBalances returns the balance of each given address, keyed by the address as given, read with JSON-RPC
+// batch requests
+func (b *balance) GetAddressBalances(ctx context.Context, client *ethclient.Client, addresses []string) (map[string]int64, error) {
+	balances := make(map[string]int64, len(addresses))
+	if len(addresses) == 0 {
+		return balances, nil
+	}
+
+	results := make([]hexutil.Big, len(addresses))
+	calls := make([]rpc.BatchElem, len(addresses))
+	for i, address := range addresses {
+		calls[i] = rpc.BatchElem{
+			Method: "eth_getBalance",
+			Args:   []any{common.HexToAddress(address), "latest"},
+			Result: &results[i],
+		}
+	}
+
+	start := time.Now()
+	err := batchCall(ctx, client, calls)
+	metrics.ObserveRPC("balances", start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+
+	for i, address := range addresses {
+		balances[address] = common2.WeiToEther(results[i].ToInt()).Int64()
+	}
+	return balances, nil
 }
@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	contracts "github.com/maxipaz/wallet/contracts/interfaces"
+	"math/big"
+	"strings"
+)
+
+var errUnknownEvent = errors.New("unknown event")
+
+// contractABI parsed contract ABI used to identify the event logs
+var contractABI, _ = abi.JSON(strings.NewReader(contracts.ContractABI))
+
+// DecodeEvent decode a contract log into its binding: *contracts.ContractAllowanceChanged, *contracts.ContractMoneySent,
+// *contracts.ContractMoneyReceived or *contracts.ContractOwnershipTransferred
+func DecodeEvent(contract *contracts.Contract, log types.Log) (any, error) {
+	if len(log.Topics) == 0 {
+		return nil, errUnknownEvent
+	}
+
+	switch log.Topics[0] {
+	case contractABI.Events["AllowanceChanged"].ID:
+		return contract.ParseAllowanceChanged(log)
+	case contractABI.Events["MoneySent"].ID:
+		return contract.ParseMoneySent(log)
+	case contractABI.Events["MoneyReceived"].ID:
+		return contract.ParseMoneyReceived(log)
+	case contractABI.Events["OwnershipTransferred"].ID:
+		return contract.ParseOwnershipTransferred(log)
+	}
+
+	return nil, errUnknownEvent
+}
+
+// FilterEvents returns the decoded contract events emitted between the given blocks, both included. A nil toBlock
+// means up to the latest block
+func FilterEvents(ctx context.Context, client *ethclient.Client, contractAddress string, fromBlock uint64, toBlock *big.Int) ([]any, error) {
+	address := ethcommon.HexToAddress(contractAddress)
+	contract, err := contracts.NewContract(address, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contract instance: %w", err)
+	}
+
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   toBlock,
+		Addresses: []ethcommon.Address{address},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter logs: %w", err)
+	}
+
+	events := make([]any, 0, len(logs))
+	for _, log := range logs {
+		event, err := DecodeEvent(contract, log)
+		if errors.Is(err, errUnknownEvent) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode log %s:%d: %w", log.TxHash.Hex(), log.Index, err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -6,33 +6,53 @@ import (
 	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
 	contracts "github.com/maxipaz/wallet/contracts/interfaces"
 	"github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/contacts"
+	"github.com/maxipaz/wallet/internal/metrics"
 	"golang.org/x/sync/errgroup"
 	"log/slog"
 	"math/big"
+	"sync/atomic"
 	"time"
 )
 
+// resubscribeBackoff maximum time to wait between attempts to re-establish a failed subscription
+const resubscribeBackoff = 30 * time.Second
+
+// Handler is notified of every contract event received by the monitor. The event is one of the contract bindings:
+// *contracts.ContractAllowanceChanged, *contracts.ContractMoneySent, *contracts.ContractMoneyReceived or
+// *contracts.ContractOwnershipTransferred
+type Handler interface {
+	Handle(ctx context.Context, event any)
+}
+
 type Monitor struct {
-	contractAddress string
+	contractAddress    string
+	lastProcessedBlock atomic.Uint64
+	handlers           []Handler
 }
 
 // AllowanceChangedEvent struct
 type AllowanceChangedEvent struct {
-	Event       string    `json:"event_type"`
-	Sender      string    `json:"sender"`
-	Beneficiary string    `json:"beneficiary"`
-	PrevAmount  *big.Int  `json:"prev_amount"`
-	NewAmount   *big.Int  `json:"new_amount"`
-	Timestamp   time.Time `json:"timestamp"`
+	Event           string    `json:"event_type"`
+	Sender          string    `json:"sender"`
+	SenderName      string    `json:"sender_name,omitempty"`
+	Beneficiary     string    `json:"beneficiary"`
+	BeneficiaryName string    `json:"beneficiary_name,omitempty"`
+	PrevAmount      *big.Int  `json:"prev_amount"`
+	NewAmount       *big.Int  `json:"new_amount"`
+	Timestamp       time.Time `json:"timestamp"`
 }
 
 // MoneyReceivedEvent struct
 type MoneyReceivedEvent struct {
 	Event       string    `json:"event_type"`
 	Sender      string    `json:"sender"`
+	SenderName  string    `json:"sender_name,omitempty"`
 	BlockNumber uint64    `json:"block_number"`
 	Amount      *big.Int  `json:"amount"`
 	Timestamp   time.Time `json:"timestamp"`
@@ -40,20 +60,23 @@ type MoneyReceivedEvent struct {
 
 // MoneySentEvent struct
 type MoneySentEvent struct {
-	Event       string    `json:"event_type"`
-	Beneficiary string    `json:"beneficiary"`
-	BlockNumber uint64    `json:"block_number"`
-	Amount      *big.Int  `json:"amount"`
-	Timestamp   time.Time `json:"timestamp"`
+	Event           string    `json:"event_type"`
+	Beneficiary     string    `json:"beneficiary"`
+	BeneficiaryName string    `json:"beneficiary_name,omitempty"`
+	BlockNumber     uint64    `json:"block_number"`
+	Amount          *big.Int  `json:"amount"`
+	Timestamp       time.Time `json:"timestamp"`
 }
 
 // OwnershipTransferredEvent struct
 type OwnershipTransferredEvent struct {
-	Event         string    `json:"event_type"`
-	PreviousOwner string    `json:"previous_owner"`
-	NewOwner      string    `json:"new_owner"`
-	BlockNumber   uint64    `json:"block_number"`
-	Timestamp     time.Time `json:"timestamp"`
+	Event             string    `json:"event_type"`
+	PreviousOwner     string    `json:"previous_owner"`
+	PreviousOwnerName string    `json:"previous_owner_name,omitempty"`
+	NewOwner          string    `json:"new_owner"`
+	NewOwnerName      string    `json:"new_owner_name,omitempty"`
+	BlockNumber       uint64    `json:"block_number"`
+	Timestamp         time.Time `json:"timestamp"`
 }
 
 // NewMonitor returns a new runner instance
@@ -63,6 +86,11 @@ func NewMonitor(contractAddress string) *Monitor {
 	}
 }
 
+// AddHandler register a handler to be notified of the contract events. It must be called before Start
+func (m *Monitor) AddHandler(handler Handler) {
+	m.handlers = append(m.handlers, handler)
+}
+
 // Start register to listen blockchain events
 func (m *Monitor) Start(ctx context.Context, client *ethclient.Client) error {
 	slog.DebugContext(ctx, "start monitoring", slog.String("contract_address", m.contractAddress))
@@ -76,17 +104,23 @@ func (m *Monitor) Start(ctx context.Context, client *ethclient.Client) error {
 		return fmt.Errorf("failed to create contract instance: %w", err)
 	}
 
+	m.updateContractBalance(ctx, client)
+
 	eg := new(errgroup.Group)
+	eg.Go(func() error {
+		return m.watchHeads(ctx, client)
+	})
+
 	eg.Go(func() error {
 		return m.watchAllowanceChanged(ctx, contract)
 	})
 
 	eg.Go(func() error {
-		return m.watchMoneySent(ctx, contract)
+		return m.watchMoneySent(ctx, client, contract)
 	})
 
 	eg.Go(func() error {
-		return m.watchMoneyReceived(ctx, contract)
+		return m.watchMoneyReceived(ctx, client, contract)
 	})
 
 	eg.Go(func() error {
@@ -99,14 +133,9 @@ func (m *Monitor) Start(ctx context.Context, client *ethclient.Client) error {
 func (m *Monitor) watchAllowanceChanged(ctx context.Context, contract *contracts.Contract) error {
 	events := make(chan *contracts.ContractAllowanceChanged)
 
-	subscription, err := contract.WatchAllowanceChanged(&bind.WatchOpts{
-		Start:   nil,
-		Context: ctx,
-	}, events, nil, nil)
-	if err != nil {
-		return err
-	}
-
+	subscription := m.subscribe(ctx, "AllowanceChanged", func(opts *bind.WatchOpts) (event.Subscription, error) {
+		return contract.WatchAllowanceChanged(opts, events, nil, nil)
+	})
 	defer subscription.Unsubscribe()
 
 	for {
@@ -116,14 +145,19 @@ func (m *Monitor) watchAllowanceChanged(ctx context.Context, contract *contracts
 		case errChan := <-subscription.Err():
 			return errChan
 		case event := <-events:
+			m.processed(ctx, "AllowanceChanged", event.Raw.BlockNumber, event)
+			metrics.BeneficiaryAllowance.WithLabelValues(event.Beneficiary.Hex()).Set(metrics.WeiToEther(event.NewAmount))
+
 			j, err := json.MarshalIndent(
 				AllowanceChangedEvent{
-					Event:       "AllowanceChanged",
-					Sender:      event.Sender.Hex(),
-					Beneficiary: event.Beneficiary.Hex(),
-					PrevAmount:  common.WeiToEther(event.PrevAmount),
-					NewAmount:   common.WeiToEther(event.NewAmount),
-					Timestamp:   time.Now().UTC(),
+					Event:           "AllowanceChanged",
+					Sender:          event.Sender.Hex(),
+					SenderName:      contacts.Label(event.Sender.Hex()),
+					Beneficiary:     event.Beneficiary.Hex(),
+					BeneficiaryName: contacts.Label(event.Beneficiary.Hex()),
+					PrevAmount:      common.WeiToEther(event.PrevAmount),
+					NewAmount:       common.WeiToEther(event.NewAmount),
+					Timestamp:       time.Now().UTC(),
 				},
 				"",
 				"  ",
@@ -138,17 +172,12 @@ func (m *Monitor) watchAllowanceChanged(ctx context.Context, contract *contracts
 	}
 }
 
-func (m *Monitor) watchMoneySent(ctx context.Context, contract *contracts.Contract) error {
+func (m *Monitor) watchMoneySent(ctx context.Context, client *ethclient.Client, contract *contracts.Contract) error {
 	events := make(chan *contracts.ContractMoneySent)
 
-	subscription, err := contract.WatchMoneySent(&bind.WatchOpts{
-		Start:   nil,
-		Context: ctx,
-	}, events, nil)
-	if err != nil {
-		return err
-	}
-
+	subscription := m.subscribe(ctx, "MoneySent", func(opts *bind.WatchOpts) (event.Subscription, error) {
+		return contract.WatchMoneySent(opts, events, nil)
+	})
 	defer subscription.Unsubscribe()
 
 	for {
@@ -158,13 +187,17 @@ func (m *Monitor) watchMoneySent(ctx context.Context, contract *contracts.Contra
 		case errChan := <-subscription.Err():
 			return errChan
 		case event := <-events:
+			m.processed(ctx, "MoneySent", event.Raw.BlockNumber, event)
+			m.updateContractBalance(ctx, client)
+
 			j, err := json.MarshalIndent(
 				MoneySentEvent{
-					Event:       "MoneySent",
-					Beneficiary: event.Beneficiary.Hex(),
-					BlockNumber: event.Raw.BlockNumber,
-					Amount:      common.WeiToEther(event.Amount),
-					Timestamp:   time.Now().UTC(),
+					Event:           "MoneySent",
+					Beneficiary:     event.Beneficiary.Hex(),
+					BeneficiaryName: contacts.Label(event.Beneficiary.Hex()),
+					BlockNumber:     event.Raw.BlockNumber,
+					Amount:          common.WeiToEther(event.Amount),
+					Timestamp:       time.Now().UTC(),
 				},
 				"",
 				"  ",
@@ -179,16 +212,12 @@ func (m *Monitor) watchMoneySent(ctx context.Context, contract *contracts.Contra
 	}
 }
 
-func (m *Monitor) watchMoneyReceived(ctx context.Context, contract *contracts.Contract) error {
+func (m *Monitor) watchMoneyReceived(ctx context.Context, client *ethclient.Client, contract *contracts.Contract) error {
 	events := make(chan *contracts.ContractMoneyReceived)
 
-	subscription, err := contract.WatchMoneyReceived(&bind.WatchOpts{
-		Start:   nil,
-		Context: ctx,
-	}, events, nil)
-	if err != nil {
-		return err
-	}
+	subscription := m.subscribe(ctx, "MoneyReceived", func(opts *bind.WatchOpts) (event.Subscription, error) {
+		return contract.WatchMoneyReceived(opts, events, nil)
+	})
 	defer subscription.Unsubscribe()
 
 	for {
@@ -198,10 +227,14 @@ func (m *Monitor) watchMoneyReceived(ctx context.Context, contract *contracts.Co
 		case errChan := <-subscription.Err():
 			return errChan
 		case event := <-events:
+			m.processed(ctx, "MoneyReceived", event.Raw.BlockNumber, event)
+			m.updateContractBalance(ctx, client)
+
 			j, err := json.MarshalIndent(
 				MoneyReceivedEvent{
 					Event:       "MoneyReceived",
 					Sender:      event.From.Hex(),
+					SenderName:  contacts.Label(event.From.Hex()),
 					BlockNumber: event.Raw.BlockNumber,
 					Amount:      common.WeiToEther(event.Amount),
 					Timestamp:   time.Now(),
@@ -222,13 +255,9 @@ func (m *Monitor) watchMoneyReceived(ctx context.Context, contract *contracts.Co
 func (m *Monitor) watchOwnershipTransferred(ctx context.Context, contract *contracts.Contract) error {
 	events := make(chan *contracts.ContractOwnershipTransferred)
 
-	subscription, err := contract.WatchOwnershipTransferred(&bind.WatchOpts{
-		Start:   nil,
-		Context: ctx,
-	}, events, nil, nil)
-	if err != nil {
-		return err
-	}
+	subscription := m.subscribe(ctx, "OwnershipTransferred", func(opts *bind.WatchOpts) (event.Subscription, error) {
+		return contract.WatchOwnershipTransferred(opts, events, nil, nil)
+	})
 	defer subscription.Unsubscribe()
 
 	for {
@@ -238,13 +267,17 @@ func (m *Monitor) watchOwnershipTransferred(ctx context.Context, contract *contr
 		case errChan := <-subscription.Err():
 			return errChan
 		case event := <-events:
+			m.processed(ctx, "OwnershipTransferred", event.Raw.BlockNumber, event)
+
 			j, err := json.MarshalIndent(
 				OwnershipTransferredEvent{
-					Event:         "OwnershipTransferred",
-					PreviousOwner: event.PreviousOwner.Hex(),
-					NewOwner:      event.NewOwner.Hex(),
-					BlockNumber:   event.Raw.BlockNumber,
-					Timestamp:     time.Now(),
+					Event:             "OwnershipTransferred",
+					PreviousOwner:     event.PreviousOwner.Hex(),
+					PreviousOwnerName: contacts.Label(event.PreviousOwner.Hex()),
+					NewOwner:          event.NewOwner.Hex(),
+					NewOwnerName:      contacts.Label(event.NewOwner.Hex()),
+					BlockNumber:       event.Raw.BlockNumber,
+					Timestamp:         time.Now(),
 				},
 				"",
 				"  ",
@@ -258,3 +291,74 @@ func (m *Monitor) watchOwnershipTransferred(ctx context.Context, contract *contr
 		}
 	}
 }
+
+func (m *Monitor) watchHeads(ctx context.Context, client *ethclient.Client) error {
+	headers := make(chan *types.Header)
+
+	subscription := m.subscribe(ctx, "NewHead", func(opts *bind.WatchOpts) (event.Subscription, error) {
+		return client.SubscribeNewHead(opts.Context, headers)
+	})
+	defer subscription.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case errChan := <-subscription.Err():
+			return errChan
+		case header := <-headers:
+			head := header.Number.Uint64()
+			metrics.HeadBlock.Set(float64(head))
+			if last := m.lastProcessedBlock.Load(); last > 0 && head >= last {
+				metrics.BlockLag.Set(float64(head - last))
+			}
+		}
+	}
+}
+
+// subscribe keeps a subscription alive, re-establishing it with a backoff whenever it fails
+func (m *Monitor) subscribe(ctx context.Context, name string, watch func(opts *bind.WatchOpts) (event.Subscription, error)) event.Subscription {
+	return event.ResubscribeErr(resubscribeBackoff, func(ctx context.Context, lastErr error) (event.Subscription, error) {
+		if lastErr != nil {
+			slog.WarnContext(ctx, "subscription failed, resubscribing", slog.String("subscription", name), slog.String("error", lastErr.Error()))
+			metrics.SubscriptionReconnects.WithLabelValues(name).Inc()
+		}
+
+		subscription, err := watch(&bind.WatchOpts{Context: ctx})
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to subscribe", slog.String("subscription", name), slog.String("error", err.Error()))
+			return nil, err
+		}
+		return subscription, nil
+	})
+}
+
+// processed records an event as processed by the monitor and notifies the handlers
+func (m *Monitor) processed(ctx context.Context, name string, blockNumber uint64, event any) {
+	metrics.EventsTotal.WithLabelValues(name).Inc()
+
+	for {
+		last := m.lastProcessedBlock.Load()
+		if blockNumber <= last || m.lastProcessedBlock.CompareAndSwap(last, blockNumber) {
+			break
+		}
+	}
+	metrics.LastProcessedBlock.Set(float64(m.lastProcessedBlock.Load()))
+
+	for _, handler := range m.handlers {
+		handler.Handle(ctx, event)
+	}
+}
+
+// updateContractBalance refresh the contract balance gauge
+func (m *Monitor) updateContractBalance(ctx context.Context, client *ethclient.Client) {
+	start := time.Now()
+	balance, err := client.BalanceAt(ctx, ethcommon.HexToAddress(m.contractAddress), nil)
+	metrics.ObserveRPC("balance_at", start)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get contract balance", slog.String("error", err.Error()))
+		return
+	}
+
+	metrics.ContractBalance.Set(metrics.WeiToEther(balance))
+}
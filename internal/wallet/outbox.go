@@ -0,0 +1,171 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/internal/store"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// maxBroadcastAttempts broadcast attempts after which a transaction rejected by the node is no longer tracked
+const maxBroadcastAttempts = 10
+
+// Outbox tracks the signed transactions recorded in the store until they reach a final status, broadcasting again
+// the ones dropped from the mempool
+type Outbox struct {
+	store *store.Store
+}
+
+// NewOutbox returns a new outbox instance
+func NewOutbox(st *store.Store) *Outbox {
+	return &Outbox{
+		store: st,
+	}
+}
+
+// Start checks the pending transactions every interval until the context is cancelled
+func (o *Outbox) Start(ctx context.Context, client *ethclient.Client, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := o.Resume(ctx, client); err != nil {
+			slog.ErrorContext(ctx, "failed to resume outbox", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resume checks once every transaction without final status of the client chain and returns how many are still
+// pending
+func (o *Outbox) Resume(ctx context.Context, client *ethclient.Client) (int, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get chain id: %w", err)
+	}
+
+	transactions, err := o.store.PendingTransactions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, tx := range transactions {
+		if tx.ChainID != chainID.Uint64() {
+			continue
+		}
+
+		if err := o.check(ctx, client, tx); err != nil {
+			slog.WarnContext(ctx, "failed to check transaction",
+				slog.String("tx_hash", tx.Hash),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		current, err := o.store.GetTransaction(ctx, tx.Hash)
+		if err != nil {
+			return 0, err
+		}
+		if current != nil && !current.Final() {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// check updates the status of a recorded transaction. A transaction mined records its receipt, a transaction unknown
+// by the node is broadcast again unless its nonce was already used
+func (o *Outbox) check(ctx context.Context, client *ethclient.Client, tx store.Transaction) error {
+	hash := ethcommon.HexToHash(tx.Hash)
+
+	mined, err := o.checkReceipt(ctx, client, hash)
+	if err != nil || mined {
+		return err
+	}
+
+	_, _, err = client.TransactionByHash(ctx, hash)
+	if err == nil {
+		if tx.Status == store.TxSigned {
+			return o.store.RecordBroadcast(ctx, tx.Hash, nil)
+		}
+		return nil
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return fmt.Errorf("failed to get transaction %s: %w", tx.Hash, err)
+	}
+
+	nonce, err := client.NonceAt(ctx, ethcommon.HexToAddress(tx.Sender), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get nonce: %w", err)
+	}
+	if nonce > tx.Nonce {
+		// the receipt is checked again, the transaction might have been mined since the first check
+		if mined, err := o.checkReceipt(ctx, client, hash); err != nil || mined {
+			return err
+		}
+		slog.WarnContext(ctx, "transaction replaced", slog.String("tx_hash", tx.Hash), slog.Uint64("nonce", tx.Nonce))
+		return o.store.SetTransactionStatus(ctx, tx.Hash, store.TxReplaced, 0, 0)
+	}
+
+	if tx.Attempts >= maxBroadcastAttempts {
+		slog.ErrorContext(ctx, "transaction dropped",
+			slog.String("tx_hash", tx.Hash),
+			slog.Int("attempts", tx.Attempts),
+			slog.String("error", tx.LastError),
+		)
+		return o.store.SetTransactionStatus(ctx, tx.Hash, store.TxDropped, 0, 0)
+	}
+
+	return o.rebroadcast(ctx, client, tx)
+}
+
+// checkReceipt records the status of a mined transaction, it reports whether the transaction was mined
+func (o *Outbox) checkReceipt(ctx context.Context, client *ethclient.Client, hash ethcommon.Hash) (bool, error) {
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get receipt %s: %w", hash.Hex(), err)
+	}
+
+	status := store.TxMined
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		status = store.TxFailed
+	}
+	return true, o.store.SetTransactionStatus(ctx, hash.Hex(), status, receipt.BlockNumber.Uint64(), receipt.GasUsed)
+}
+
+// rebroadcast broadcasts again the raw signed transaction
+func (o *Outbox) rebroadcast(ctx context.Context, client *ethclient.Client, tx store.Transaction) error {
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(tx.Raw); err != nil {
+		return fmt.Errorf("failed to decode transaction %s: %w", tx.Hash, err)
+	}
+
+	err := client.SendTransaction(ctx, signed)
+	if err != nil && strings.Contains(err.Error(), "already known") {
+		err = nil
+	}
+	if recordErr := o.store.RecordBroadcast(ctx, tx.Hash, err); recordErr != nil {
+		return recordErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to broadcast transaction %s: %w", tx.Hash, err)
+	}
+
+	slog.InfoContext(ctx, "transaction broadcast again", slog.String("tx_hash", tx.Hash), slog.Uint64("nonce", tx.Nonce))
+	return nil
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/maxipaz/wallet/internal/store"
+	"testing"
+)
+
+// signPayout signs a payout of the beneficiary and records it in the outbox without broadcasting it, i.e. the process
+// stopped before the broadcast
+func (w *testWallet) signPayout(t *testing.T, amount int64) *types.Transaction {
+	t.Helper()
+	ctx := w.context(t, "")
+	request, build, err := w.transfers.sendTx(ctx, w.chain.Client, w.beneficiary, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := w.transfers.sign(ctx, w.chain.Client, request, build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// status returns the status of a transaction in the outbox
+func (w *testWallet) status(t *testing.T, hash ethcommon.Hash) string {
+	t.Helper()
+	stored, err := w.store.GetTransaction(context.Background(), hash.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored.Status
+}
+
+func TestOutboxBroadcastsSigned(t *testing.T) {
+	w := newTestWallet(t)
+	w.setAllowance(t, 5)
+	ctx := w.context(t, "")
+	outbox := NewOutbox(w.store)
+
+	tx := w.signPayout(t, 1)
+	if status := w.status(t, tx.Hash()); status != store.TxSigned {
+		t.Fatalf("the payout is %s, expected %s", status, store.TxSigned)
+	}
+
+	pending, err := outbox.Resume(ctx, w.chain.Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 1 || w.status(t, tx.Hash()) != store.TxPending {
+		t.Fatalf("%d pending, the payout is %s, expected it to be broadcast", pending, w.status(t, tx.Hash()))
+	}
+
+	if _, err := bind.WaitMined(ctx, w.chain.Client, tx); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err = outbox.Resume(ctx, w.chain.Client); err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 || w.status(t, tx.Hash()) != store.TxMined {
+		t.Fatalf("%d pending, the payout is %s, expected it to be mined", pending, w.status(t, tx.Hash()))
+	}
+}
+
+func TestOutboxReplaced(t *testing.T) {
+	w := newTestWallet(t)
+	w.setAllowance(t, 5)
+	ctx := w.context(t, "invoice-1")
+	outbox := NewOutbox(w.store)
+
+	// the key is bound to a payout never broadcast, whose nonce is then used by another payout
+	replaced := w.signPayout(t, 1)
+	request, _, err := w.transfers.sendTx(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := w.store.ReserveIdempotencyKey(ctx, "invoice-1", request.operation, request.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.store.SetIdempotencyTx(ctx, "invoice-1", replaced.Hash().Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := w.transfers.Send(w.context(t, ""), w.chain.Client, w.beneficiary, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.TxHash == replaced.Hash().Hex() {
+		t.Fatal("the payouts are the same transaction")
+	}
+
+	if _, err := outbox.Resume(ctx, w.chain.Client); err != nil {
+		t.Fatal(err)
+	}
+	if status := w.status(t, replaced.Hash()); status != store.TxReplaced {
+		t.Fatalf("the payout is %s, expected %s", status, store.TxReplaced)
+	}
+
+	// the replaced payout will never be mined, the retry signs a new one
+	retry, err := w.transfers.Send(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.TxHash == replaced.Hash().Hex() || retry.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("retry returned %s with status %d, expected a new successful transaction", retry.TxHash, retry.Status)
+	}
+}
@@ -7,15 +7,22 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	common2 "github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"time"
 )
 
 // Owner interface
 type Owner interface {
 	GetOwner(ctx context.Context, client *ethclient.Client) (string, error)
-	TransferOwner(ctx context.Context, client *ethclient.Client, targetAddress string) error
+	TransferOwner(ctx context.Context, client *ethclient.Client, targetAddress string) (*TransactionResult, error)
+	SetStore(st *store.Store)
+	SetPolicy(p *policy.Policy)
 }
 
 type owner struct {
+	transactor
 	privateKey      string
 	contractAddress string
 }
@@ -30,37 +37,34 @@ func NewOwnerRunner(privateKey string, contractAddress string) Owner {
 
 // GetOwner returns the contract owner address
 func (o *owner) GetOwner(ctx context.Context, client *ethclient.Client) (string, error) {
-	contract, err := common2.getContract(ctx, client, o.contractAddress)
+	contract, err := common2.GetContract(ctx, client, o.contractAddress)
 	if err != nil {
 		return "", err
 	}
 
+	start := time.Now()
 	ownerAddress, err := contract.Owner(&bind.CallOpts{Pending: false, Context: ctx})
+	metrics.ObserveRPC("owner", start)
 	if err != nil {
 		return "", err
 	}
 	return ownerAddress.Hex(), nil
 }
 
-// TransferOwner transfer the ownership to a target address
-func (o *owner) TransferOwner(ctx context.Context, client *ethclient.Client, targetAddress string) error {
-	contract, err := common2.getContract(ctx, client, o.contractAddress)
-	if err != nil {
-		return err
+// TransferOwner transfer the ownership to a target address. The zero address, the burn addresses and the contract itself
+// are rejected, the ownership could never be recovered from them
+func (o *owner) TransferOwner(ctx context.Context, client *ethclient.Client, targetAddress string) (*TransactionResult, error) {
+	if err := common2.ValidateBeneficiary(targetAddress, o.contractAddress); err != nil {
+		return nil, err
 	}
-	signer, err := common2.getSigner(ctx, client)
+
+	contract, err := common2.GetContract(ctx, client, o.contractAddress)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	tx, txErr := contract.TransferOwnership(signer, common.HexToAddress(targetAddress))
-	if txErr != nil {
-		return txErr
-	}
-	receipt, err := bind.WaitMined(ctx, client, tx)
-	if receipt.Status != types.ReceiptStatusSuccessful || err != nil {
-		return err
-	}
-	processTransaction(ctx, tx, "transfer owner")
-	return nil
+	target := common.HexToAddress(targetAddress)
+	return o.transact(ctx, client, txRequest{operation: "transfer_ownership", target: target.Hex()}, func(signer *bind.TransactOpts) (*types.Transaction, error) {
+		return contract.TransferOwnership(signer, target)
+	})
 }
@@ -0,0 +1,28 @@
+package wallet
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// readBatchSize number of calls sent in a single JSON-RPC batch request, below the default limit of the nodes
+const readBatchSize = 100
+
+// batchCall sends the calls in JSON-RPC batch requests of at most readBatchSize calls, so reading many addresses takes
+// a few round trips instead of one per address. It returns the first error of the calls
+func batchCall(ctx context.Context, client *ethclient.Client, calls []rpc.BatchElem) error {
+	for start := 0; start < len(calls); start += readBatchSize {
+		end := min(start+readBatchSize, len(calls))
+		if err := client.Client().BatchCallContext(ctx, calls[start:end]); err != nil {
+			return err
+		}
+	}
+
+	for _, call := range calls {
+		if call.Error != nil {
+			return call.Error
+		}
+	}
+	return nil
+}
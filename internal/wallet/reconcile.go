@@ -0,0 +1,258 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	contracts "github.com/maxipaz/wallet/contracts/interfaces"
+	"github.com/maxipaz/wallet/internal/common"
+	"log/slog"
+	"math/big"
+	"sort"
+)
+
+// Reconciliation result of comparing the on-chain state with the replayed events history. Amounts are in Wei
+type Reconciliation struct {
+	Block           uint64   `json:"block"`
+	ExpectedBalance *big.Int `json:"expected_balance"`
+	ActualBalance   *big.Int `json:"actual_balance"`
+	// BalanceFirstBlock first block where the balance diverges, nil when it matches or the block is unknown
+	BalanceFirstBlock *uint64          `json:"balance_first_block,omitempty"`
+	Allowances        []AllowanceCheck `json:"allowances"`
+	// Issues inconsistencies found in the events history itself
+	Issues []HistoryIssue `json:"issues"`
+}
+
+// AllowanceCheck comparison of a beneficiary allowance
+type AllowanceCheck struct {
+	Beneficiary string   `json:"beneficiary"`
+	Expected    *big.Int `json:"expected"`
+	Actual      *big.Int `json:"actual"`
+	// FirstBlock first block where the allowance diverges, nil when it matches or the block is unknown
+	FirstBlock *uint64 `json:"first_block,omitempty"`
+}
+
+// HistoryIssue inconsistency found while replaying the events
+type HistoryIssue struct {
+	Block       uint64 `json:"block"`
+	TxHash      string `json:"tx_hash"`
+	Description string `json:"description"`
+}
+
+// Matches reports whether the allowance matches the replayed history
+func (c AllowanceCheck) Matches() bool {
+	return c.Expected.Cmp(c.Actual) == 0
+}
+
+// BalanceMatches reports whether the contract balance matches the replayed history
+func (r *Reconciliation) BalanceMatches() bool {
+	return r.ExpectedBalance.Cmp(r.ActualBalance) == 0
+}
+
+// Matches reports whether the on-chain state and the events history fully agree
+func (r *Reconciliation) Matches() bool {
+	if !r.BalanceMatches() || len(r.Issues) > 0 {
+		return false
+	}
+	for _, allowance := range r.Allowances {
+		if !allowance.Matches() {
+			return false
+		}
+	}
+	return true
+}
+
+// change value of a replayed amount from a given block
+type change struct {
+	block uint64
+	value *big.Int
+}
+
+// history replayed values of an amount ordered by block
+type history []change
+
+// at returns the replayed value at the given block
+func (h history) at(block uint64) *big.Int {
+	i := sort.Search(len(h), func(i int) bool {
+		return h[i].block > block
+	})
+	if i == 0 {
+		return new(big.Int)
+	}
+	return h[i-1].value
+}
+
+// current returns the last replayed value
+func (h history) current() *big.Int {
+	if len(h) == 0 {
+		return new(big.Int)
+	}
+	return h[len(h)-1].value
+}
+
+// record sets the value from the given block
+func (h history) record(block uint64, value *big.Int) history {
+	if len(h) > 0 && h[len(h)-1].block == block {
+		h[len(h)-1].value = value
+		return h
+	}
+	return append(h, change{block: block, value: value})
+}
+
+type Reconciler struct {
+	contractAddress string
+}
+
+// NewReconcileRunner returns a new runner instance
+func NewReconcileRunner(contractAddress string) *Reconciler {
+	return &Reconciler{
+		contractAddress: contractAddress,
+	}
+}
+
+// Reconcile replays the contract events up to the given block, or the latest one when nil, and compares the expected
+// allowances and contract balance with the on-chain state at that block
+func (r *Reconciler) Reconcile(ctx context.Context, client *ethclient.Client, block *uint64) (*Reconciliation, error) {
+	contract, err := common.GetContract(ctx, client, r.contractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract: %w", err)
+	}
+
+	if block == nil {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest block: %w", err)
+		}
+		block = &head
+	}
+	target := new(big.Int).SetUint64(*block)
+
+	events, err := FilterEvents(ctx, client, r.contractAddress, 0, target)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Reconciliation{Block: *block}
+	var (
+		firstBlock = *block
+		balance    history
+		allowances = make(map[ethcommon.Address]history)
+		// changes allowance changes by transaction and beneficiary, used to match the payouts reductions
+		changes = make(map[string]*contracts.ContractAllowanceChanged)
+	)
+
+	for _, event := range events {
+		if changed, ok := event.(*contracts.ContractAllowanceChanged); ok {
+			changes[changed.Raw.TxHash.Hex()+changed.Beneficiary.Hex()] = changed
+		}
+	}
+
+	for _, event := range events {
+		switch event := event.(type) {
+		case *contracts.ContractAllowanceChanged:
+			firstBlock = min(firstBlock, event.Raw.BlockNumber)
+			expected := allowances[event.Beneficiary].current()
+			if event.PrevAmount.Cmp(expected) != 0 {
+				result.Issues = append(result.Issues, HistoryIssue{
+					Block:  event.Raw.BlockNumber,
+					TxHash: event.Raw.TxHash.Hex(),
+					Description: fmt.Sprintf("allowance of %s changed from %s but the replayed value is %s",
+						event.Beneficiary.Hex(), event.PrevAmount, expected),
+				})
+			}
+			allowances[event.Beneficiary] = allowances[event.Beneficiary].record(event.Raw.BlockNumber, event.NewAmount)
+		case *contracts.ContractMoneySent:
+			firstBlock = min(firstBlock, event.Raw.BlockNumber)
+			balance = balance.record(event.Raw.BlockNumber, new(big.Int).Sub(balance.current(), event.Amount))
+			if issue := r.checkPayout(changes[event.Raw.TxHash.Hex()+event.Beneficiary.Hex()], event); issue != nil {
+				result.Issues = append(result.Issues, *issue)
+			}
+		case *contracts.ContractMoneyReceived:
+			firstBlock = min(firstBlock, event.Raw.BlockNumber)
+			balance = balance.record(event.Raw.BlockNumber, new(big.Int).Add(balance.current(), event.Amount))
+		case *contracts.ContractOwnershipTransferred:
+			firstBlock = min(firstBlock, event.Raw.BlockNumber)
+		}
+	}
+
+	result.ExpectedBalance = balance.current()
+	result.ActualBalance, err = client.BalanceAt(ctx, ethcommon.HexToAddress(r.contractAddress), target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract balance: %w", err)
+	}
+	if !result.BalanceMatches() {
+		result.BalanceFirstBlock = r.firstDivergence(ctx, firstBlock, *block, func(b uint64) (*big.Int, error) {
+			return client.BalanceAt(ctx, ethcommon.HexToAddress(r.contractAddress), new(big.Int).SetUint64(b))
+		}, balance)
+	}
+
+	for beneficiary, replayed := range allowances {
+		actual, err := contract.Allowance(&bind.CallOpts{Context: ctx, BlockNumber: target}, beneficiary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get allowance of %s: %w", beneficiary.Hex(), err)
+		}
+
+		check := AllowanceCheck{
+			Beneficiary: beneficiary.Hex(),
+			Expected:    replayed.current(),
+			Actual:      actual,
+		}
+		if !check.Matches() {
+			check.FirstBlock = r.firstDivergence(ctx, firstBlock, *block, func(b uint64) (*big.Int, error) {
+				return contract.Allowance(&bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(b)}, beneficiary)
+			}, replayed)
+		}
+		result.Allowances = append(result.Allowances, check)
+	}
+	sort.Slice(result.Allowances, func(i, j int) bool {
+		return result.Allowances[i].Beneficiary < result.Allowances[j].Beneficiary
+	})
+
+	return result, nil
+}
+
+// checkPayout verifies the allowance was reduced by the payout amount in the same transaction, as sendMoney does
+func (r *Reconciler) checkPayout(changed *contracts.ContractAllowanceChanged, sent *contracts.ContractMoneySent) *HistoryIssue {
+	if changed == nil {
+		return &HistoryIssue{
+			Block:       sent.Raw.BlockNumber,
+			TxHash:      sent.Raw.TxHash.Hex(),
+			Description: fmt.Sprintf("payout of %s to %s without allowance reduction", sent.Amount, sent.Beneficiary.Hex()),
+		}
+	}
+
+	reduced := new(big.Int).Sub(changed.PrevAmount, changed.NewAmount)
+	if reduced.Cmp(sent.Amount) == 0 {
+		return nil
+	}
+	return &HistoryIssue{
+		Block:  sent.Raw.BlockNumber,
+		TxHash: sent.Raw.TxHash.Hex(),
+		Description: fmt.Sprintf("payout of %s to %s reduced the allowance by %s",
+			sent.Amount, sent.Beneficiary.Hex(), reduced),
+	}
+}
+
+// firstDivergence binary searches the first block in [from, to] where the on-chain value differs from the replayed
+// one, assuming the drift persists once it appears. Historical state is required, nil is returned when unavailable
+func (r *Reconciler) firstDivergence(ctx context.Context, from uint64, to uint64, actualAt func(block uint64) (*big.Int, error), replayed history) *uint64 {
+	lo, hi := from, to
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		actual, err := actualAt(mid)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to get historical state, first divergent block is unknown",
+				slog.Uint64("block", mid), slog.String("error", err.Error()))
+			return nil
+		}
+
+		if actual.Cmp(replayed.at(mid)) != 0 {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return &lo
+}
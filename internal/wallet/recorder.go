@@ -0,0 +1,153 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	contracts "github.com/maxipaz/wallet/contracts/interfaces"
+	"github.com/maxipaz/wallet/internal/store"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// maxCachedBlockTimes number of block timestamps kept in memory to avoid fetching the same header several times
+const maxCachedBlockTimes = 256
+
+// Recorder persists the contract events into the local store
+type Recorder struct {
+	store           *store.Store
+	client          *ethclient.Client
+	chainID         uint64
+	contractAddress string
+
+	mu         sync.Mutex
+	blockTimes map[uint64]time.Time
+}
+
+// NewRecorder returns a new recorder instance
+func NewRecorder(st *store.Store, client *ethclient.Client, chainID uint64, contractAddress string) *Recorder {
+	return &Recorder{
+		store:           st,
+		client:          client,
+		chainID:         chainID,
+		contractAddress: ethcommon.HexToAddress(contractAddress).Hex(),
+		blockTimes:      make(map[uint64]time.Time),
+	}
+}
+
+// Handle stores an event received by the monitor
+func (r *Recorder) Handle(ctx context.Context, event any) {
+	if err := r.record(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "failed to record event", slog.String("error", err.Error()))
+	}
+}
+
+// Backfill stores the events emitted since the last recorded block, so the history has no gaps after a downtime
+func (r *Recorder) Backfill(ctx context.Context) error {
+	lastBlock, err := r.store.LastBlock(ctx, r.chainID, r.contractAddress)
+	if err != nil {
+		return err
+	}
+
+	slog.DebugContext(ctx, "backfilling events", slog.Uint64("from_block", lastBlock))
+	events, err := FilterEvents(ctx, r.client, r.contractAddress, lastBlock, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get events history: %w", err)
+	}
+
+	for _, event := range events {
+		if err := r.record(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	slog.DebugContext(ctx, "events backfilled", slog.Int("events", len(events)))
+	return nil
+}
+
+func (r *Recorder) record(ctx context.Context, event any) error {
+	var (
+		raw    types.Log
+		stored store.Event
+	)
+
+	switch event := event.(type) {
+	case *contracts.ContractAllowanceChanged:
+		raw = event.Raw
+		stored = store.Event{
+			Type:         store.AllowanceChangedEvent,
+			Address:      event.Beneficiary.Hex(),
+			Counterparty: event.Sender.Hex(),
+			Amount:       event.NewAmount,
+			PrevAmount:   event.PrevAmount,
+		}
+	case *contracts.ContractMoneySent:
+		raw = event.Raw
+		stored = store.Event{
+			Type:    store.MoneySentEvent,
+			Address: event.Beneficiary.Hex(),
+			Amount:  event.Amount,
+		}
+	case *contracts.ContractMoneyReceived:
+		raw = event.Raw
+		stored = store.Event{
+			Type:    store.MoneyReceivedEvent,
+			Address: event.From.Hex(),
+			Amount:  event.Amount,
+		}
+	case *contracts.ContractOwnershipTransferred:
+		raw = event.Raw
+		stored = store.Event{
+			Type:         store.OwnershipTransferredEvent,
+			Address:      event.NewOwner.Hex(),
+			Counterparty: event.PreviousOwner.Hex(),
+		}
+	default:
+		return nil
+	}
+
+	if raw.Removed {
+		return r.store.DeleteEvent(ctx, r.chainID, r.contractAddress, raw.TxHash.Hex(), raw.Index)
+	}
+
+	blockTime, err := r.blockTime(ctx, raw.BlockNumber)
+	if err != nil {
+		return err
+	}
+
+	stored.ChainID = r.chainID
+	stored.Contract = r.contractAddress
+	stored.TxHash = raw.TxHash.Hex()
+	stored.LogIndex = raw.Index
+	stored.BlockNumber = raw.BlockNumber
+	stored.Timestamp = blockTime
+
+	return r.store.SaveEvent(ctx, stored)
+}
+
+// blockTime returns the timestamp of a block
+func (r *Recorder) blockTime(ctx context.Context, number uint64) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if blockTime, ok := r.blockTimes[number]; ok {
+		return blockTime, nil
+	}
+
+	header, err := r.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get block %d header: %w", number, err)
+	}
+
+	if len(r.blockTimes) >= maxCachedBlockTimes {
+		r.blockTimes = make(map[uint64]time.Time)
+	}
+	blockTime := time.Unix(int64(header.Time), 0).UTC()
+	r.blockTimes[number] = blockTime
+
+	return blockTime, nil
+}
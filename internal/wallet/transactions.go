@@ -2,10 +2,94 @@ package wallet
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/maxipaz/wallet/internal/common"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/metrics"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"log/slog"
 	"math/big"
+	"sync"
+	"time"
 )
 
+// signMu is held by the runners of the process, i.e. the API, the gRPC service and the schedules, from the policy
+// check and the nonce fetch until the transaction is broadcast, so concurrent payouts cannot both pass a cap nor sign
+// the same nonce
+var signMu sync.Mutex
+
+// idempotencyLease time after which a key reserved by a request that never signed its transaction, i.e. the process
+// stopped, can be taken over by a retry
+var idempotencyLease = 2 * time.Minute
+
+type idempotencyKeyContext struct{}
+
+// WithIdempotencyKey returns a context whose transaction is broadcast once per key. Retrying a request with the same
+// key returns the outcome of the original transaction instead of broadcasting a new one
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKeyContext{}, key)
+}
+
+// WithCallerIdempotencyKey returns a context whose idempotency key, provided by an API client, is scoped to the
+// requester set by WithRequester. Two callers using the same key get distinct transactions
+func WithCallerIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return WithIdempotencyKey(ctx, Requester(ctx)+"/"+key)
+}
+
+func idempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContext{}).(string)
+	return key
+}
+
+type proposalContext struct{}
+
+// WithProposal returns a context executing an approved proposal, the operations requiring approval are only signed
+// when they match it
+func WithProposal(ctx context.Context, proposal *store.Proposal) context.Context {
+	return context.WithValue(ctx, proposalContext{}, proposal)
+}
+
+// checkProposal checks the request matches the approved proposal of the context
+func checkProposal(ctx context.Context, request txRequest) error {
+	proposal, _ := ctx.Value(proposalContext{}).(*store.Proposal)
+	if proposal == nil {
+		return fmt.Errorf("%w: %s of %d ether to %s", errs.ErrApprovalRequired, request.operation, request.amount, request.target)
+	}
+	if proposal.Status != store.ProposalExecuting {
+		return fmt.Errorf("%w: %s is %s", errs.ErrProposalNotApproved, proposal.ID, proposal.Status)
+	}
+	if proposal.Operation != request.operation || proposal.Target != request.target || proposal.Amount != request.amount {
+		return fmt.Errorf("%w: %s", errs.ErrProposalMismatch, proposal.ID)
+	}
+	return nil
+}
+
+type requesterContext struct{}
+
+// WithRequester returns a context whose transactions are recorded in the outbox as requested by the given caller
+func WithRequester(ctx context.Context, requester string) context.Context {
+	return context.WithValue(ctx, requesterContext{}, requester)
+}
+
+// Requester returns the caller of the context set by WithRequester
+func Requester(ctx context.Context) string {
+	name, _ := ctx.Value(requesterContext{}).(string)
+	return name
+}
+
 type TransactionInfo struct {
 	Operation string   `json:"operation"`
 	Gas       uint64   `json:"gas"`
@@ -13,6 +97,382 @@ type TransactionInfo struct {
 	Cost      *big.Int `json:"cost"`
 }
 
+// TransactionResult outcome of a mined transaction
+type TransactionResult struct {
+	Operation   string `json:"operation"`
+	TxHash      string `json:"tx_hash"`
+	BlockNumber uint64 `json:"block_number"`
+	Status      uint64 `json:"status"`
+	GasUsed     uint64 `json:"gas_used"`
+}
+
+// txRequest operation requested to a runner, the amount is expressed in Ether
+type txRequest struct {
+	operation string
+	target    string
+	amount    int64
+	// allowance allowance resulting from an allowance change
+	allowance int64
+}
+
+// String describes the request parameters, a retry with the same idempotency key must describe the same request
+func (r txRequest) String() string {
+	return fmt.Sprintf("%s %d", r.target, r.amount)
+}
+
+// transactor signs, records and broadcasts the runners transactions
+type transactor struct {
+	store  *store.Store
+	policy *policy.Policy
+	// pipeline signer whose nonce is incremented after each signed transaction, see Pipeline
+	pipeline *bind.TransactOpts
+}
+
+// SetStore sets the store recording the transactions in the outbox, it is required to use idempotency keys
+func (t *transactor) SetStore(st *store.Store) {
+	t.store = st
+}
+
+// SetPolicy sets the spending policy checked before signing the payouts and the allowance changes
+func (t *transactor) SetPolicy(p *policy.Policy) {
+	t.policy = p
+}
+
+// PendingTransaction transaction broadcast by a runner without waiting for it to be mined
+type PendingTransaction struct {
+	Operation string
+	TxHash    string
+
+	tx *types.Transaction
+	// key idempotency key bound to the transaction, released when it reverts
+	key string
+	// result of the original transaction when its idempotency key was already used
+	result *TransactionResult
+}
+
+// transact signs the transaction built by the given function, records it in the outbox, broadcasts it and waits for
+// it to be mined
+func (t *transactor) transact(ctx context.Context, client *ethclient.Client, request txRequest, build func(signer *bind.TransactOpts) (*types.Transaction, error)) (*TransactionResult, error) {
+	pending, err := t.submit(ctx, client, request, build)
+	if err != nil {
+		if pending != nil {
+			return pending.result, err
+		}
+		return nil, err
+	}
+	return t.Wait(ctx, client, pending)
+}
+
+// submit signs the transaction built by the given function, records it in the outbox and broadcasts it. When the
+// idempotency key was already used the pending transaction holds the result of the original one
+func (t *transactor) submit(ctx context.Context, client *ethclient.Client, request txRequest, build func(signer *bind.TransactOpts) (*types.Transaction, error)) (*PendingTransaction, error) {
+	key := idempotencyKey(ctx)
+	if key != "" {
+		result, done, err := t.reserve(ctx, client, key, request)
+		if done || err != nil {
+			pending := &PendingTransaction{Operation: request.operation, result: result}
+			if result != nil {
+				pending.TxHash = result.TxHash
+			}
+			return pending, err
+		}
+	}
+
+	signMu.Lock()
+	defer signMu.Unlock()
+
+	tx, err := t.checkAndSign(ctx, client, request, build)
+	if err != nil {
+		if key != "" {
+			if releaseErr := t.store.ReleaseIdempotencyKey(ctx, key); releaseErr != nil {
+				slog.ErrorContext(ctx, "failed to release idempotency key", slog.String("error", releaseErr.Error()))
+			}
+		}
+		return nil, err
+	}
+
+	if key != "" {
+		// the hash is recorded before broadcasting, a retry after a timeout must never broadcast a second transaction
+		if err := t.store.SetIdempotencyTx(ctx, key, tx.Hash().Hex()); err != nil {
+			return nil, err
+		}
+	}
+
+	sendErr := client.SendTransaction(ctx, tx)
+	if t.store != nil {
+		if err := t.store.RecordBroadcast(ctx, tx.Hash().Hex(), sendErr); err != nil {
+			slog.ErrorContext(ctx, "failed to record broadcast", slog.String("tx_hash", tx.Hash().Hex()), slog.String("error", err.Error()))
+		}
+	}
+	if sendErr != nil {
+		return nil, fmt.Errorf("failed to send transaction %s: %w", tx.Hash().Hex(), sendErr)
+	}
+
+	return &PendingTransaction{Operation: request.operation, TxHash: tx.Hash().Hex(), tx: tx, key: key}, nil
+}
+
+// Wait waits for a transaction submitted by the runner to be mined and returns its result
+func (t *transactor) Wait(ctx context.Context, client *ethclient.Client, pending *PendingTransaction) (*TransactionResult, error) {
+	if pending.tx == nil {
+		return pending.result, nil
+	}
+	result, err := t.waitMined(ctx, client, pending.tx, pending.Operation)
+	if errors.Is(err, errs.ErrTransactionFailed) && pending.key != "" {
+		// the reverted transaction is final, a retry with the same key signs a new one
+		t.releaseTx(ctx, pending.key, pending.TxHash)
+	}
+	return result, err
+}
+
+// Pipeline makes the runner sign its next transactions from a signer fetched once, incrementing its nonce locally,
+// so they can be broadcast one after the other without waiting for the previous ones to be mined
+func (t *transactor) Pipeline(ctx context.Context, client *ethclient.Client) error {
+	signer, err := common.GetSigner(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to get signer: %w", err)
+	}
+	t.pipeline = signer
+	return nil
+}
+
+// checkAndSign checks the request against the spending policy and signs its transaction. It is called under signMu,
+// the caps computed from the outbox account for every payout signed before
+func (t *transactor) checkAndSign(ctx context.Context, client *ethclient.Client, request txRequest, build func(signer *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	if t.policy != nil {
+		var err error
+		// the allowance changes are checked against the allowance they result in, not against the change
+		amount := request.amount
+		switch request.operation {
+		case "send_money":
+			err = t.policy.CheckPayout(ctx, t.store, nil, request.target, request.amount)
+		case "set_allowance", "increase_allowance":
+			err = t.policy.CheckAllowance(ctx, request.target, request.allowance)
+			amount = request.allowance
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if t.policy.RequiresApproval(request.operation, amount) {
+			if err := checkProposal(ctx, request); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return t.sign(ctx, client, request, build)
+}
+
+// sign builds and signs a transaction without broadcasting it, recording it in the outbox when there is a store
+func (t *transactor) sign(ctx context.Context, client *ethclient.Client, request txRequest, build func(signer *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	var signer *bind.TransactOpts
+	if t.pipeline != nil {
+		// the other runners of the process may have signed since the last transaction of the pipeline
+		pending, err := client.PendingNonceAt(ctx, t.pipeline.From)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nonce: %w", err)
+		}
+		pipelined := *t.pipeline
+		if pending > pipelined.Nonce.Uint64() {
+			pipelined.Nonce = new(big.Int).SetUint64(pending)
+		}
+		signer = &pipelined
+	} else {
+		var err error
+		if signer, err = common.GetSigner(ctx, client); err != nil {
+			return nil, fmt.Errorf("failed to get signer: %w", err)
+		}
+	}
+	signer.Context = ctx
+	signer.NoSend = true
+
+	start := time.Now()
+	tx, err := build(signer)
+	metrics.ObserveRPC(request.operation, start)
+	if err != nil {
+		return nil, err
+	}
+	if t.pipeline != nil {
+		t.pipeline.Nonce = new(big.Int).SetUint64(tx.Nonce() + 1)
+	}
+
+	if t.store == nil {
+		return tx, nil
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	err = t.store.SaveTransaction(ctx, store.Transaction{
+		Hash:      tx.Hash().Hex(),
+		ChainID:   tx.ChainId().Uint64(),
+		Sender:    signer.From.Hex(),
+		Nonce:     tx.Nonce(),
+		Operation: request.operation,
+		Target:    request.target,
+		Amount:    common.EtherToWei(big.NewInt(request.amount)),
+		Requester: Requester(ctx),
+		Raw:       raw,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// reserve reserves the idempotency key for the request. When the key was already used done is true and the result
+// of the original transaction is returned
+func (t *transactor) reserve(ctx context.Context, client *ethclient.Client, key string, request txRequest) (*TransactionResult, bool, error) {
+	if t.store == nil {
+		return nil, true, errs.ErrIdempotencyStoreRequired
+	}
+
+	existing, reserved, err := t.store.ReserveIdempotencyKey(ctx, key, request.operation, request.String())
+	if err != nil || reserved {
+		return nil, err != nil, err
+	}
+
+	if existing.Operation != request.operation || existing.Request != request.String() {
+		return nil, true, fmt.Errorf("%w: %s was used for %s %s", errs.ErrIdempotencyKeyConflict, key, existing.Operation, existing.Request)
+	}
+
+	if existing.TxHash == "" {
+		taken, err := t.store.TakeOverIdempotencyKey(ctx, key, time.Now().Add(-idempotencyLease))
+		if err != nil {
+			return nil, true, err
+		}
+		if !taken {
+			return nil, true, fmt.Errorf("%w: %s", errs.ErrIdempotencyKeyInProgress, key)
+		}
+		return nil, false, nil
+	}
+
+	slog.InfoContext(ctx, "idempotency key already used, returning the original transaction",
+		slog.String("key", key),
+		slog.String("tx_hash", existing.TxHash),
+	)
+	result, err := t.waitMinedHash(ctx, client, ethcommon.HexToHash(existing.TxHash), request.operation)
+	if err == nil || !t.reusable(ctx, existing.TxHash, err) {
+		return result, true, err
+	}
+
+	// the original transaction reverted or will never be mined, the key is bound to a new one
+	slog.InfoContext(ctx, "idempotency key released, the original transaction did not succeed",
+		slog.String("key", key),
+		slog.String("tx_hash", existing.TxHash),
+		slog.String("error", err.Error()),
+	)
+	if !t.releaseTx(ctx, key, existing.TxHash) {
+		return result, true, err
+	}
+	if _, reserved, err = t.store.ReserveIdempotencyKey(ctx, key, request.operation, request.String()); err != nil || !reserved {
+		if err == nil {
+			err = fmt.Errorf("%w: %s", errs.ErrIdempotencyKeyInProgress, key)
+		}
+		return nil, true, err
+	}
+	return nil, false, nil
+}
+
+// reusable reports whether the transaction bound to an idempotency key is final without success: it reverted, its
+// nonce was used by another transaction or the outbox gave up broadcasting it
+func (t *transactor) reusable(ctx context.Context, txHash string, err error) bool {
+	if errors.Is(err, errs.ErrTransactionFailed) {
+		return true
+	}
+	if !errors.Is(err, errs.ErrTransactionDropped) {
+		return false
+	}
+
+	// a transaction unknown by the node and by the outbox may still be mined, the key is kept
+	stored, getErr := t.store.GetTransaction(ctx, txHash)
+	if getErr != nil {
+		return false
+	}
+	return stored != nil && (stored.Status == store.TxReplaced || stored.Status == store.TxDropped)
+}
+
+// releaseTx releases an idempotency key bound to a transaction that did not succeed, it reports whether the key
+// was released
+func (t *transactor) releaseTx(ctx context.Context, key string, txHash string) bool {
+	released, err := t.store.ReleaseIdempotencyTx(ctx, key, txHash)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to release idempotency key", slog.String("key", key), slog.String("error", err.Error()))
+		return false
+	}
+	return released
+}
+
+// waitMined waits for the transaction to be mined, records its final status in the outbox and returns its result
+func (t *transactor) waitMined(ctx context.Context, client *ethclient.Client, tx *types.Transaction, operation string) (*TransactionResult, error) {
+	result, err := waitMined(ctx, client, tx, operation)
+	if result != nil && t.store != nil {
+		status := store.TxMined
+		if result.Status != types.ReceiptStatusSuccessful {
+			status = store.TxFailed
+		}
+		if err := t.store.SetTransactionStatus(ctx, result.TxHash, status, result.BlockNumber, result.GasUsed); err != nil {
+			slog.ErrorContext(ctx, "failed to record transaction status", slog.String("tx_hash", result.TxHash), slog.String("error", err.Error()))
+		}
+	}
+	return result, err
+}
+
+// waitMined waits for the transaction to be mined and returns its result. When the transaction reverts the result
+// is returned along with errs.ErrTransactionFailed
+func waitMined(ctx context.Context, client *ethclient.Client, tx *types.Transaction, operation string) (*TransactionResult, error) {
+	start := time.Now()
+	receipt, err := bind.WaitMined(ctx, client, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait mined: %w", err)
+	}
+	metrics.ObserveConfirmation(operation, start)
+
+	result := &TransactionResult{
+		Operation:   operation,
+		TxHash:      tx.Hash().Hex(),
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		Status:      receipt.Status,
+		GasUsed:     receipt.GasUsed,
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return result, errs.ErrTransactionFailed
+	}
+
+	processTransaction(ctx, tx, operation)
+	return result, nil
+}
+
+// waitMinedHash waits for a previously signed transaction to be mined and returns its result. A transaction unknown
+// by the node is broadcast again from the outbox, unless its nonce was used by another transaction
+func (t *transactor) waitMinedHash(ctx context.Context, client *ethclient.Client, hash ethcommon.Hash, operation string) (*TransactionResult, error) {
+	tx, _, err := client.TransactionByHash(ctx, hash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", hash.Hex(), err)
+	}
+
+	if errors.Is(err, ethereum.NotFound) {
+		stored, err := t.store.GetTransaction(ctx, hash.Hex())
+		if err != nil {
+			return nil, err
+		}
+		if stored == nil || stored.Final() {
+			return nil, fmt.Errorf("%w: %s", errs.ErrTransactionDropped, hash.Hex())
+		}
+
+		if err := NewOutbox(t.store).check(ctx, client, *stored); err != nil {
+			return nil, err
+		}
+		if tx, _, err = client.TransactionByHash(ctx, hash); err != nil {
+			return nil, fmt.Errorf("%w: %s", errs.ErrTransactionDropped, hash.Hex())
+		}
+	}
+
+	return t.waitMined(ctx, client, tx, operation)
+}
+
 // processTransaction process the transaction in order to get stats
 func processTransaction(ctx context.Context, tx *types.Transaction, operation string) {
 	if tx == nil {
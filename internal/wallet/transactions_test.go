@@ -0,0 +1,246 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/maxipaz/wallet/internal/deploy"
+	errs "github.com/maxipaz/wallet/internal/errors"
+	"github.com/maxipaz/wallet/internal/store"
+	"github.com/maxipaz/wallet/internal/testchain"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testWallet contract deployed on a simulated chain with the runners recording their transactions in a store
+type testWallet struct {
+	chain       *testchain.Chain
+	store       *store.Store
+	transfers   *transfers
+	allowance   *Allowance
+	beneficiary string
+}
+
+// newTestWallet deploys the contract, funds it with 10 ether and returns runners sharing a store. The beneficiary has
+// no allowance
+func newTestWallet(t *testing.T) *testWallet {
+	t.Helper()
+
+	chain := testchain.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deployer := deploy.NewDeployer()
+	if err := deployer.Deploy(ctx, chain.Client); err != nil {
+		t.Fatal(err)
+	}
+	if err := deployer.Wait(ctx, chain.Client); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := store.Open(ctx, filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &testWallet{
+		chain:       chain,
+		store:       st,
+		transfers:   NewTransfersRunner("", deployer.ContractAddress()).(*transfers),
+		allowance:   NewAllowanceRunner("", deployer.ContractAddress()),
+		beneficiary: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+	}
+	w.transfers.SetStore(st)
+	w.allowance.SetStore(st)
+
+	if _, err := w.transfers.Receive(ctx, chain.Client, 10); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// context returns a context of the test bound to the idempotency key, when not empty
+func (w *testWallet) context(t *testing.T, key string) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+	return WithIdempotencyKey(ctx, key)
+}
+
+// setAllowance sets the allowance of the beneficiary
+func (w *testWallet) setAllowance(t *testing.T, amount int64) {
+	t.Helper()
+	if _, err := w.allowance.ChangeAllowance(w.context(t, ""), w.chain.Client, SetAction, w.beneficiary, amount); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// payouts returns the payouts recorded in the outbox
+func (w *testWallet) payouts(t *testing.T) []store.Transaction {
+	t.Helper()
+	transactions, err := w.store.ListTransactions(context.Background(), store.TransactionFilter{Operations: []string{"send_money"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transactions
+}
+
+// sendReverting sends a payout of the beneficiary with a fixed gas limit, so it is mined and reverts when the
+// beneficiary has no allowance instead of failing the gas estimation
+func (w *testWallet) sendReverting(ctx context.Context, t *testing.T, amount int64) (*TransactionResult, error) {
+	t.Helper()
+	request, build, err := w.transfers.sendTx(ctx, w.chain.Client, w.beneficiary, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w.transfers.transact(ctx, w.chain.Client, request, func(signer *bind.TransactOpts) (*types.Transaction, error) {
+		signer.GasLimit = 100_000
+		return build(signer)
+	})
+}
+
+func TestIdempotencyKeyRetry(t *testing.T) {
+	w := newTestWallet(t)
+	w.setAllowance(t, 5)
+	ctx := w.context(t, "invoice-1")
+
+	first, err := w.transfers.Send(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := w.transfers.Send(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.TxHash != first.TxHash || retry.BlockNumber != first.BlockNumber {
+		t.Fatalf("retry returned %s in block %d, expected the original %s in block %d", retry.TxHash, retry.BlockNumber, first.TxHash, first.BlockNumber)
+	}
+	if payouts := w.payouts(t); len(payouts) != 1 {
+		t.Fatalf("%d payouts signed, expected one", len(payouts))
+	}
+
+	// the key is bound to the request, another amount or beneficiary is a conflict
+	if _, err := w.transfers.Send(ctx, w.chain.Client, w.beneficiary, 2); !errors.Is(err, errs.ErrIdempotencyKeyConflict) {
+		t.Fatalf("other amount returned %v, expected a key conflict", err)
+	}
+	if _, err := w.transfers.Send(ctx, w.chain.Client, w.chain.Address().Hex(), 1); !errors.Is(err, errs.ErrIdempotencyKeyConflict) {
+		t.Fatalf("other beneficiary returned %v, expected a key conflict", err)
+	}
+	if payouts := w.payouts(t); len(payouts) != 1 {
+		t.Fatalf("%d payouts signed after the conflicts, expected one", len(payouts))
+	}
+}
+
+func TestIdempotencyKeyAfterRevert(t *testing.T) {
+	w := newTestWallet(t)
+	ctx := w.context(t, "invoice-1")
+
+	reverted, err := w.sendReverting(ctx, t, 1)
+	if !errors.Is(err, errs.ErrTransactionFailed) {
+		t.Fatalf("payout without allowance returned %v, expected a failed transaction", err)
+	}
+	if reservation, err := w.store.GetIdempotencyKey(ctx, "invoice-1"); err != nil || reservation != nil {
+		t.Fatalf("the key is still reserved after the revert: %v %v", reservation, err)
+	}
+
+	// the reverted transaction is final, the retry signs a new one
+	w.setAllowance(t, 5)
+	retry, err := w.transfers.Send(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.TxHash == reverted.TxHash || retry.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("retry returned %s with status %d, expected a new successful transaction", retry.TxHash, retry.Status)
+	}
+
+	stored, err := w.store.GetTransaction(ctx, reverted.TxHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != store.TxFailed {
+		t.Fatalf("the reverted payout is %s in the outbox, expected %s", stored.Status, store.TxFailed)
+	}
+}
+
+func TestIdempotencyKeyReusedAfterRevert(t *testing.T) {
+	w := newTestWallet(t)
+	ctx := w.context(t, "invoice-1")
+
+	// the key is bound to a reverted transaction the runner did not wait for, i.e. the process stopped
+	reverted, err := w.sendReverting(WithIdempotencyKey(ctx, "other"), t, 1)
+	if !errors.Is(err, errs.ErrTransactionFailed) {
+		t.Fatalf("payout without allowance returned %v, expected a failed transaction", err)
+	}
+	request, _, err := w.transfers.sendTx(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := w.store.ReserveIdempotencyKey(ctx, "invoice-1", request.operation, request.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.store.SetIdempotencyTx(ctx, "invoice-1", reverted.TxHash); err != nil {
+		t.Fatal(err)
+	}
+
+	w.setAllowance(t, 5)
+	retry, err := w.transfers.Send(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.TxHash == reverted.TxHash {
+		t.Fatal("retry returned the reverted transaction")
+	}
+	reservation, err := w.store.GetIdempotencyKey(ctx, "invoice-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reservation.TxHash != retry.TxHash {
+		t.Fatalf("the key is bound to %s, expected the new transaction %s", reservation.TxHash, retry.TxHash)
+	}
+}
+
+func TestIdempotencyKeyLease(t *testing.T) {
+	w := newTestWallet(t)
+	w.setAllowance(t, 5)
+	ctx := w.context(t, "invoice-1")
+
+	// a request reserved the key and never signed, i.e. the process stopped
+	request, _, err := w.transfers.sendTx(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := w.store.ReserveIdempotencyKey(ctx, "invoice-1", request.operation, request.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.transfers.Send(ctx, w.chain.Client, w.beneficiary, 1); !errors.Is(err, errs.ErrIdempotencyKeyInProgress) {
+		t.Fatalf("retry within the lease returned %v, expected the key in progress", err)
+	}
+	if payouts := w.payouts(t); len(payouts) != 0 {
+		t.Fatalf("%d payouts signed within the lease", len(payouts))
+	}
+
+	previous := idempotencyLease
+	idempotencyLease = -time.Second
+	t.Cleanup(func() { idempotencyLease = previous })
+
+	result, err := w.transfers.Send(ctx, w.chain.Client, w.beneficiary, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservation, err := w.store.GetIdempotencyKey(ctx, "invoice-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reservation.TxHash != result.TxHash {
+		t.Fatalf("the key is bound to %q, expected the transaction %s", reservation.TxHash, result.TxHash)
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	common2 "github.com/maxipaz/wallet/internal/common"
+	"github.com/maxipaz/wallet/internal/policy"
+	"github.com/maxipaz/wallet/internal/store"
+	"log/slog"
 	"math/big"
 )
 
@@ -17,11 +20,17 @@ const (
 
 // Transfers interface
 type Transfers interface {
-	Receive(ctx context.Context, client *ethclient.Client, amount int64) error
-	Send(ctx context.Context, client *ethclient.Client, target string, amount int64) error
+	Receive(ctx context.Context, client *ethclient.Client, amount int64) (*TransactionResult, error)
+	Send(ctx context.Context, client *ethclient.Client, target string, amount int64) (*TransactionResult, error)
+	SubmitSend(ctx context.Context, client *ethclient.Client, target string, amount int64) (*PendingTransaction, error)
+	Pipeline(ctx context.Context, client *ethclient.Client) error
+	Wait(ctx context.Context, client *ethclient.Client, pending *PendingTransaction) (*TransactionResult, error)
+	SetStore(st *store.Store)
+	SetPolicy(p *policy.Policy)
 }
 
 type transfers struct {
+	transactor
 	privateKey      string
 	contractAddress string
 }
@@ -35,53 +44,67 @@ func NewTransfersRunner(privateKey string, contractAddress string) Transfers {
 }
 
 // Receive method to receive founds in the contract
-func (t *transfers) Receive(ctx context.Context, client *ethclient.Client, amount int64) error {
-	contract, err := common2.getContract(ctx, client, t.contractAddress)
+func (t *transfers) Receive(ctx context.Context, client *ethclient.Client, amount int64) (*TransactionResult, error) {
+	contract, err := common2.GetContract(ctx, client, t.contractAddress)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	signer, err := common2.getSigner(ctx, client)
+	return t.transact(ctx, client, txRequest{operation: "receive", amount: amount}, func(signer *bind.TransactOpts) (*types.Transaction, error) {
+		signer.Value = common2.EtherToWei(big.NewInt(amount))
+		return contract.Receive(signer)
+	})
+}
+
+// Send method to send founds to a beneficiary
+func (t *transfers) Send(ctx context.Context, client *ethclient.Client, target string, amount int64) (*TransactionResult, error) {
+	request, build, err := t.sendTx(ctx, client, target, amount)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return t.transact(ctx, client, request, build)
+}
 
-	signer.Value = common2.etherToWei(big.NewInt(amount))
-	tx, txErr := contract.Receive(signer)
-	if txErr != nil {
-		return txErr
+// SubmitSend broadcasts the payout without waiting for it to be mined, see Pipeline and Wait
+func (t *transfers) SubmitSend(ctx context.Context, client *ethclient.Client, target string, amount int64) (*PendingTransaction, error) {
+	request, build, err := t.sendTx(ctx, client, target, amount)
+	if err != nil {
+		return nil, err
 	}
-	receipt, err := bind.WaitMined(ctx, client, tx)
-	if receipt.Status != types.ReceiptStatusSuccessful || err != nil {
-		return err
+	return t.submit(ctx, client, request, build)
+}
+
+// sendTx returns the request and the transaction builder of a payout
+func (t *transfers) sendTx(ctx context.Context, client *ethclient.Client, target string, amount int64) (txRequest, func(signer *bind.TransactOpts) (*types.Transaction, error), error) {
+	contract, err := common2.GetContract(ctx, client, t.contractAddress)
+	if err != nil {
+		return txRequest{}, nil, err
+	}
+	if err := checkBeneficiary(ctx, client, target, t.contractAddress); err != nil {
+		return txRequest{}, nil, err
 	}
-	processTransaction(ctx, tx, "receive")
 
-	return nil
+	targetAddress := common.HexToAddress(target)
+	request := txRequest{operation: "send_money", target: targetAddress.Hex(), amount: amount}
+	return request, func(signer *bind.TransactOpts) (*types.Transaction, error) {
+		return contract.SendMoney(signer, targetAddress, common2.EtherToWei(big.NewInt(amount)))
+	}, nil
 }
 
-// Send method to send founds to a beneficiary
-func (t *transfers) Send(ctx context.Context, client *ethclient.Client, target string, amount int64) error {
-	contract, err := common2.getContract(ctx, client, t.contractAddress)
-	if err != nil {
+// checkBeneficiary rejects the addresses which cannot receive allowances or payouts, and warns when the beneficiary
+// is a contract: the payouts are sent with transfer(), forwarding only 2300 gas, and may revert
+func checkBeneficiary(ctx context.Context, client *ethclient.Client, target string, contractAddress string) error {
+	if err := common2.ValidateBeneficiary(target, contractAddress); err != nil {
 		return err
 	}
 
-	signer, err := common2.getSigner(ctx, client)
+	hasCode, err := common2.HasCode(ctx, client, target)
 	if err != nil {
 		return err
 	}
-
-	targetAddress := common.HexToAddress(target)
-	tx, txErr := contract.SendMoney(signer, targetAddress, common2.etherToWei(big.NewInt(amount)))
-	if txErr != nil {
-		return txErr
+	if hasCode {
+		slog.WarnContext(ctx, "the beneficiary is a contract, its payouts may revert as transfer() only forwards 2300 gas",
+			slog.String("beneficiary", target))
 	}
-	receipt, err := bind.WaitMined(ctx, client, tx)
-	if receipt.Status != types.ReceiptStatusSuccessful || err != nil {
-		return err
-	}
-	processTransaction(ctx, tx, "send")
-
 	return nil
 }
@@ -0,0 +1,509 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.5.1-go
+// source: wallet/v1/wallet.proto
+
+package walletv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	WalletService_GetAllowance_FullMethodName       = "/wallet.v1.WalletService/GetAllowance"
+	WalletService_SetAllowance_FullMethodName       = "/wallet.v1.WalletService/SetAllowance"
+	WalletService_IncreaseAllowance_FullMethodName  = "/wallet.v1.WalletService/IncreaseAllowance"
+	WalletService_ReduceAllowance_FullMethodName    = "/wallet.v1.WalletService/ReduceAllowance"
+	WalletService_GetContractBalance_FullMethodName = "/wallet.v1.WalletService/GetContractBalance"
+	WalletService_GetAddressBalance_FullMethodName  = "/wallet.v1.WalletService/GetAddressBalance"
+	WalletService_GetOwner_FullMethodName           = "/wallet.v1.WalletService/GetOwner"
+	WalletService_TransferOwnership_FullMethodName  = "/wallet.v1.WalletService/TransferOwnership"
+	WalletService_Send_FullMethodName               = "/wallet.v1.WalletService/Send"
+	WalletService_Receive_FullMethodName            = "/wallet.v1.WalletService/Receive"
+	WalletService_WatchEvents_FullMethodName        = "/wallet.v1.WalletService/WatchEvents"
+)
+
+// WalletServiceClient is the client API for WalletService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WalletServiceClient interface {
+	GetAllowance(ctx context.Context, in *GetAllowanceRequest, opts ...grpc.CallOption) (*GetAllowanceResponse, error)
+	SetAllowance(ctx context.Context, in *ChangeAllowanceRequest, opts ...grpc.CallOption) (*TransactionResult, error)
+	IncreaseAllowance(ctx context.Context, in *ChangeAllowanceRequest, opts ...grpc.CallOption) (*TransactionResult, error)
+	ReduceAllowance(ctx context.Context, in *ChangeAllowanceRequest, opts ...grpc.CallOption) (*TransactionResult, error)
+	GetContractBalance(ctx context.Context, in *GetContractBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+	GetAddressBalance(ctx context.Context, in *GetAddressBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+	GetOwner(ctx context.Context, in *GetOwnerRequest, opts ...grpc.CallOption) (*GetOwnerResponse, error)
+	TransferOwnership(ctx context.Context, in *TransferOwnershipRequest, opts ...grpc.CallOption) (*TransactionResult, error)
+	Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*TransactionResult, error)
+	Receive(ctx context.Context, in *ReceiveRequest, opts ...grpc.CallOption) (*TransactionResult, error)
+	// WatchEvents streams the contract events received by the monitor from the moment of the call
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (WalletService_WatchEventsClient, error)
+}
+
+type walletServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWalletServiceClient(cc grpc.ClientConnInterface) WalletServiceClient {
+	return &walletServiceClient{cc}
+}
+
+func (c *walletServiceClient) GetAllowance(ctx context.Context, in *GetAllowanceRequest, opts ...grpc.CallOption) (*GetAllowanceResponse, error) {
+	out := new(GetAllowanceResponse)
+	err := c.cc.Invoke(ctx, WalletService_GetAllowance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) SetAllowance(ctx context.Context, in *ChangeAllowanceRequest, opts ...grpc.CallOption) (*TransactionResult, error) {
+	out := new(TransactionResult)
+	err := c.cc.Invoke(ctx, WalletService_SetAllowance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) IncreaseAllowance(ctx context.Context, in *ChangeAllowanceRequest, opts ...grpc.CallOption) (*TransactionResult, error) {
+	out := new(TransactionResult)
+	err := c.cc.Invoke(ctx, WalletService_IncreaseAllowance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) ReduceAllowance(ctx context.Context, in *ChangeAllowanceRequest, opts ...grpc.CallOption) (*TransactionResult, error) {
+	out := new(TransactionResult)
+	err := c.cc.Invoke(ctx, WalletService_ReduceAllowance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) GetContractBalance(ctx context.Context, in *GetContractBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error) {
+	out := new(GetBalanceResponse)
+	err := c.cc.Invoke(ctx, WalletService_GetContractBalance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) GetAddressBalance(ctx context.Context, in *GetAddressBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error) {
+	out := new(GetBalanceResponse)
+	err := c.cc.Invoke(ctx, WalletService_GetAddressBalance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) GetOwner(ctx context.Context, in *GetOwnerRequest, opts ...grpc.CallOption) (*GetOwnerResponse, error) {
+	out := new(GetOwnerResponse)
+	err := c.cc.Invoke(ctx, WalletService_GetOwner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) TransferOwnership(ctx context.Context, in *TransferOwnershipRequest, opts ...grpc.CallOption) (*TransactionResult, error) {
+	out := new(TransactionResult)
+	err := c.cc.Invoke(ctx, WalletService_TransferOwnership_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*TransactionResult, error) {
+	out := new(TransactionResult)
+	err := c.cc.Invoke(ctx, WalletService_Send_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Receive(ctx context.Context, in *ReceiveRequest, opts ...grpc.CallOption) (*TransactionResult, error) {
+	out := new(TransactionResult)
+	err := c.cc.Invoke(ctx, WalletService_Receive_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (WalletService_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &WalletService_ServiceDesc.Streams[0], WalletService_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &walletServiceWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type WalletService_WatchEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type walletServiceWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *walletServiceWatchEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WalletServiceServer is the server API for WalletService service.
+// All implementations must embed UnimplementedWalletServiceServer
+// for forward compatibility
+type WalletServiceServer interface {
+	GetAllowance(context.Context, *GetAllowanceRequest) (*GetAllowanceResponse, error)
+	SetAllowance(context.Context, *ChangeAllowanceRequest) (*TransactionResult, error)
+	IncreaseAllowance(context.Context, *ChangeAllowanceRequest) (*TransactionResult, error)
+	ReduceAllowance(context.Context, *ChangeAllowanceRequest) (*TransactionResult, error)
+	GetContractBalance(context.Context, *GetContractBalanceRequest) (*GetBalanceResponse, error)
+	GetAddressBalance(context.Context, *GetAddressBalanceRequest) (*GetBalanceResponse, error)
+	GetOwner(context.Context, *GetOwnerRequest) (*GetOwnerResponse, error)
+	TransferOwnership(context.Context, *TransferOwnershipRequest) (*TransactionResult, error)
+	Send(context.Context, *SendRequest) (*TransactionResult, error)
+	Receive(context.Context, *ReceiveRequest) (*TransactionResult, error)
+	// WatchEvents streams the contract events received by the monitor from the moment of the call
+	WatchEvents(*WatchEventsRequest, WalletService_WatchEventsServer) error
+	mustEmbedUnimplementedWalletServiceServer()
+}
+
+// UnimplementedWalletServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedWalletServiceServer struct {
+}
+
+func (UnimplementedWalletServiceServer) GetAllowance(context.Context, *GetAllowanceRequest) (*GetAllowanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllowance not implemented")
+}
+func (UnimplementedWalletServiceServer) SetAllowance(context.Context, *ChangeAllowanceRequest) (*TransactionResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAllowance not implemented")
+}
+func (UnimplementedWalletServiceServer) IncreaseAllowance(context.Context, *ChangeAllowanceRequest) (*TransactionResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IncreaseAllowance not implemented")
+}
+func (UnimplementedWalletServiceServer) ReduceAllowance(context.Context, *ChangeAllowanceRequest) (*TransactionResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReduceAllowance not implemented")
+}
+func (UnimplementedWalletServiceServer) GetContractBalance(context.Context, *GetContractBalanceRequest) (*GetBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetContractBalance not implemented")
+}
+func (UnimplementedWalletServiceServer) GetAddressBalance(context.Context, *GetAddressBalanceRequest) (*GetBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAddressBalance not implemented")
+}
+func (UnimplementedWalletServiceServer) GetOwner(context.Context, *GetOwnerRequest) (*GetOwnerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOwner not implemented")
+}
+func (UnimplementedWalletServiceServer) TransferOwnership(context.Context, *TransferOwnershipRequest) (*TransactionResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferOwnership not implemented")
+}
+func (UnimplementedWalletServiceServer) Send(context.Context, *SendRequest) (*TransactionResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Send not implemented")
+}
+func (UnimplementedWalletServiceServer) Receive(context.Context, *ReceiveRequest) (*TransactionResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Receive not implemented")
+}
+func (UnimplementedWalletServiceServer) WatchEvents(*WatchEventsRequest, WalletService_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedWalletServiceServer) mustEmbedUnimplementedWalletServiceServer() {}
+
+// UnsafeWalletServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WalletServiceServer will
+// result in compilation errors.
+type UnsafeWalletServiceServer interface {
+	mustEmbedUnimplementedWalletServiceServer()
+}
+
+func RegisterWalletServiceServer(s grpc.ServiceRegistrar, srv WalletServiceServer) {
+	s.RegisterService(&WalletService_ServiceDesc, srv)
+}
+
+func _WalletService_GetAllowance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllowanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).GetAllowance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_GetAllowance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).GetAllowance(ctx, req.(*GetAllowanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_SetAllowance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAllowanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).SetAllowance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_SetAllowance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).SetAllowance(ctx, req.(*ChangeAllowanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_IncreaseAllowance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAllowanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).IncreaseAllowance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_IncreaseAllowance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).IncreaseAllowance(ctx, req.(*ChangeAllowanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_ReduceAllowance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAllowanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).ReduceAllowance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_ReduceAllowance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).ReduceAllowance(ctx, req.(*ChangeAllowanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_GetContractBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetContractBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).GetContractBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_GetContractBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).GetContractBalance(ctx, req.(*GetContractBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_GetAddressBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAddressBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).GetAddressBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_GetAddressBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).GetAddressBalance(ctx, req.(*GetAddressBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_GetOwner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOwnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).GetOwner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_GetOwner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).GetOwner(ctx, req.(*GetOwnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_TransferOwnership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferOwnershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).TransferOwnership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_TransferOwnership_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).TransferOwnership(ctx, req.(*TransferOwnershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Send_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_Send_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Send(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Receive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReceiveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Receive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletService_Receive_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Receive(ctx, req.(*ReceiveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WalletServiceServer).WatchEvents(m, &walletServiceWatchEventsServer{stream})
+}
+
+type WalletService_WatchEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type walletServiceWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *walletServiceWatchEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// WalletService_ServiceDesc is the grpc.ServiceDesc for WalletService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WalletService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "wallet.v1.WalletService",
+	HandlerType: (*WalletServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAllowance",
+			Handler:    _WalletService_GetAllowance_Handler,
+		},
+		{
+			MethodName: "SetAllowance",
+			Handler:    _WalletService_SetAllowance_Handler,
+		},
+		{
+			MethodName: "IncreaseAllowance",
+			Handler:    _WalletService_IncreaseAllowance_Handler,
+		},
+		{
+			MethodName: "ReduceAllowance",
+			Handler:    _WalletService_ReduceAllowance_Handler,
+		},
+		{
+			MethodName: "GetContractBalance",
+			Handler:    _WalletService_GetContractBalance_Handler,
+		},
+		{
+			MethodName: "GetAddressBalance",
+			Handler:    _WalletService_GetAddressBalance_Handler,
+		},
+		{
+			MethodName: "GetOwner",
+			Handler:    _WalletService_GetOwner_Handler,
+		},
+		{
+			MethodName: "TransferOwnership",
+			Handler:    _WalletService_TransferOwnership_Handler,
+		},
+		{
+			MethodName: "Send",
+			Handler:    _WalletService_Send_Handler,
+		},
+		{
+			MethodName: "Receive",
+			Handler:    _WalletService_Receive_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _WalletService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "wallet/v1/wallet.proto",
+}